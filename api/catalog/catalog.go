@@ -0,0 +1,404 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/media"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const MANIFEST_NAME = "manifest.json"
+const MANIFEST_VERSION = 1
+
+// Describes catalog archive content, file paths are relative to the archive root
+type Manifest struct {
+	Version int              `json:"version"`
+	Objects []ManifestObject `json:"objects"`
+}
+
+type ManifestObject struct {
+	Code  string `json:"code"`
+	Venue string `json:"venue"`
+	Year  *int   `json:"year,omitempty"`
+	// Covers in display order, existing covers are kept if empty
+	Covers       []string                       `json:"covers,omitempty"`
+	Translations map[string]ManifestTranslation `json:"translations"`
+}
+
+type ManifestTranslation struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Location    string   `json:"location,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	// Required for new translations, existing audio is kept if empty
+	Audio      string `json:"audio,omitempty"`
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// Imports and exports objects with their covers, translations and audio as ZIP archives
+type Catalog struct {
+	BlobProvider     blob.BlobProvider
+	MediaProvider    media.MediaProvider
+	ObjectRepository repository.ObjectRepository
+	VenueRepository  repository.VenueRepository
+}
+
+// Object validated against the manifest files, ready to be stored
+type importObject struct {
+	manifest ManifestObject
+	venueID  int64
+	audio    map[string]media.AudioInfo
+}
+
+// Upserts objects described by the manifest, the whole manifest is validated before any changes are made.
+// Translations and covers missing in the manifest are kept as is
+func (catalog *Catalog) Import(files fs.FS) (int, error) {
+	data, err := fs.ReadFile(files, MANIFEST_NAME)
+	if err != nil {
+		return 0, err
+	}
+
+	manifest := Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if manifest.Version != MANIFEST_VERSION {
+		return 0, fmt.Errorf("manifest version %d is not supported", manifest.Version)
+	}
+
+	objects := []importObject{}
+	for _, object := range manifest.Objects {
+		validated, err := catalog.validateObject(files, object)
+		if err != nil {
+			return 0, fmt.Errorf("object %q: %w", object.Code, err)
+		}
+
+		objects = append(objects, validated)
+	}
+
+	for i, object := range objects {
+		if err := catalog.importObject(files, object); err != nil {
+			return i, fmt.Errorf("object %q: %w", object.manifest.Code, err)
+		}
+	}
+
+	return len(objects), nil
+}
+
+func (catalog *Catalog) validateObject(files fs.FS, object ManifestObject) (importObject, error) {
+	result := importObject{manifest: object, audio: map[string]media.AudioInfo{}}
+	if strings.TrimSpace(object.Code) == "" {
+		return importObject{}, errors.New("code is empty")
+	}
+
+	venue, err := catalog.VenueRepository.GetVenueByCode(object.Venue)
+	if err != nil {
+		return importObject{}, err
+	}
+
+	if venue == nil {
+		return importObject{}, fmt.Errorf("venue %q not found", object.Venue)
+	}
+	result.venueID = venue.ID
+
+	objectID, err := catalog.ObjectRepository.GetObjectID(object.Code)
+	if err != nil {
+		return importObject{}, err
+	}
+
+	existing := map[string]bool{}
+	if objectID != nil {
+		translations, err := catalog.ObjectRepository.GetObjectTranslations(*objectID)
+		if err != nil {
+			return importObject{}, err
+		}
+
+		for _, translation := range translations {
+			existing[translation.Language] = true
+		}
+	}
+
+	for _, cover := range object.Covers {
+		if _, err := fs.Stat(files, cover); err != nil {
+			return importObject{}, err
+		}
+	}
+
+	for language, translation := range object.Translations {
+		if !isLanguageCode(language) {
+			return importObject{}, fmt.Errorf("language %q is not valid", language)
+		}
+
+		if strings.TrimSpace(translation.Title) == "" {
+			return importObject{}, fmt.Errorf("title for language %q is empty", language)
+		}
+
+		if translation.Audio == "" && !existing[language] {
+			return importObject{}, fmt.Errorf("audio for language %q is required", language)
+		}
+
+		if translation.Audio != "" {
+			data, err := fs.ReadFile(files, translation.Audio)
+			if err != nil {
+				return importObject{}, err
+			}
+
+			info, err := catalog.MediaProvider.ParseAudio(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return importObject{}, fmt.Errorf("audio %q: %w", translation.Audio, err)
+			}
+			result.audio[language] = info
+		}
+
+		if translation.Transcript != "" {
+			extension := strings.ToLower(path.Ext(translation.Transcript))
+			if extension != ".txt" && extension != ".vtt" {
+				return importObject{}, fmt.Errorf("transcript %q must be either .txt or .vtt", translation.Transcript)
+			}
+
+			if _, err := fs.Stat(files, translation.Transcript); err != nil {
+				return importObject{}, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (catalog *Catalog) importObject(files fs.FS, object importObject) error {
+	code := object.manifest.Code
+	objectID, err := catalog.ObjectRepository.GetObjectID(code)
+	if err != nil {
+		return err
+	}
+
+	if objectID == nil {
+		createdID, err := catalog.ObjectRepository.CreateObject(code, object.venueID)
+		if err != nil {
+			return err
+		}
+		objectID = &createdID
+	}
+
+	if err := catalog.ObjectRepository.SetObjectYear(*objectID, object.manifest.Year); err != nil {
+		return err
+	}
+
+	if len(object.manifest.Covers) > 0 {
+		covers := []repository.Cover{}
+		for index, cover := range object.manifest.Covers {
+			coverPath, err := catalog.uploadFile(files, code, cover)
+			if err != nil {
+				return err
+			}
+
+			covers = append(covers, repository.Cover{Index: index, Path: coverPath})
+		}
+
+		if err := catalog.ObjectRepository.SetObjectCovers(*objectID, covers); err != nil {
+			return err
+		}
+	}
+
+	existing, err := catalog.ObjectRepository.GetObjectTranslations(*objectID)
+	if err != nil {
+		return err
+	}
+
+	translations := map[string]repository.ObjectTranslation{}
+	for _, translation := range existing {
+		translations[translation.Language] = translation
+	}
+
+	for language, manifest := range object.manifest.Translations {
+		translation := translations[language]
+		translation.Language = language
+		translation.Title = strings.TrimSpace(manifest.Title)
+		translation.Description = manifest.Description
+		translation.Author = manifest.Author
+		translation.Location = manifest.Location
+		translation.Tags = append([]string{}, manifest.Tags...)
+
+		if manifest.Audio != "" {
+			translation.AudioPath, err = catalog.uploadFile(files, code, manifest.Audio)
+			if err != nil {
+				return err
+			}
+
+			info := object.audio[language]
+			translation.AudioDurationMs = info.DurationMs
+			translation.AudioBitrate = info.Bitrate
+			translation.AudioSampleRate = info.SampleRate
+			translation.AudioMIMEType = info.MIMEType
+		}
+
+		if manifest.Transcript != "" {
+			translation.TranscriptPath, err = catalog.uploadFile(files, code, manifest.Transcript)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := catalog.ObjectRepository.SetObjectTranslation(*objectID, translation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Blobs are immutable, so each upload gets a unique name, same as in admin API
+func (catalog *Catalog) uploadFile(files fs.FS, objectCode string, name string) (string, error) {
+	file, err := files.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	blobPath := objectCode + "/" + uuid.New().String() + strings.ToLower(path.Ext(name))
+	if err := catalog.BlobProvider.WriteBlob(blobPath, file); err != nil {
+		return "", err
+	}
+
+	return blobPath, nil
+}
+
+// Writes objects of all venues to the archive in the import format
+func (catalog *Catalog) Export(writer io.Writer) (int, error) {
+	archive := zip.NewWriter(writer)
+	manifest := Manifest{Version: MANIFEST_VERSION, Objects: []ManifestObject{}}
+
+	venues, err := catalog.VenueRepository.GetVenues()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, venue := range venues {
+		objects, err := catalog.ObjectRepository.GetVenueObjects(venue.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, object := range objects {
+			exported, err := catalog.exportObject(archive, venue, object)
+			if err != nil {
+				return 0, fmt.Errorf("object %q: %w", object.Code, err)
+			}
+
+			manifest.Objects = append(manifest.Objects, exported)
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := archive.Create(MANIFEST_NAME)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return 0, err
+	}
+
+	return len(manifest.Objects), archive.Close()
+}
+
+func (catalog *Catalog) exportObject(archive *zip.Writer, venue repository.Venue, object repository.Object) (ManifestObject, error) {
+	result := ManifestObject{
+		Code:         object.Code,
+		Venue:        venue.Code,
+		Year:         object.Year,
+		Covers:       []string{},
+		Translations: map[string]ManifestTranslation{},
+	}
+
+	// Object codes are not restricted, so they are escaped to be used as directory names
+	directory := url.PathEscape(object.Code)
+	for _, cover := range object.Covers {
+		name := directory + "/cover-" + strconv.Itoa(cover.Index) + path.Ext(cover.Path)
+		if err := catalog.exportFile(archive, name, cover.Path); err != nil {
+			return ManifestObject{}, err
+		}
+
+		result.Covers = append(result.Covers, name)
+	}
+
+	translations, err := catalog.ObjectRepository.GetObjectTranslations(object.ID)
+	if err != nil {
+		return ManifestObject{}, err
+	}
+
+	for _, translation := range translations {
+		exported := ManifestTranslation{
+			Title:       translation.Title,
+			Description: translation.Description,
+			Author:      translation.Author,
+			Location:    translation.Location,
+			Tags:        translation.Tags,
+			Audio:       directory + "/audio-" + translation.Language + path.Ext(translation.AudioPath),
+		}
+
+		if err := catalog.exportFile(archive, exported.Audio, translation.AudioPath); err != nil {
+			return ManifestObject{}, err
+		}
+
+		if translation.TranscriptPath != "" {
+			exported.Transcript = directory + "/transcript-" + translation.Language + path.Ext(translation.TranscriptPath)
+			if err := catalog.exportFile(archive, exported.Transcript, translation.TranscriptPath); err != nil {
+				return ManifestObject{}, err
+			}
+		}
+
+		result.Translations[translation.Language] = exported
+	}
+
+	return result, nil
+}
+
+func (catalog *Catalog) exportFile(archive *zip.Writer, name string, blobPath string) error {
+	reader, err := catalog.BlobProvider.ReadBlob(blobPath, blob.ReadBlobOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	// Media files are already compressed
+	file, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, reader)
+	return err
+}
+
+func isLanguageCode(value string) bool {
+	if len(value) != 2 {
+		return false
+	}
+
+	for _, char := range value {
+		if char < 'a' || char > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,212 @@
+package catalog
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/media"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const TEST_VENUE_CODE = "museum"
+
+func createTestCatalog(t *testing.T) (*Catalog, *repository.MemoryRepository, *blob.MemoryBlobProvider) {
+	repo := repository.CreateMemoryRepository()
+	repo.AddVenue(repository.Venue{Code: TEST_VENUE_CODE, Name: "Museum"})
+	blobProvider := blob.CreateMemoryBlobProvider()
+	mediaProvider, err := media.CreateNativeMediaProvider()
+	if err != nil {
+		t.Fatalf("failed to create media provider: %v", err)
+	}
+
+	catalog := Catalog{
+		BlobProvider:     blobProvider,
+		MediaProvider:    mediaProvider,
+		ObjectRepository: repo,
+		VenueRepository:  repo,
+	}
+
+	return &catalog, repo, blobProvider
+}
+
+// Ten MPEG1 Layer III frames of 417 bytes, 128 kbps, 44.1 kHz
+func createTestAudio() []byte {
+	audio := []byte{}
+	for i := 0; i < 10; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		audio = append(audio, frame...)
+	}
+
+	return audio
+}
+
+func createTestFiles(t *testing.T, manifest Manifest) fstest.MapFS {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("failed to marshal manifest: %v", err)
+	}
+
+	return fstest.MapFS{
+		MANIFEST_NAME:            {Data: data},
+		"painting/cover-1.jpg":   {Data: []byte("cover-1")},
+		"painting/cover-2.png":   {Data: []byte("cover-2")},
+		"painting/audio-en.mp3":  {Data: createTestAudio()},
+		"painting/audio-be.mp3":  {Data: createTestAudio()},
+		"painting/transcript.vt": {Data: []byte("WEBVTT")},
+		"painting/text-en.txt":   {Data: []byte("Transcript")},
+	}
+}
+
+func createTestManifest() Manifest {
+	year := 1890
+	return Manifest{
+		Version: MANIFEST_VERSION,
+		Objects: []ManifestObject{{
+			Code:   "painting",
+			Venue:  TEST_VENUE_CODE,
+			Year:   &year,
+			Covers: []string{"painting/cover-1.jpg", "painting/cover-2.png"},
+			Translations: map[string]ManifestTranslation{
+				"en": {Title: "Painting", Author: "Author", Tags: []string{"oil"}, Audio: "painting/audio-en.mp3", Transcript: "painting/text-en.txt"},
+				"be": {Title: "Карціна", Audio: "painting/audio-be.mp3"},
+			},
+		}},
+	}
+}
+
+func readTestBlob(t *testing.T, provider blob.BlobProvider, path string) string {
+	reader, err := provider.ReadBlob(path, blob.ReadBlobOptions{})
+	if err != nil {
+		t.Fatalf("failed to read blob %q: %v", path, err)
+	}
+	defer reader.Close()
+
+	data, _ := io.ReadAll(reader)
+	return string(data)
+}
+
+func TestImport(t *testing.T) {
+	catalog, repo, blobProvider := createTestCatalog(t)
+
+	count, err := catalog.Import(createTestFiles(t, createTestManifest()))
+	if err != nil || count != 1 {
+		t.Fatalf("failed to import: %d, %v", count, err)
+	}
+
+	object, _ := repo.GetObject("painting", "en")
+	if object == nil || object.Title != "Painting" || object.Author != "Author" || object.Year == nil || *object.Year != 1890 {
+		t.Fatalf("unexpected object %+v", object)
+	}
+
+	if object.AudioDurationMs != 260 || len(object.Tags) != 1 {
+		t.Fatalf("expected audio metadata and tags, got %+v", object)
+	}
+
+	if len(object.Covers) != 2 || readTestBlob(t, blobProvider, object.Covers[1].Path) != "cover-2" {
+		t.Fatalf("unexpected covers %+v", object.Covers)
+	}
+
+	if !strings.HasSuffix(object.TranscriptPath, ".txt") || readTestBlob(t, blobProvider, object.TranscriptPath) != "Transcript" {
+		t.Fatalf("unexpected transcript %q", object.TranscriptPath)
+	}
+
+	// Re-import updates existing translations and keeps audio if it's not passed
+	manifest := createTestManifest()
+	manifest.Objects[0].Covers = nil
+	manifest.Objects[0].Translations = map[string]ManifestTranslation{"be": {Title: "Новая назва"}}
+	if _, err := catalog.Import(createTestFiles(t, manifest)); err != nil {
+		t.Fatalf("failed to re-import: %v", err)
+	}
+
+	updated, _ := repo.GetObject("painting", "be")
+	if updated.Title != "Новая назва" || updated.AudioPath == "" || updated.AudioDurationMs != 260 || len(updated.Covers) != 2 {
+		t.Fatalf("unexpected updated object %+v", updated)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	cases := map[string]func(manifest *Manifest){
+		"version": func(manifest *Manifest) { manifest.Version = 2 },
+		"venue":   func(manifest *Manifest) { manifest.Objects[0].Venue = "unknown" },
+		"cover":   func(manifest *Manifest) { manifest.Objects[0].Covers = []string{"painting/missing.jpg"} },
+		"language": func(manifest *Manifest) {
+			manifest.Objects[0].Translations["eng"] = ManifestTranslation{Title: "Title"}
+		},
+		"no audio": func(manifest *Manifest) {
+			manifest.Objects[0].Translations["ru"] = ManifestTranslation{Title: "Назва"}
+		},
+		"bad audio": func(manifest *Manifest) {
+			manifest.Objects[0].Translations["ru"] = ManifestTranslation{Title: "Назва", Audio: "painting/cover-1.jpg"}
+		},
+		"transcript": func(manifest *Manifest) {
+			manifest.Objects[0].Translations["en"] = ManifestTranslation{Title: "Title", Audio: "painting/audio-en.mp3", Transcript: "painting/transcript.vt"}
+		},
+	}
+
+	for name, modify := range cases {
+		catalog, repo, _ := createTestCatalog(t)
+		manifest := createTestManifest()
+		modify(&manifest)
+
+		if _, err := catalog.Import(createTestFiles(t, manifest)); err == nil {
+			t.Fatalf("%s: expected import to fail", name)
+		}
+
+		// Manifest is validated before any changes are made
+		if objectID, _ := repo.GetObjectID("painting"); objectID != nil {
+			t.Fatalf("%s: expected no objects to be created", name)
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	catalog, _, _ := createTestCatalog(t)
+	if _, err := catalog.Import(createTestFiles(t, createTestManifest())); err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+
+	buffer := bytes.Buffer{}
+	count, err := catalog.Export(&buffer)
+	if err != nil || count != 1 {
+		t.Fatalf("failed to export: %d, %v", count, err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+
+	// Exported archive can be imported to another deployment
+	imported, repo, blobProvider := createTestCatalog(t)
+	if _, err := imported.Import(archive); err != nil {
+		t.Fatalf("failed to import exported archive: %v", err)
+	}
+
+	object, _ := repo.GetObject("painting", "be")
+	if object == nil || object.Title != "Карціна" || len(object.Covers) != 2 || readTestBlob(t, blobProvider, object.Covers[0].Path) != "cover-1" {
+		t.Fatalf("unexpected object after round trip %+v", object)
+	}
+
+	object, _ = repo.GetObject("painting", "en")
+	if object.Author != "Author" || object.Tags[0] != "oil" || readTestBlob(t, blobProvider, object.TranscriptPath) != "Transcript" {
+		t.Fatalf("unexpected metadata after round trip %+v", object)
+	}
+}
+
+func TestImportTestData(t *testing.T) {
+	catalog, repo, _ := createTestCatalog(t)
+	repo.AddVenue(repository.Venue{Code: "default", Name: "Default"})
+
+	count, err := catalog.Import(os.DirFS("../../admin/test-data"))
+	if err != nil || count != 5 {
+		t.Fatalf("failed to import test data: %d, %v", count, err)
+	}
+}
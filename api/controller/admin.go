@@ -0,0 +1,856 @@
+package controller
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/media"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+// Multipart form fields of object create and update requests
+const (
+	ADMIN_FIELD_CODE               = "code"
+	ADMIN_FIELD_VENUE              = "venue"
+	ADMIN_FIELD_YEAR               = "year"
+	ADMIN_FIELD_COVER              = "cover"
+	ADMIN_FIELD_TITLE_PREFIX       = "title-"
+	ADMIN_FIELD_DESCRIPTION_PREFIX = "description-"
+	ADMIN_FIELD_AUTHOR_PREFIX      = "author-"
+	ADMIN_FIELD_LOCATION_PREFIX    = "location-"
+	ADMIN_FIELD_TAGS_PREFIX        = "tags-"
+	ADMIN_FIELD_AUDIO_PREFIX       = "audio-"
+	ADMIN_FIELD_TRANSCRIPT_PREFIX  = "transcript-"
+	ADMIN_FIELD_TRACKS_PREFIX      = "tracks-"
+)
+
+const MAX_OBJECT_CODE_LENGTH = 64
+const MAX_OBJECT_TITLE_LENGTH = 255
+const MAX_OBJECT_DESCRIPTION_LENGTH = 4096
+const MAX_OBJECT_AUTHOR_LENGTH = 128
+const MAX_OBJECT_LOCATION_LENGTH = 128
+const MAX_OBJECT_TAG_LENGTH = 32
+const MAX_TRACK_NAME_LENGTH = 32
+
+type AdminController struct {
+	AdminToken       string
+	BlobProvider     blob.BlobProvider
+	ObjectRepository repository.ObjectRepository
+	VenueRepository  repository.VenueRepository
+	MediaProvider    media.MediaProvider
+	TourRepository   repository.TourRepository
+	TicketRepository repository.TicketRepository
+	BotProvider      bot.BotProvider
+	// Mini App direct link encoded in object QR codes, e.g. https://t.me/<bot>/<app>
+	MiniAppURL string
+}
+
+func (controller *AdminController) GetRoutes() []Route {
+	return []Route{
+		{
+			Method:  "POST",
+			Path:    "/admin/objects",
+			Handler: controller.HandleCreateObject,
+		},
+		{
+			Method:  "PUT",
+			Path:    "/admin/objects/:code",
+			Handler: controller.HandleUpdateObject,
+		},
+		{
+			Method:  "DELETE",
+			Path:    "/admin/objects/:code",
+			Handler: controller.HandleDeleteObject,
+		},
+		{
+			Method:  "DELETE",
+			Path:    "/admin/objects/:code/translations/:language",
+			Handler: controller.HandleDeleteObjectTranslation,
+		},
+		{
+			Method:  "GET",
+			Path:    "/admin/objects/:code/qr.:format",
+			Handler: controller.HandleGetObjectQR,
+		},
+		{
+			Method:  "GET",
+			Path:    "/admin/venues/:code/labels.pdf",
+			Handler: controller.HandleGetVenueLabels,
+		},
+		{
+			Method:  "GET",
+			Path:    "/admin/tours/:id/labels.pdf",
+			Handler: controller.HandleGetTourLabels,
+		},
+		{
+			Method:  "POST",
+			Path:    "/admin/tickets/:code/refund",
+			Handler: controller.HandleRefundTicket,
+		},
+	}
+}
+
+func (controller *AdminController) HandleCreateObject(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	multipartForm, err := c.MultipartForm()
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse multipart form", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse multipart form")
+	}
+
+	form, err := controller.parseObjectForm(multipartForm)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Object form is not valid", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	objectCode := strings.TrimSpace(c.FormValue(ADMIN_FIELD_CODE))
+	if objectCode == "" || len(objectCode) > MAX_OBJECT_CODE_LENGTH {
+		HandlerPrintf(c, LOG_WARNING, "Object code is not valid")
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Object code is not valid")
+	}
+
+	venue, err := controller.VenueRepository.GetVenueByCode(c.FormValue(ADMIN_FIELD_VENUE))
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get venue", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get venue")
+	}
+
+	if venue == nil {
+		HandlerPrintf(c, LOG_WARNING, "Venue not found")
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Venue not found")
+	}
+
+	languages := form.getLanguages()
+	if len(languages) == 0 {
+		HandlerPrintf(c, LOG_WARNING, "Object has no translations")
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Object has no translations")
+	}
+
+	for _, language := range languages {
+		if form.Titles[language] == "" || form.Audio[language] == nil {
+			HandlerPrintf(c, LOG_WARNING, "Object translation is incomplete", "language", language)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Object translation requires both title and audio")
+		}
+	}
+
+	objectID, err := controller.ObjectRepository.GetObjectID(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
+
+	if objectID != nil {
+		HandlerPrintf(c, LOG_WARNING, "Object already exists")
+		return HandlerSendFailure(c, fiber.StatusConflict, "Object already exists")
+	}
+
+	// Partially created object is deleted if any step fails, together with the uploaded blobs
+	uploaded := []string{}
+	createdID := int64(0)
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+
+		if createdID != 0 {
+			if err := controller.ObjectRepository.DeleteObject(createdID); err != nil {
+				HandlerPrintf(c, LOG_WARNING, "Failed to delete partially created object", "error", err)
+			}
+		}
+		controller.deleteUnreferencedBlobs(c, createdID, uploaded)
+	}()
+
+	covers, err := controller.uploadCovers(objectCode, form.Covers, &uploaded)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to upload covers", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload covers")
+	}
+
+	translations := []repository.ObjectTranslation{}
+	tracks := map[string][]repository.AudioTrack{}
+	for _, language := range languages {
+		audioPath, err := controller.uploadFile(objectCode, form.Audio[language], &uploaded)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to upload audio", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload audio")
+		}
+
+		translation := repository.ObjectTranslation{Language: language, Tags: []string{}, AudioPath: audioPath}
+		form.applyTranslation(&translation)
+
+		if transcript, ok := form.Transcripts[language]; ok {
+			translation.TranscriptPath, err = controller.uploadFile(objectCode, transcript, &uploaded)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to upload transcript", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload transcript")
+			}
+		}
+
+		tracks[language], err = controller.uploadTracks(objectCode, form.Tracks[language], &uploaded)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to upload tracks", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload tracks")
+		}
+
+		translations = append(translations, translation)
+	}
+
+	createdID, err = controller.ObjectRepository.CreateObject(objectCode, venue.ID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to create object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to create object")
+	}
+
+	if err := controller.ObjectRepository.SetObjectCovers(createdID, covers); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to save object covers", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save object covers")
+	}
+
+	if err := controller.ObjectRepository.SetObjectYear(createdID, form.Year); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to save object year", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save object year")
+	}
+
+	for _, translation := range translations {
+		if err := controller.ObjectRepository.SetObjectTranslation(createdID, translation); err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to save object translation", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save object translation")
+		}
+
+		if err := controller.ObjectRepository.SetObjectTracks(createdID, translation.Language, tracks[translation.Language]); err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to save object tracks", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save object tracks")
+		}
+	}
+
+	completed = true
+	HandlerPrintf(c, LOG_INFO, "Created object", "object", objectCode, "venue", venue.Code)
+	return HandlerSendSuccess(c, fiber.StatusCreated, nil)
+}
+
+func (controller *AdminController) HandleUpdateObject(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	multipartForm, err := c.MultipartForm()
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse multipart form", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse multipart form")
+	}
+
+	form, err := controller.parseObjectForm(multipartForm)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Object form is not valid", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	objectCode := c.Params("code")
+	objectID, err := controller.ObjectRepository.GetObjectID(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
+
+	if objectID == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
+
+	existing, err := controller.ObjectRepository.GetObjectTranslations(*objectID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object translations", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object translations")
+	}
+
+	translations := map[string]repository.ObjectTranslation{}
+	for _, translation := range existing {
+		translations[translation.Language] = translation
+	}
+
+	languages := form.getLanguages()
+	for _, language := range languages {
+		_, found := translations[language]
+		if !found && (form.Titles[language] == "" || form.Audio[language] == nil) {
+			HandlerPrintf(c, LOG_WARNING, "Object translation is incomplete", "language", language)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Object translation requires both title and audio")
+		}
+	}
+
+	previous, err := controller.ObjectRepository.GetObjectBlobs(*objectID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object blobs", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object blobs")
+	}
+
+	// Files are uploaded before the rows are written in a single transaction, replaced blobs
+	// are deleted only after it is committed and uploaded ones only if it is not
+	uploaded := []string{}
+	committed := false
+	defer func() {
+		if committed {
+			controller.deleteUnreferencedBlobs(c, *objectID, previous)
+		} else {
+			controller.deleteUnreferencedBlobs(c, *objectID, uploaded)
+		}
+	}()
+
+	covers, err := controller.uploadCovers(objectCode, form.Covers, &uploaded)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to upload covers", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload covers")
+	}
+
+	updated := []repository.ObjectTranslation{}
+	tracks := map[string][]repository.AudioTrack{}
+	for _, language := range languages {
+		translation := translations[language]
+		translation.Language = language
+		form.applyTranslation(&translation)
+
+		if audio, ok := form.Audio[language]; ok {
+			translation.AudioPath, err = controller.uploadFile(objectCode, audio, &uploaded)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to upload audio", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload audio")
+			}
+		}
+
+		if transcript, ok := form.Transcripts[language]; ok {
+			translation.TranscriptPath, err = controller.uploadFile(objectCode, transcript, &uploaded)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to upload transcript", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload transcript")
+			}
+		}
+
+		if formTracks, ok := form.Tracks[language]; ok {
+			tracks[language], err = controller.uploadTracks(objectCode, formTracks, &uploaded)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to upload tracks", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to upload tracks")
+			}
+		}
+
+		updated = append(updated, translation)
+	}
+
+	err = controller.ObjectRepository.ObjectTransaction(func(tx repository.ObjectRepository) error {
+		if len(covers) > 0 {
+			if err := tx.SetObjectCovers(*objectID, covers); err != nil {
+				return fmt.Errorf("failed to save covers: %w", err)
+			}
+		}
+
+		if form.HasYear {
+			if err := tx.SetObjectYear(*objectID, form.Year); err != nil {
+				return fmt.Errorf("failed to save year: %w", err)
+			}
+		}
+
+		for _, translation := range updated {
+			if err := tx.SetObjectTranslation(*objectID, translation); err != nil {
+				return fmt.Errorf("failed to save %s translation: %w", translation.Language, err)
+			}
+
+			if languageTracks, ok := tracks[translation.Language]; ok {
+				if err := tx.SetObjectTracks(*objectID, translation.Language, languageTracks); err != nil {
+					return fmt.Errorf("failed to save %s tracks: %w", translation.Language, err)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to save object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save object")
+	}
+
+	committed = true
+	HandlerPrintf(c, LOG_INFO, "Updated object", "object", objectCode)
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *AdminController) HandleDeleteObject(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	objectCode := c.Params("code")
+	objectID, err := controller.ObjectRepository.GetObjectID(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
+
+	if objectID == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
+
+	blobs, err := controller.ObjectRepository.GetObjectBlobs(*objectID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object blobs", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object blobs")
+	}
+
+	if err := controller.ObjectRepository.DeleteObject(*objectID); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to delete object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to delete object")
+	}
+
+	// Blobs are deleted only after the rows referencing them
+	controller.deleteUnreferencedBlobs(c, *objectID, blobs)
+
+	HandlerPrintf(c, LOG_INFO, "Deleted object", "object", objectCode)
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *AdminController) HandleDeleteObjectTranslation(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	objectCode := c.Params("code")
+	objectID, err := controller.ObjectRepository.GetObjectID(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
+
+	if objectID == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
+
+	blobs, err := controller.ObjectRepository.GetObjectBlobs(*objectID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object blobs", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object blobs")
+	}
+
+	deleted, err := controller.ObjectRepository.DeleteObjectTranslation(*objectID, c.Params("language"))
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to delete object translation", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to delete object translation")
+	}
+
+	if !deleted {
+		HandlerPrintf(c, LOG_WARNING, "Object translation not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object translation not found")
+	}
+
+	controller.deleteUnreferencedBlobs(c, *objectID, blobs)
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *AdminController) isAuthorized(c *fiber.Ctx) bool {
+	// Admin API is disabled if token is not configured
+	if controller.AdminToken == "" {
+		return false
+	}
+
+	authHeader := c.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(authHeader), []byte(controller.AdminToken)) == 1
+}
+
+type ObjectForm struct {
+	Covers []*multipart.FileHeader
+	// Year is only changed if the field is passed, empty value clears it
+	HasYear      bool
+	Year         *int
+	Titles       map[string]string
+	Descriptions map[string]string
+	Authors      map[string]string
+	Locations    map[string]string
+	Tags         map[string][]string
+	Audio        map[string]*multipart.FileHeader
+	AudioInfo    map[string]media.AudioInfo
+	Transcripts  map[string]*multipart.FileHeader
+	// Additional audio tracks replace all tracks of the language
+	Tracks map[string][]ObjectFormTrack
+}
+
+type ObjectFormTrack struct {
+	Name string
+	File *multipart.FileHeader
+	Info media.AudioInfo
+}
+
+func (form *ObjectForm) getLanguages() []string {
+	set := map[string]bool{}
+	for _, values := range []map[string]string{form.Titles, form.Descriptions, form.Authors, form.Locations} {
+		for language := range values {
+			set[language] = true
+		}
+	}
+	for language := range form.Tags {
+		set[language] = true
+	}
+	for language := range form.Audio {
+		set[language] = true
+	}
+	for language := range form.Transcripts {
+		set[language] = true
+	}
+	for language := range form.Tracks {
+		set[language] = true
+	}
+
+	result := []string{}
+	for language := range set {
+		result = append(result, language)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// Replaces translation text fields that are passed in the form
+func (form *ObjectForm) applyTranslation(translation *repository.ObjectTranslation) {
+	if title, ok := form.Titles[translation.Language]; ok {
+		translation.Title = title
+	}
+	if description, ok := form.Descriptions[translation.Language]; ok {
+		translation.Description = description
+	}
+	if author, ok := form.Authors[translation.Language]; ok {
+		translation.Author = author
+	}
+	if location, ok := form.Locations[translation.Language]; ok {
+		translation.Location = location
+	}
+	if tags, ok := form.Tags[translation.Language]; ok {
+		translation.Tags = tags
+	}
+	if info, ok := form.AudioInfo[translation.Language]; ok {
+		translation.AudioDurationMs = info.DurationMs
+		translation.AudioBitrate = info.Bitrate
+		translation.AudioSampleRate = info.SampleRate
+		translation.AudioMIMEType = info.MIMEType
+	}
+}
+
+func (controller *AdminController) parseObjectForm(multipartForm *multipart.Form) (ObjectForm, error) {
+	form := ObjectForm{
+		Covers:      multipartForm.File[ADMIN_FIELD_COVER],
+		Tags:        map[string][]string{},
+		Audio:       map[string]*multipart.FileHeader{},
+		AudioInfo:   map[string]media.AudioInfo{},
+		Transcripts: map[string]*multipart.FileHeader{},
+		Tracks:      map[string][]ObjectFormTrack{},
+	}
+
+	for _, cover := range form.Covers {
+		if filepath.Ext(cover.Filename) == "" {
+			return ObjectForm{}, errors.New("cover file name has no extension")
+		}
+	}
+
+	if values, ok := multipartForm.Value[ADMIN_FIELD_YEAR]; ok && len(values) > 0 {
+		form.HasYear = true
+		if value := strings.TrimSpace(values[0]); value != "" {
+			year, err := strconv.Atoi(value)
+			if err != nil {
+				return ObjectForm{}, errors.New("year is not valid")
+			}
+			form.Year = &year
+		}
+	}
+
+	var err error
+	form.Titles, err = parseTranslatedValues(multipartForm.Value, ADMIN_FIELD_TITLE_PREFIX, MAX_OBJECT_TITLE_LENGTH, true)
+	if err != nil {
+		return ObjectForm{}, err
+	}
+
+	form.Descriptions, err = parseTranslatedValues(multipartForm.Value, ADMIN_FIELD_DESCRIPTION_PREFIX, MAX_OBJECT_DESCRIPTION_LENGTH, false)
+	if err != nil {
+		return ObjectForm{}, err
+	}
+
+	form.Authors, err = parseTranslatedValues(multipartForm.Value, ADMIN_FIELD_AUTHOR_PREFIX, MAX_OBJECT_AUTHOR_LENGTH, false)
+	if err != nil {
+		return ObjectForm{}, err
+	}
+
+	form.Locations, err = parseTranslatedValues(multipartForm.Value, ADMIN_FIELD_LOCATION_PREFIX, MAX_OBJECT_LOCATION_LENGTH, false)
+	if err != nil {
+		return ObjectForm{}, err
+	}
+
+	// Tags are passed as a comma-separated list
+	tagLists, err := parseTranslatedValues(multipartForm.Value, ADMIN_FIELD_TAGS_PREFIX, math.MaxInt, false)
+	if err != nil {
+		return ObjectForm{}, err
+	}
+
+	for language, list := range tagLists {
+		tags := []string{}
+		for _, tag := range strings.Split(list, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "" || slices.Contains(tags, tag) {
+				continue
+			}
+
+			if len([]rune(tag)) > MAX_OBJECT_TAG_LENGTH {
+				return ObjectForm{}, fmt.Errorf("tag %q is too long", tag)
+			}
+
+			tags = append(tags, tag)
+		}
+
+		form.Tags[language] = tags
+	}
+
+	for key, files := range multipartForm.File {
+		language, found := strings.CutPrefix(key, ADMIN_FIELD_AUDIO_PREFIX)
+		if !found || len(files) == 0 {
+			continue
+		}
+
+		if !isLanguageCode(language) {
+			return ObjectForm{}, fmt.Errorf("audio language %q is not valid", language)
+		}
+
+		// Some clients are not able to play audio without an extension
+		if filepath.Ext(files[0].Filename) == "" {
+			return ObjectForm{}, errors.New("audio file name has no extension")
+		}
+
+		// Headers are parsed to reject files which are not audio and to get the duration
+		info, err := controller.parseAudio(files[0])
+		if errors.Is(err, media.ErrUnsupportedFormat) {
+			return ObjectForm{}, fmt.Errorf("audio for language %q is not a supported MP3, M4A or OGG file", language)
+		}
+
+		if err != nil {
+			return ObjectForm{}, err
+		}
+
+		form.Audio[language] = files[0]
+		form.AudioInfo[language] = info
+	}
+
+	for key, files := range multipartForm.File {
+		language, found := strings.CutPrefix(key, ADMIN_FIELD_TRANSCRIPT_PREFIX)
+		if !found || len(files) == 0 {
+			continue
+		}
+
+		if !isLanguageCode(language) {
+			return ObjectForm{}, fmt.Errorf("transcript language %q is not valid", language)
+		}
+
+		// Transcript format is detected by the extension
+		extension := strings.ToLower(filepath.Ext(files[0].Filename))
+		if extension != ".txt" && extension != ".vtt" {
+			return ObjectForm{}, errors.New("transcript file must be either .txt or .vtt")
+		}
+
+		form.Transcripts[language] = files[0]
+	}
+
+	for key, files := range multipartForm.File {
+		language, found := strings.CutPrefix(key, ADMIN_FIELD_TRACKS_PREFIX)
+		if !found || len(files) == 0 {
+			continue
+		}
+
+		if !isLanguageCode(language) {
+			return ObjectForm{}, fmt.Errorf("tracks language %q is not valid", language)
+		}
+
+		tracks := []ObjectFormTrack{}
+		for _, file := range files {
+			// Track is named after the file, e.g. kids.mp3 is served as "kids" track
+			extension := filepath.Ext(file.Filename)
+			name := strings.TrimSuffix(filepath.Base(file.Filename), extension)
+			if extension == "" {
+				return ObjectForm{}, errors.New("track file name has no extension")
+			}
+
+			if !isTrackName(name) {
+				return ObjectForm{}, fmt.Errorf("track name %q is not valid", name)
+			}
+
+			if slices.ContainsFunc(tracks, func(track ObjectFormTrack) bool { return track.Name == name }) {
+				return ObjectForm{}, fmt.Errorf("track %q is duplicated", name)
+			}
+
+			// Track duration is shown in the UI, so it's parsed the same way as for the main audio
+			info, err := controller.parseAudio(file)
+			if errors.Is(err, media.ErrUnsupportedFormat) {
+				return ObjectForm{}, fmt.Errorf("track %q is not a supported MP3, M4A or OGG file", name)
+			}
+
+			if err != nil {
+				return ObjectForm{}, err
+			}
+
+			tracks = append(tracks, ObjectFormTrack{Name: name, File: file, Info: info})
+		}
+
+		form.Tracks[language] = tracks
+	}
+
+	return form, nil
+}
+
+// Collects values of "{prefix}{LANGUAGE}" fields by language
+func parseTranslatedValues(values map[string][]string, prefix string, maxLength int, required bool) (map[string]string, error) {
+	name := strings.TrimSuffix(prefix, "-")
+	result := map[string]string{}
+	for key, fieldValues := range values {
+		language, found := strings.CutPrefix(key, prefix)
+		if !found || len(fieldValues) == 0 {
+			continue
+		}
+
+		if !isLanguageCode(language) {
+			return nil, fmt.Errorf("%s language %q is not valid", name, language)
+		}
+
+		value := strings.TrimSpace(fieldValues[0])
+		if (required && value == "") || len([]rune(value)) > maxLength {
+			return nil, fmt.Errorf("%s for language %q is not valid", name, language)
+		}
+
+		result[language] = value
+	}
+
+	return result, nil
+}
+
+func (controller *AdminController) parseAudio(file *multipart.FileHeader) (media.AudioInfo, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return media.AudioInfo{}, err
+	}
+	defer reader.Close()
+
+	return controller.MediaProvider.ParseAudio(reader, file.Size)
+}
+
+func (controller *AdminController) uploadCovers(objectCode string, files []*multipart.FileHeader, uploaded *[]string) ([]repository.Cover, error) {
+	covers := []repository.Cover{}
+	for index, file := range files {
+		path, err := controller.uploadFile(objectCode, file, uploaded)
+		if err != nil {
+			return nil, err
+		}
+
+		covers = append(covers, repository.Cover{Index: index, Path: path})
+	}
+
+	return covers, nil
+}
+
+func (controller *AdminController) uploadTracks(objectCode string, formTracks []ObjectFormTrack, uploaded *[]string) ([]repository.AudioTrack, error) {
+	tracks := []repository.AudioTrack{}
+	for index, track := range formTracks {
+		path, err := controller.uploadFile(objectCode, track.File, uploaded)
+		if err != nil {
+			return nil, err
+		}
+
+		tracks = append(tracks, repository.AudioTrack{Name: track.Name, Index: index, DurationMs: track.Info.DurationMs, AudioPath: path})
+	}
+
+	return tracks, nil
+}
+
+// Uploaded blob path is appended to uploaded, so it can be deleted if the request fails
+func (controller *AdminController) uploadFile(objectCode string, file *multipart.FileHeader, uploaded *[]string) (string, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	// Blobs are immutable, so each upload gets a unique name
+	extension := strings.ToLower(filepath.Ext(file.Filename))
+	path := objectCode + "/" + uuid.New().String() + extension
+	if err := controller.BlobProvider.WriteBlob(path, reader); err != nil {
+		return "", err
+	}
+
+	*uploaded = append(*uploaded, path)
+	return path, nil
+}
+
+// Deletes blobs which are not referenced by the object anymore, failures are only logged,
+// since an orphaned blob doesn't break the object, while a missing one does
+func (controller *AdminController) deleteUnreferencedBlobs(c *fiber.Ctx, objectID int64, blobs []string) {
+	referenced, err := controller.ObjectRepository.GetObjectBlobs(objectID)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to get object blobs", "error", err)
+		return
+	}
+
+	for _, path := range blobs {
+		if slices.Contains(referenced, path) {
+			continue
+		}
+
+		if err := controller.BlobProvider.DeleteBlob(path); err != nil {
+			HandlerPrintf(c, LOG_WARNING, "Failed to delete blob", "path", path, "error", err)
+		}
+
+		// Variants are deleted after the blob, so they can't be generated from it again
+		deleteCoverVariants(c, controller.BlobProvider, path)
+	}
+}
+
+func isLanguageCode(value string) bool {
+	if len(value) != 2 {
+		return false
+	}
+
+	for _, char := range value {
+		if char < 'a' || char > 'z' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Track name is a part of the track URL, so only lowercase letters, digits, _ and - are allowed
+func isTrackName(value string) bool {
+	if value == "" || len(value) > MAX_TRACK_NAME_LENGTH {
+		return false
+	}
+
+	for _, char := range value {
+		if (char < 'a' || char > 'z') && (char < '0' || char > '9') && char != '_' && char != '-' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,383 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/media"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+func createTestAudioForm(t *testing.T, name string, data []byte) *multipart.Form {
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+	writer.WriteField(ADMIN_FIELD_TITLE_PREFIX+"en", "Title")
+	part, _ := writer.CreateFormFile(ADMIN_FIELD_AUDIO_PREFIX+"en", name)
+	part.Write(data)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("failed to create form: %v", err)
+	}
+
+	return form
+}
+
+// MPEG1 Layer III frames of 417 bytes, 128 kbps, 44.1 kHz, 26 ms each
+func createTestMP3(frames int) []byte {
+	audio := []byte{}
+	for i := 0; i < frames; i++ {
+		frame := make([]byte, 417)
+		copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+		audio = append(audio, frame...)
+	}
+
+	return audio
+}
+
+func TestParseObjectForm(t *testing.T) {
+	controller := AdminController{}
+	form, err := controller.parseObjectForm(&multipart.Form{
+		Value: map[string][]string{
+			ADMIN_FIELD_YEAR:                      {"1890"},
+			ADMIN_FIELD_TITLE_PREFIX + "en":       {" Title "},
+			ADMIN_FIELD_DESCRIPTION_PREFIX + "be": {"Апісанне"},
+			ADMIN_FIELD_TAGS_PREFIX + "en":        {"painting, oil,,painting"},
+			ADMIN_FIELD_LOCATION_PREFIX + "en":    {""},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to parse form: %v", err)
+	}
+
+	if !form.HasYear || form.Year == nil || *form.Year != 1890 {
+		t.Fatalf("unexpected year %v", form.Year)
+	}
+
+	if form.Titles["en"] != "Title" || form.Descriptions["be"] != "Апісанне" {
+		t.Fatalf("unexpected text fields %+v", form)
+	}
+
+	if location, ok := form.Locations["en"]; !ok || location != "" {
+		t.Fatalf("expected empty location to be passed to clear it")
+	}
+
+	if strings.Join(form.Tags["en"], "|") != "painting|oil" {
+		t.Fatalf("unexpected tags %v", form.Tags["en"])
+	}
+
+	languages := form.getLanguages()
+	if strings.Join(languages, ",") != "be,en" {
+		t.Fatalf("unexpected languages %v", languages)
+	}
+}
+
+func TestParseObjectFormErrors(t *testing.T) {
+	controller := AdminController{}
+	cases := []map[string][]string{
+		{ADMIN_FIELD_YEAR: {"unknown"}},
+		{ADMIN_FIELD_TITLE_PREFIX + "en": {" "}},
+		{ADMIN_FIELD_TITLE_PREFIX + "eng": {"Title"}},
+		{ADMIN_FIELD_AUTHOR_PREFIX + "en": {strings.Repeat("a", MAX_OBJECT_AUTHOR_LENGTH+1)}},
+		{ADMIN_FIELD_TAGS_PREFIX + "en": {strings.Repeat("a", MAX_OBJECT_TAG_LENGTH+1)}},
+	}
+
+	for _, values := range cases {
+		if _, err := controller.parseObjectForm(&multipart.Form{Value: values}); err == nil {
+			t.Fatalf("expected form %v to be rejected", values)
+		}
+	}
+}
+
+func TestParseObjectFormAudio(t *testing.T) {
+	mediaProvider, _ := media.CreateNativeMediaProvider()
+	controller := AdminController{MediaProvider: mediaProvider}
+
+	form, err := controller.parseObjectForm(createTestAudioForm(t, "audio.mp3", createTestMP3(10)))
+	if err != nil {
+		t.Fatalf("failed to parse form: %v", err)
+	}
+
+	info := form.AudioInfo["en"]
+	if form.Audio["en"] == nil || info.DurationMs != 260 || info.SampleRate != 44100 || info.MIMEType != "audio/mpeg" {
+		t.Fatalf("unexpected audio info %+v", info)
+	}
+
+	if _, err := controller.parseObjectForm(createTestAudioForm(t, "audio.mp3", []byte("not an audio"))); err == nil {
+		t.Fatalf("expected file which is not audio to be rejected")
+	}
+}
+
+func TestAdminObjectBlobsCleanup(t *testing.T) {
+	blobProvider := blob.CreateMemoryBlobProvider()
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	objectID, _ := repo.CreateObject("object", venueID)
+	blobProvider.WriteBlob("object/cover.jpg", strings.NewReader("cover"))
+	blobProvider.WriteBlob("object/en.mp3", strings.NewReader("audio"))
+	repo.SetObjectCovers(objectID, []repository.Cover{{Index: 0, Path: "object/cover.jpg"}})
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: "Object", AudioPath: "object/en.mp3"})
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+	})
+
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile(ADMIN_FIELD_COVER, "new.jpg")
+	part.Write([]byte("new cover"))
+	writer.Close()
+
+	request := httptest.NewRequest("PUT", "/admin/objects/object", &body)
+	request.Header.Set("Authorization", TEST_ADMIN_TOKEN)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	response, responseBody := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", response.StatusCode, responseBody)
+	}
+
+	// Replaced cover is deleted, the new one is referenced by the object
+	blobs, _ := repo.GetObjectBlobs(objectID)
+	if _, err := blobProvider.StatBlob("object/cover.jpg"); err != blob.ErrNotExist || len(blobs) != 2 {
+		t.Fatalf("expected replaced cover to be deleted, got blobs %v", blobs)
+	}
+
+	request = httptest.NewRequest("DELETE", "/admin/objects/object", nil)
+	request.Header.Set("Authorization", TEST_ADMIN_TOKEN)
+	if response, _ := sendTestRequest(t, app, request); response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+
+	for _, path := range blobs {
+		if _, err := blobProvider.StatBlob(path); err != blob.ErrNotExist {
+			t.Fatalf("expected blob %s of deleted object to be deleted", path)
+		}
+	}
+}
+
+func TestAdminObjectTracks(t *testing.T) {
+	mediaProvider, _ := media.CreateNativeMediaProvider()
+	blobProvider := blob.CreateMemoryBlobProvider()
+	repo := repository.CreateMemoryRepository()
+	repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+		VenueRepository:  repo,
+		MediaProvider:    mediaProvider,
+	})
+
+	sendForm := func(method string, path string, fields map[string]string, files map[string][]byte) int {
+		body := bytes.Buffer{}
+		writer := multipart.NewWriter(&body)
+		for name, value := range fields {
+			writer.WriteField(name, value)
+		}
+		// Field names are "{FIELD}/{FILE NAME}" to repeat the same field with different files
+		for name, data := range files {
+			field, fileName, _ := strings.Cut(name, "/")
+			part, _ := writer.CreateFormFile(field, fileName)
+			part.Write(data)
+		}
+		writer.Close()
+
+		request := httptest.NewRequest(method, path, &body)
+		request.Header.Set("Authorization", TEST_ADMIN_TOKEN)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		response, _ := sendTestRequest(t, app, request)
+		return response.StatusCode
+	}
+
+	status := sendForm("POST", "/admin/objects", map[string]string{
+		ADMIN_FIELD_CODE: "object", ADMIN_FIELD_VENUE: "museum", ADMIN_FIELD_TITLE_PREFIX + "en": "Object",
+	}, map[string][]byte{
+		ADMIN_FIELD_AUDIO_PREFIX + "en/audio.mp3": createTestMP3(10),
+		ADMIN_FIELD_TRACKS_PREFIX + "en/kids.mp3": createTestMP3(5),
+	})
+	if status != http.StatusCreated {
+		t.Fatalf("unexpected create status %d", status)
+	}
+
+	object, _ := repo.GetObject("object", "en")
+	if len(object.Tracks) != 1 || object.Tracks[0].Name != "kids" || object.Tracks[0].DurationMs != 130 {
+		t.Fatalf("unexpected tracks %+v", object.Tracks)
+	}
+
+	invalid := map[string][]byte{
+		ADMIN_FIELD_TRACKS_PREFIX + "en/Kids Version.mp3": createTestMP3(5),
+		ADMIN_FIELD_TRACKS_PREFIX + "en/kids.txt":         []byte("not an audio"),
+		ADMIN_FIELD_TRACKS_PREFIX + "fr/kids.mp3":         createTestMP3(5),
+	}
+	for name, data := range invalid {
+		if status := sendForm("PUT", "/admin/objects/object", nil, map[string][]byte{name: data}); status != http.StatusBadRequest {
+			t.Fatalf("%s: expected bad request, got %d", name, status)
+		}
+	}
+
+	// Tracks of the language are replaced, the old blob is deleted
+	oldPath := object.Tracks[0].AudioPath
+	status = sendForm("PUT", "/admin/objects/object", nil, map[string][]byte{ADMIN_FIELD_TRACKS_PREFIX + "en/short.mp3": createTestMP3(3)})
+	if status != http.StatusOK {
+		t.Fatalf("unexpected update status %d", status)
+	}
+
+	object, _ = repo.GetObject("object", "en")
+	if len(object.Tracks) != 1 || object.Tracks[0].Name != "short" || object.Tracks[0].DurationMs != 78 {
+		t.Fatalf("unexpected replaced tracks %+v", object.Tracks)
+	}
+
+	if _, err := blobProvider.StatBlob(oldPath); err != blob.ErrNotExist {
+		t.Fatalf("expected replaced track to be deleted")
+	}
+}
+
+// Records names of written blobs, so uploads with generated names can be checked
+type testRecordingBlobProvider struct {
+	*blob.MemoryBlobProvider
+	written []string
+}
+
+func (provider *testRecordingBlobProvider) WriteBlob(name string, reader io.Reader) error {
+	provider.written = append(provider.written, name)
+	return provider.MemoryBlobProvider.WriteBlob(name, reader)
+}
+
+func TestAdminObjectUpdateRollback(t *testing.T) {
+	mediaProvider, _ := media.CreateNativeMediaProvider()
+	blobProvider := &testRecordingBlobProvider{MemoryBlobProvider: blob.CreateMemoryBlobProvider()}
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	objectID, _ := repo.CreateObject("object", venueID)
+	blobProvider.WriteBlob("object/cover.jpg", strings.NewReader("cover"))
+	blobProvider.WriteBlob("object/en.mp3", strings.NewReader("audio"))
+	repo.SetObjectCovers(objectID, []repository.Cover{{Index: 0, Path: "object/cover.jpg"}})
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: "Object", AudioPath: "object/en.mp3"})
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+		MediaProvider:    mediaProvider,
+	})
+
+	// Tracks are saved last, so covers, year and translation are already written when it fails
+	repo.ObjectTracksError = errors.New("tracks write failed")
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+	writer.WriteField(ADMIN_FIELD_YEAR, "1900")
+	writer.WriteField(ADMIN_FIELD_TITLE_PREFIX+"en", "Renamed")
+	part, _ := writer.CreateFormFile(ADMIN_FIELD_COVER, "new.jpg")
+	part.Write([]byte("new cover"))
+	part, _ = writer.CreateFormFile(ADMIN_FIELD_AUDIO_PREFIX+"en", "new.mp3")
+	part.Write(createTestMP3(10))
+	part, _ = writer.CreateFormFile(ADMIN_FIELD_TRACKS_PREFIX+"en", "kids.mp3")
+	part.Write(createTestMP3(5))
+	writer.Close()
+
+	request := httptest.NewRequest("PUT", "/admin/objects/object", &body)
+	request.Header.Set("Authorization", TEST_ADMIN_TOKEN)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	response, responseBody := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unexpected status %d: %s", response.StatusCode, responseBody)
+	}
+
+	object, _ := repo.GetObject("object", "en")
+	if object.Title != "Object" || object.Year != nil || object.AudioPath != "object/en.mp3" || len(object.Covers) != 1 || object.Covers[0].Path != "object/cover.jpg" {
+		t.Fatalf("expected update to be rolled back, got %+v", object)
+	}
+
+	// Blobs of the object are kept, uploaded ones are deleted
+	for _, path := range blobProvider.written {
+		_, err := blobProvider.StatBlob(path)
+		referenced := path == "object/cover.jpg" || path == "object/en.mp3"
+		if referenced && err != nil || !referenced && err != blob.ErrNotExist {
+			t.Fatalf("unexpected blob %s state: %v", path, err)
+		}
+	}
+
+	if len(blobProvider.written) != 5 {
+		t.Fatalf("expected 3 uploads after 2 initial blobs, got %v", blobProvider.written)
+	}
+}
+
+func TestAdminCoverVariantsCleanup(t *testing.T) {
+	blobProvider := blob.CreateMemoryBlobProvider()
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	objectID, _ := repo.CreateObject("object", venueID)
+	repo.SetObjectCovers(objectID, []repository.Cover{{Index: 0, Path: "object/cover.jpg"}})
+	for _, language := range []string{"en", "fr"} {
+		repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: language, Title: "Object", AudioPath: "object/" + language + ".mp3"})
+		blobProvider.WriteBlob("object/"+language+".mp3", strings.NewReader("audio"))
+	}
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+	})
+
+	writeVariants := func(path string) []string {
+		variants := []string{}
+		for _, name := range []string{"160x0.jpeg", "0x320.webp", "0x0.webp"} {
+			variants = append(variants, "variants/"+path+"/"+name)
+			blobProvider.WriteBlob(variants[len(variants)-1], strings.NewReader("variant"))
+		}
+		return variants
+	}
+
+	checkVariants := func(variants []string, exist bool) {
+		for _, variant := range variants {
+			if _, err := blobProvider.StatBlob(variant); (err == nil) != exist {
+				t.Fatalf("expected variant %s to exist: %v, got error %v", variant, exist, err)
+			}
+		}
+	}
+
+	sendRequest := func(method string, path string, body io.Reader, contentType string) {
+		request := httptest.NewRequest(method, path, body)
+		request.Header.Set("Authorization", TEST_ADMIN_TOKEN)
+		request.Header.Set("Content-Type", contentType)
+		if response, responseBody := sendTestRequest(t, app, request); response.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: unexpected status %d: %s", method, path, response.StatusCode, responseBody)
+		}
+	}
+
+	blobProvider.WriteBlob("object/cover.jpg", strings.NewReader("cover"))
+	replaced := writeVariants("object/cover.jpg")
+	// Prefix of another cover path doesn't match, since variants are listed by directory
+	unrelated := writeVariants("object/cover.jpg.old")
+
+	body := bytes.Buffer{}
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile(ADMIN_FIELD_COVER, "new.jpg")
+	part.Write([]byte("new cover"))
+	writer.Close()
+	sendRequest("PUT", "/admin/objects/object", &body, writer.FormDataContentType())
+	checkVariants(replaced, false)
+	checkVariants(unrelated, true)
+
+	object, _ := repo.GetObject("object", "en")
+	current := writeVariants(object.Covers[0].Path)
+
+	// Covers belong to the object, so deleting a translation keeps their variants
+	sendRequest("DELETE", "/admin/objects/object/translations/fr", nil, "")
+	checkVariants(current, true)
+
+	sendRequest("DELETE", "/admin/objects/object", nil, "")
+	checkVariants(current, false)
+	checkVariants(unrelated, true)
+}
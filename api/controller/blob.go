@@ -0,0 +1,283 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+)
+
+// Requests with more ranges are served with the whole blob
+const MAX_BLOB_RANGES = 16
+
+// Lifetime of blob URLs issued in redirect mode
+const BLOB_URL_EXPIRATION = 5 * time.Minute
+
+// Redirects to a short-lived blob URL if requested with redirect=true,
+// falls back to streaming if provider is not able to issue URLs
+func sendBlobOrRedirect(c *fiber.Ctx, provider blob.BlobProvider, path string) error {
+	if !c.QueryBool("redirect") {
+		return sendBlob(c, provider, path)
+	}
+
+	blobURL, err := provider.GetBlobURL(path, BLOB_URL_EXPIRATION)
+	if errors.Is(err, blob.ErrBlobURLNotSupported) {
+		return sendBlob(c, provider, path)
+	}
+
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get blob URL", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get blob URL")
+	}
+
+	// URL is valid only for a short time and must not be cached by clients
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(blobURL, fiber.StatusFound)
+}
+
+func sendWholeBlob(c *fiber.Ctx, provider blob.BlobProvider, path string, contentType string, size int64) error {
+	reader, err := provider.ReadBlob(path, blob.ReadBlobOptions{})
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Status(fiber.StatusOK)
+
+	return c.SendStream(reader, int(size))
+}
+
+// Streams a blob with support of conditional and range requests
+func sendBlob(c *fiber.Ctx, provider blob.BlobProvider, path string) error {
+	blobStat, err := provider.StatBlob(path)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Blob stat failed", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Blob stat failed")
+	}
+
+	etag := ""
+	if blobStat.ETag != "" {
+		etag = `"` + blobStat.ETag + `"`
+		c.Set(fiber.HeaderETag, etag)
+	}
+
+	if !blobStat.LastModified.IsZero() {
+		c.Set(fiber.HeaderLastModified, blobStat.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	if isBlobNotModified(c, etag, blobStat.LastModified) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	contentType := getBlobContentType(path)
+	rangesHeader := c.Get(fiber.HeaderRange)
+	if rangesHeader == "" || !isBlobRangeFresh(c, etag, blobStat.LastModified) {
+		return sendWholeBlob(c, provider, path, contentType, blobStat.Size)
+	}
+
+	// Malformed range header is ignored, see RFC 9110 section 14.2
+	units, ranges, err := parseRange(rangesHeader, blobStat.Size)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse range header, serving whole blob", "error", err)
+		return sendWholeBlob(c, provider, path, contentType, blobStat.Size)
+	}
+
+	if units != "bytes" {
+		HandlerPrintf(c, LOG_WARNING, "Incorrect range units", "error", units)
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", blobStat.Size))
+		return HandlerSendFailure(c, fiber.StatusRequestedRangeNotSatisfiable, "Incorrect range units")
+	}
+
+	if len(ranges) < 1 {
+		HandlerPrintf(c, LOG_WARNING, "No satisfiable ranges provided")
+		c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", blobStat.Size))
+		return HandlerSendFailure(c, fiber.StatusRequestedRangeNotSatisfiable, "No satisfiable ranges provided")
+	}
+
+	if len(ranges) > MAX_BLOB_RANGES {
+		HandlerPrintf(c, LOG_WARNING, "Too many ranges, serving whole blob", "ranges", len(ranges))
+		return sendWholeBlob(c, provider, path, contentType, blobStat.Size)
+	}
+
+	if len(ranges) == 1 {
+		reader, err := provider.ReadBlob(path, blob.ReadBlobOptions{Range: &ranges[0]})
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
+		}
+
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentRange, formatContentRange(ranges[0], blobStat.Size))
+		c.Status(fiber.StatusPartialContent)
+
+		return c.SendStream(reader, int(ranges[0].End-ranges[0].Start+1))
+	}
+
+	// Parts are written to the pipe while the response is being sent
+	pipeReader, pipeWriter := io.Pipe()
+	writer := multipart.NewWriter(pipeWriter)
+	go func() {
+		pipeWriter.CloseWithError(writeBlobRanges(writer, provider, path, contentType, ranges, blobStat.Size))
+	}()
+
+	c.Set(fiber.HeaderContentType, "multipart/byteranges; boundary="+writer.Boundary())
+	c.Status(fiber.StatusPartialContent)
+
+	return c.SendStream(pipeReader)
+}
+
+func writeBlobRanges(writer *multipart.Writer, provider blob.BlobProvider, path string, contentType string, ranges []blob.BlobRange, size int64) error {
+	for i := range ranges {
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			fiber.HeaderContentType:  {contentType},
+			fiber.HeaderContentRange: {formatContentRange(ranges[i], size)},
+		})
+		if err != nil {
+			return err
+		}
+
+		reader, err := provider.ReadBlob(path, blob.ReadBlobOptions{Range: &ranges[i]})
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(part, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Close()
+}
+
+func formatContentRange(blobRange blob.BlobRange, size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", blobRange.Start, blobRange.End, size)
+}
+
+func getBlobContentType(path string) string {
+	if contentType := utils.GetMIME(filepath.Ext(path)); contentType != "" {
+		return contentType
+	}
+
+	return fiber.MIMEOctetStream
+}
+
+// Implements If-None-Match and If-Modified-Since checks, If-Modified-Since is ignored if If-None-Match is passed
+func isBlobNotModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if header := c.Get(fiber.HeaderIfNoneMatch); header != "" {
+		return etag != "" && matchETag(header, etag, true)
+	}
+
+	if header := c.Get(fiber.HeaderIfModifiedSince); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
+// Implements If-Range check, range is ignored if client representation is outdated
+func isBlobRangeFresh(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	header := c.Get(fiber.HeaderIfRange)
+	if header == "" {
+		return true
+	}
+
+	if strings.HasPrefix(header, `"`) || strings.HasPrefix(header, "W/") {
+		return etag != "" && matchETag(header, etag, false)
+	}
+
+	date, err := http.ParseTime(header)
+	return err == nil && !lastModified.IsZero() && lastModified.Truncate(time.Second).Equal(date)
+}
+
+// Matches a list of entity tags, weak comparison ignores W/ prefix
+func matchETag(header string, etag string, weak bool) bool {
+	for _, item := range strings.Split(header, ",") {
+		item = strings.TrimSpace(item)
+		if item == "*" && weak {
+			return true
+		}
+
+		if weak {
+			item = strings.TrimPrefix(item, "W/")
+		}
+
+		if item == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns satisfiable ranges only, suffix ranges longer than the blob select the whole blob
+func parseRange(header string, size int64) (string, []blob.BlobRange, error) {
+	units, data, found := strings.Cut(header, "=")
+	if !found {
+		return "", nil, errors.New("malformed range header string")
+	}
+
+	units = strings.TrimSpace(units)
+	ranges := []blob.BlobRange{}
+	for _, item := range strings.Split(data, ",") {
+		startString, endString, found := strings.Cut(strings.TrimSpace(item), "-")
+		if !found {
+			return "", nil, errors.New("malformed range header string")
+		}
+
+		var start, end int64
+		if startString == "" { // -nnn
+			suffix, err := strconv.ParseInt(endString, 10, 64)
+			if err != nil || suffix < 0 {
+				return "", nil, errors.New("malformed range header string")
+			}
+
+			start = max(size-suffix, 0)
+			end = size - 1
+			if suffix == 0 {
+				continue
+			}
+		} else {
+			var err error
+			start, err = strconv.ParseInt(startString, 10, 64)
+			if err != nil || start < 0 {
+				return "", nil, errors.New("malformed range header string")
+			}
+
+			end = size - 1
+			if endString != "" { // nnn-
+				end, err = strconv.ParseInt(endString, 10, 64)
+				if err != nil || end < start {
+					return "", nil, errors.New("malformed range header string")
+				}
+			}
+		}
+
+		// limit last-byte-pos to current length
+		end = min(end, size-1)
+		if start > end {
+			continue
+		}
+
+		ranges = append(ranges, blob.BlobRange{
+			Start: start,
+			End:   end,
+		})
+	}
+
+	return units, ranges, nil
+}
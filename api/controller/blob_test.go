@@ -0,0 +1,232 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+)
+
+func sendTestAudioRequest(t *testing.T, app *fiber.App, token string, headers map[string]string) (*http.Response, []byte) {
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio?language=en&access-token="+token, nil)
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	return sendTestRequest(t, app, request)
+}
+
+func TestSendBlobMultipleRanges(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	response, body := sendTestAudioRequest(t, app, token, map[string]string{fiber.HeaderRange: "bytes=0-1, 8-"})
+	if response.StatusCode != http.StatusPartialContent {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(response.Header.Get(fiber.HeaderContentType))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("unexpected content type %q", response.Header.Get(fiber.HeaderContentType))
+	}
+
+	expected := []struct {
+		contentRange string
+		body         string
+	}{
+		{"bytes 0-1/10", "01"},
+		{"bytes 8-9/10", "89"},
+	}
+
+	reader := multipart.NewReader(strings.NewReader(string(body)), params["boundary"])
+	for i, part := range expected {
+		next, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("part %d: failed to read: %v", i, err)
+		}
+
+		data, _ := io.ReadAll(next)
+		if next.Header.Get(fiber.HeaderContentRange) != part.contentRange || string(data) != part.body {
+			t.Fatalf("part %d: unexpected range %q with body %q", i, next.Header.Get(fiber.HeaderContentRange), data)
+		}
+
+		if next.Header.Get(fiber.HeaderContentType) != "audio/mpeg" {
+			t.Fatalf("part %d: unexpected content type %q", i, next.Header.Get(fiber.HeaderContentType))
+		}
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Fatalf("expected only two parts")
+	}
+}
+
+func TestSendBlobUnsatisfiableRange(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	for _, header := range []string{"bytes=10-", "bytes=-0", "items=0-1"} {
+		response, _ := sendTestAudioRequest(t, app, token, map[string]string{fiber.HeaderRange: header})
+		if response.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("range %q: expected status 416, got %d", header, response.StatusCode)
+		}
+
+		if response.Header.Get(fiber.HeaderContentRange) != "bytes */10" {
+			t.Fatalf("range %q: unexpected content range %q", header, response.Header.Get(fiber.HeaderContentRange))
+		}
+	}
+}
+
+func TestSendBlobIgnoredRange(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	for _, header := range []string{"bytes", "bytes=a-b", "bytes=5-2", "bytes=0-1,"} {
+		response, body := sendTestAudioRequest(t, app, token, map[string]string{fiber.HeaderRange: header})
+		if response.StatusCode != http.StatusOK || string(body) != TEST_OBJECT_AUDIO {
+			t.Fatalf("range %q: expected whole blob, got %d %q", header, response.StatusCode, body)
+		}
+	}
+}
+
+func TestSendBlobConditional(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	response, _ := sendTestAudioRequest(t, app, token, nil)
+	etag := response.Header.Get(fiber.HeaderETag)
+	lastModified := response.Header.Get(fiber.HeaderLastModified)
+	if etag == "" || lastModified == "" {
+		t.Fatalf("expected validators, got ETag %q and Last-Modified %q", etag, lastModified)
+	}
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	cases := []struct {
+		headers map[string]string
+		status  int
+		body    string
+	}{
+		{map[string]string{fiber.HeaderIfNoneMatch: etag}, http.StatusNotModified, ""},
+		{map[string]string{fiber.HeaderIfNoneMatch: `"other", W/` + etag}, http.StatusNotModified, ""},
+		{map[string]string{fiber.HeaderIfNoneMatch: `"other"`}, http.StatusOK, TEST_OBJECT_AUDIO},
+		{map[string]string{fiber.HeaderIfModifiedSince: future}, http.StatusNotModified, ""},
+		{map[string]string{fiber.HeaderIfModifiedSince: past}, http.StatusOK, TEST_OBJECT_AUDIO},
+		// If-None-Match takes precedence over If-Modified-Since
+		{map[string]string{fiber.HeaderIfNoneMatch: `"other"`, fiber.HeaderIfModifiedSince: future}, http.StatusOK, TEST_OBJECT_AUDIO},
+		{map[string]string{fiber.HeaderRange: "bytes=0-1", fiber.HeaderIfRange: etag}, http.StatusPartialContent, "01"},
+		{map[string]string{fiber.HeaderRange: "bytes=0-1", fiber.HeaderIfRange: `"other"`}, http.StatusOK, TEST_OBJECT_AUDIO},
+		{map[string]string{fiber.HeaderRange: "bytes=0-1", fiber.HeaderIfRange: lastModified}, http.StatusPartialContent, "01"},
+		{map[string]string{fiber.HeaderRange: "bytes=0-1", fiber.HeaderIfRange: past}, http.StatusOK, TEST_OBJECT_AUDIO},
+	}
+
+	for _, test := range cases {
+		response, body := sendTestAudioRequest(t, app, token, test.headers)
+		if response.StatusCode != test.status || string(body) != test.body {
+			t.Fatalf("headers %v: expected %d %q, got %d %q", test.headers, test.status, test.body, response.StatusCode, body)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := map[string][][2]int64{
+		"bytes=0-0":      {{0, 0}},
+		"bytes=-20":      {{0, 9}},
+		"bytes=5-2":      nil,
+		"bytes=20-30,1-": {{1, 9}},
+		"bytes=a-b":      nil,
+		"bytes 0-1":      nil,
+	}
+
+	for header, expected := range cases {
+		_, ranges, err := parseRange(header, 10)
+		if expected == nil {
+			if err == nil {
+				t.Fatalf("range %q: expected error, got %v", header, ranges)
+			}
+			continue
+		}
+
+		if err != nil || len(ranges) != len(expected) {
+			t.Fatalf("range %q: unexpected result %v, %v", header, ranges, err)
+		}
+
+		for i := range expected {
+			if ranges[i].Start != expected[i][0] || ranges[i].End != expected[i][1] {
+				t.Fatalf("range %q: expected %v, got %v", header, expected, ranges)
+			}
+		}
+	}
+}
+
+func createTestBlobsApp(t *testing.T, params string) (*fiber.App, blob.BlobProvider) {
+	provider, err := blob.CreateFilesystemBlobProvider("file://" + t.TempDir() + params)
+	if err != nil {
+		t.Fatalf("failed to create blob provider: %v", err)
+	}
+
+	provider.WriteBlob("object/cover 1.jpg", strings.NewReader("cover"))
+	app := createTestApp(&BlobsController{
+		BlobProvider: provider,
+		URLVerifier:  provider.(blob.BlobURLVerifier),
+	})
+
+	return app, provider
+}
+
+func TestGetSignedBlob(t *testing.T) {
+	app, provider := createTestBlobsApp(t, "?url=https://api.test/blobs/&secret=test")
+
+	blobURL, err := provider.GetBlobURL("object/cover 1.jpg", time.Minute)
+	if err != nil {
+		t.Fatalf("failed to get blob URL: %v", err)
+	}
+
+	parsed, err := url.Parse(blobURL)
+	if err != nil || parsed.Host != "api.test" {
+		t.Fatalf("unexpected blob URL %q", blobURL)
+	}
+
+	response, body := sendTestRequest(t, app, httptest.NewRequest("GET", parsed.RequestURI(), nil))
+	if response.StatusCode != http.StatusOK || string(body) != "cover" {
+		t.Fatalf("unexpected blob response %d: %s", response.StatusCode, body)
+	}
+
+	query := parsed.Query()
+	query.Set("expires", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+	tampered := parsed.EscapedPath() + "?" + query.Encode()
+	response, _ = sendTestRequest(t, app, httptest.NewRequest("GET", tampered, nil))
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected tampered URL to be forbidden, got %d", response.StatusCode)
+	}
+
+	other := strings.Replace(parsed.RequestURI(), "cover%201.jpg", "other.jpg", 1)
+	response, _ = sendTestRequest(t, app, httptest.NewRequest("GET", other, nil))
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected URL of another blob to be forbidden, got %d", response.StatusCode)
+	}
+}
+
+func TestGetExpiredSignedBlob(t *testing.T) {
+	app, provider := createTestBlobsApp(t, "?url=https://api.test/blobs&secret=test")
+
+	blobURL, _ := provider.GetBlobURL("object/cover 1.jpg", -time.Minute)
+	parsed, _ := url.Parse(blobURL)
+	response, _ := sendTestRequest(t, app, httptest.NewRequest("GET", parsed.RequestURI(), nil))
+	if response.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected expired URL to be forbidden, got %d", response.StatusCode)
+	}
+}
+
+func TestGetBlobURLNotConfigured(t *testing.T) {
+	_, provider := createTestBlobsApp(t, "")
+
+	if _, err := provider.GetBlobURL("object/cover 1.jpg", time.Minute); !errors.Is(err, blob.ErrBlobURLNotSupported) {
+		t.Fatalf("expected URLs not to be supported, got %v", err)
+	}
+}
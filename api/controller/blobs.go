@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+)
+
+// Serves blobs by signed URLs issued by providers without own HTTP endpoint
+type BlobsController struct {
+	BlobProvider blob.BlobProvider
+	URLVerifier  blob.BlobURLVerifier
+}
+
+func (controller *BlobsController) GetRoutes() []Route {
+	return []Route{
+		{
+			Method:  "GET",
+			Path:    "/blobs/*",
+			Handler: controller.HandleGetBlob,
+		},
+	}
+}
+
+func (controller *BlobsController) HandleGetBlob(c *fiber.Ctx) error {
+	name, err := url.PathUnescape(c.Params("*"))
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse blob name", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse blob name")
+	}
+
+	query, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse blob URL", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse blob URL")
+	}
+
+	if !controller.URLVerifier.VerifyBlobURL(name, query) {
+		HandlerPrintf(c, LOG_WARNING, "Blob URL is invalid", "name", name)
+		return HandlerSendFailure(c, fiber.StatusForbidden, "Blob URL is invalid")
+	}
+
+	return sendBlob(c, controller.BlobProvider, name)
+}
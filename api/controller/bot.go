@@ -1,25 +1,62 @@
 package controller
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
 	"github.com/st-matskevich/audio-guide-bot/api/repository"
+	"golang.org/x/text/currency"
 )
 
 const BUY_TICKET_QUERY = "buy_ticket"
-const TICKET_CURRENCY_KEY = "TICKET_CURRENCY"
-const TICKET_PRICE_KEY = "TICKET_PRICE"
+
+// Callback query to buy a specific ticket type, formatted as "buy_ticket:{TICKET_TYPE_ID}"
+const BUY_TICKET_TYPE_QUERY_PREFIX = BUY_TICKET_QUERY + ":"
+
+// Callback query to buy a ticket for a specific venue, formatted as "buy_ticket_venue:{VENUE_ID}"
+const BUY_TICKET_VENUE_QUERY_PREFIX = BUY_TICKET_QUERY + "_venue:"
+
+// Deep link payload to open a specific venue, formatted as "venue_{VENUE_CODE}"
+const START_VENUE_PREFIX = "venue_"
+
+// Deep link payload to open a specific object, formatted as "obj_{OBJECT_CODE}"
+const START_OBJECT_PREFIX = "obj_"
+
+// Deep link payload to open a specific tour, formatted as "tour_{TOUR_ID}"
+const START_TOUR_PREFIX = "tour_"
+
+// Web App URL parameters to exchange a ticket, or to open an object or a tour directly
+const (
+	WEB_APP_TICKET_PARAM = "ticket"
+	WEB_APP_OBJECT_PARAM = "object"
+	WEB_APP_TOUR_PARAM   = "tour"
+)
+
+// Config key of the payments provider token, set per venue to receive payments to the venue account
+const PAYMENTS_TOKEN_CONFIG_KEY = "PAYMENTS_TOKEN"
 
 type BotController struct {
-	WebAppURL           string
-	BotProvider         bot.BotProvider
-	TranslationProvider translation.TranslationProvider
-	TicketRepository    repository.TicketRepository
-	ConfigRepository    repository.ConfigRepository
+	WebAppURL            string
+	BotProvider          bot.BotProvider
+	TranslationProvider  translation.TranslationProvider
+	TicketRepository     repository.TicketRepository
+	TicketTypeRepository repository.TicketTypeRepository
+	VenueRepository      repository.VenueRepository
+	ObjectRepository     repository.ObjectRepository
+	TourRepository       repository.TourRepository
+	UserRepository       repository.UserRepository
+	ConfigRepository     repository.ConfigRepository
+	// Chat that receives /support messages, support is disabled if zero
+	SupportChatID int64
 }
 
 func (controller *BotController) GetRoutes() []Route {
@@ -39,6 +76,13 @@ func (controller *BotController) HandleBotUpdate(c *fiber.Ctx) error {
 		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse input")
 	}
 
+	if sender := update.Sender(); sender != nil {
+		if err := controller.UserRepository.TrackUser(sender.Id, sender.LanguageCode, time.Now()); err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to track user in DB", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to track user in DB")
+		}
+	}
+
 	if update.CallbackQuery != nil {
 		HandlerPrintf(c, LOG_INFO, "Received callback query")
 		return controller.HandleBotCallback(c, &update)
@@ -58,17 +102,33 @@ func (controller *BotController) HandleBotUpdate(c *fiber.Ctx) error {
 }
 
 func (controller *BotController) HandleBotMessage(c *fiber.Ctx, update *bot.Update) error {
-	locale := update.Message.From.LanguageCode
+	locale, err := controller.getUserLocale(update.Message.From.Id, update.Message.From.LanguageCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get user locale", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get user locale")
+	}
 
 	if update.Message.SuccessfulPayment != nil {
 		HandlerPrintf(c, LOG_INFO, "Message type is successful payment")
-		ticketCode, err := uuid.Parse(update.Message.SuccessfulPayment.InvoicePayload)
+		payment := update.Message.SuccessfulPayment
+		ticketTypeID, ticketCode, err := parseInvoicePayload(payment.InvoicePayload)
 		if err != nil {
 			HandlerPrintf(c, LOG_ERROR, "Failed to parse bot payment payload", "error", err)
 			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to parse bot payment payload")
 		}
 
-		if err = controller.TicketRepository.CreateTicket(ticketCode.String()); err != nil {
+		purchasedAt := time.Now()
+		purchase := repository.TicketPurchase{
+			BuyerUserID:      update.Message.From.Id,
+			ChatID:           update.Message.Chat.Id,
+			TelegramChargeID: payment.TelegramPaymentChargeId,
+			ProviderChargeID: payment.ProviderPaymentChargeId,
+			Amount:           payment.TotalAmount,
+			Currency:         payment.Currency,
+			PurchasedAt:      &purchasedAt,
+		}
+
+		if err = controller.TicketRepository.CreateTicket(ticketCode.String(), ticketTypeID, purchase); err != nil {
 			HandlerPrintf(c, LOG_ERROR, "Failed to register ticket in DB", "error", err)
 			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to register ticket in DB")
 		}
@@ -88,8 +148,74 @@ func (controller *BotController) HandleBotMessage(c *fiber.Ctx, update *bot.Upda
 		return HandlerSendSuccess(c, fiber.StatusOK, nil)
 	}
 
+	if command, args, found := parseCommand(update.Message.Text); found {
+		if handler, found := controller.getCommandHandlers()[command]; found {
+			HandlerPrintf(c, LOG_INFO, "Message is bot command", "command", command)
+			return handler(c, update, locale, args)
+		}
+	}
+
+	payload := parseStartPayload(update.Message.Text)
+	if objectCode, found := strings.CutPrefix(payload, START_OBJECT_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Message is object deep link", "object", objectCode)
+		object, err := controller.getObject(objectCode, locale)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+		}
+
+		if object != nil {
+			appURL, err := controller.buildWebAppURL(WEB_APP_OBJECT_PARAM, object.Code)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to build Web App URL", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to build Web App URL")
+			}
+
+			return controller.sendDeepLinkMessage(c, update.Message.Chat.Id, locale, "MESSAGE_OPEN_OBJECT", object.Title, appURL, object.VenueID)
+		}
+
+		HandlerPrintf(c, LOG_WARNING, "Deep linked object not found", "object", objectCode)
+	}
+
+	if tourString, found := strings.CutPrefix(payload, START_TOUR_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Message is tour deep link", "tour", tourString)
+		tour, err := controller.getTour(tourString, locale)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get tour", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get tour")
+		}
+
+		if tour != nil {
+			appURL, err := controller.buildWebAppURL(WEB_APP_TOUR_PARAM, strconv.FormatInt(tour.ID, 10))
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to build Web App URL", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to build Web App URL")
+			}
+
+			return controller.sendDeepLinkMessage(c, update.Message.Chat.Id, locale, "MESSAGE_OPEN_TOUR", tour.Title, appURL, tour.VenueID)
+		}
+
+		HandlerPrintf(c, LOG_WARNING, "Deep linked tour not found", "tour", tourString)
+	}
+
+	var venue *repository.Venue
+	if venueCode, found := strings.CutPrefix(payload, START_VENUE_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Message is venue deep link", "venue", venueCode)
+		result, err := controller.VenueRepository.GetVenueByCode(venueCode)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get venue", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get venue")
+		}
+
+		if result == nil {
+			HandlerPrintf(c, LOG_WARNING, "Deep linked venue not found", "venue", venueCode)
+		}
+
+		venue = result
+	}
+
 	HandlerPrintf(c, LOG_INFO, "Responding with welcome message")
-	message, options, err := controller.buildWelcomeMessage(locale)
+	message, options, err := controller.buildWelcomeMessage(locale, venue)
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
@@ -104,7 +230,11 @@ func (controller *BotController) HandleBotMessage(c *fiber.Ctx, update *bot.Upda
 }
 
 func (controller *BotController) HandleBotCallback(c *fiber.Ctx, update *bot.Update) error {
-	locale := update.CallbackQuery.From.LanguageCode
+	locale, err := controller.getUserLocale(update.CallbackQuery.From.Id, update.CallbackQuery.From.LanguageCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get user locale", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get user locale")
+	}
 
 	if update.CallbackQuery.Data == "" {
 		HandlerPrintf(c, LOG_ERROR, "Bot update didn't include a callback data")
@@ -116,42 +246,99 @@ func (controller *BotController) HandleBotCallback(c *fiber.Ctx, update *bot.Upd
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to answer callback query")
 	}
 
+	if update.CallbackQuery.Message == nil {
+		HandlerPrintf(c, LOG_ERROR, "Bot update didn't include a callback message")
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Bot update didn't include a callback message")
+	}
+
+	chatID := update.CallbackQuery.Message.Chat.Id
 	if update.CallbackQuery.Data == BUY_TICKET_QUERY {
 		HandlerPrintf(c, LOG_INFO, "Callback query is BUY_TICKET_QUERY")
-		if update.CallbackQuery.Message == nil {
-			HandlerPrintf(c, LOG_ERROR, "Bot update didn't include a callback message")
-			return HandlerSendFailure(c, fiber.StatusBadRequest, "Bot update didn't include a callback message")
+		venues, err := controller.VenueRepository.GetVenues()
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get venues", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get venues")
+		}
+
+		if len(venues) == 0 {
+			HandlerPrintf(c, LOG_INFO, "No venues found, responding with disabled payments message")
+			return controller.sendPaymentsDisabledMessage(c, chatID, locale)
 		}
 
-		price, err := controller.getTicketPrice(c)
+		if len(venues) == 1 {
+			return controller.sendTicketTypesMessage(c, chatID, locale, venues[0].ID)
+		}
+
+		message, options, err := controller.buildVenuesMessage(locale, venues)
 		if err != nil {
-			HandlerPrintf(c, LOG_ERROR, "Failed to get ticket price", "error", err)
-			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket price")
-		} else if price == nil {
-			HandlerPrintf(c, LOG_INFO, "Ticket price is not set, responding with disabled payments message")
-			message, options, err := controller.buildPaymentsDisabledMessage(locale)
-			if err != nil {
-				HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
-				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
-			}
+			HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+		}
 
-			if err := controller.BotProvider.SendMessage(update.CallbackQuery.Message.Chat.Id, message, options); err != nil {
-				HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
-				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
-			}
+		HandlerPrintf(c, LOG_INFO, "Responding with venues", "count", len(venues))
+		if err := controller.BotProvider.SendMessage(chatID, message, options); err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+		}
 
-			return HandlerSendSuccess(c, fiber.StatusOK, nil)
+		return HandlerSendSuccess(c, fiber.StatusOK, nil)
+	}
+
+	if language, found := strings.CutPrefix(update.CallbackQuery.Data, SET_LANGUAGE_QUERY_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Callback query is SET_LANGUAGE_QUERY")
+		return controller.handleSetLanguageCallback(c, update, language)
+	}
+
+	if venueString, found := strings.CutPrefix(update.CallbackQuery.Data, BUY_TICKET_VENUE_QUERY_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Callback query is BUY_TICKET_VENUE_QUERY")
+		venueID, err := strconv.ParseInt(venueString, 10, 64)
+		if err != nil {
+			HandlerPrintf(c, LOG_WARNING, "Failed to parse venue", "error", err)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse venue")
+		}
+
+		venue, err := controller.VenueRepository.GetVenue(venueID)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get venue", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get venue")
+		}
+
+		if venue == nil {
+			HandlerPrintf(c, LOG_INFO, "Venue not found, responding with disabled payments message", "venue", venueID)
+			return controller.sendPaymentsDisabledMessage(c, chatID, locale)
+		}
+
+		return controller.sendTicketTypesMessage(c, chatID, locale, venue.ID)
+	}
+
+	if ticketTypeString, found := strings.CutPrefix(update.CallbackQuery.Data, BUY_TICKET_TYPE_QUERY_PREFIX); found {
+		HandlerPrintf(c, LOG_INFO, "Callback query is BUY_TICKET_TYPE_QUERY")
+		ticketTypeID, err := strconv.ParseInt(ticketTypeString, 10, 64)
+		if err != nil {
+			HandlerPrintf(c, LOG_WARNING, "Failed to parse ticket type", "error", err)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse ticket type")
+		}
+
+		ticketType, err := controller.TicketTypeRepository.GetTicketType(ticketTypeID)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get ticket type", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket type")
+		}
+
+		if ticketType == nil || !ticketType.Active {
+			HandlerPrintf(c, LOG_INFO, "Ticket type is not available, responding with disabled payments message", "type", ticketTypeID)
+			return controller.sendPaymentsDisabledMessage(c, chatID, locale)
 		}
 
-		invoice, err := controller.buildInvoiceData(locale, *price)
+		invoice, err := controller.buildInvoiceData(locale, *ticketType)
 		if err != nil {
 			HandlerPrintf(c, LOG_ERROR, "Failed to prepare invoice", "error", err)
 			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare invoice")
 		}
 
 		ticketCode := uuid.New()
-		HandlerPrintf(c, LOG_INFO, "Responding with invoice for ticket", "ticket", ticketCode.String())
-		if err := controller.BotProvider.SendInvoice(update.CallbackQuery.Message.Chat.Id, invoice.Title, invoice.Description, ticketCode.String(), invoice.Price, invoice.Options); err != nil {
+		HandlerPrintf(c, LOG_INFO, "Responding with invoice for ticket", "ticket", ticketCode.String(), "type", ticketType.ID)
+		if err := controller.BotProvider.SendInvoice(chatID, invoice.Title, invoice.Description, buildInvoicePayload(ticketType.ID, ticketCode), invoice.Price, invoice.Options); err != nil {
 			HandlerPrintf(c, LOG_ERROR, "Failed to send bot invoice", "error", err)
 			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot invoice")
 		}
@@ -162,8 +349,71 @@ func (controller *BotController) HandleBotCallback(c *fiber.Ctx, update *bot.Upd
 	return HandlerSendSuccess(c, fiber.StatusOK, nil)
 }
 
+func (controller *BotController) sendTicketTypesMessage(c *fiber.Ctx, chatID int64, locale string, venueID int64) error {
+	ticketTypes, err := controller.TicketTypeRepository.GetActiveTicketTypes(venueID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket types", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket types")
+	}
+
+	if len(ticketTypes) == 0 {
+		HandlerPrintf(c, LOG_INFO, "No ticket types are active, responding with disabled payments message", "venue", venueID)
+		return controller.sendPaymentsDisabledMessage(c, chatID, locale)
+	}
+
+	message, options, err := controller.buildTicketTypesMessage(locale, ticketTypes)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Responding with ticket types", "venue", venueID, "count", len(ticketTypes))
+	if err := controller.BotProvider.SendMessage(chatID, message, options); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+// Deep link message opens the object or the tour in the Web App and offers tickets for its venue
+func (controller *BotController) sendDeepLinkMessage(c *fiber.Ctx, chatID int64, locale string, messageID string, title string, appURL string, venueID int64) error {
+	message, options, err := controller.buildDeepLinkMessage(locale, messageID, title, appURL, venueID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Responding with deep link message", "url", appURL)
+	if err := controller.BotProvider.SendMessage(chatID, message, options); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *BotController) sendPaymentsDisabledMessage(c *fiber.Ctx, chatID int64, locale string) error {
+	message, options, err := controller.buildPaymentsDisabledMessage(locale)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	if err := controller.BotProvider.SendMessage(chatID, message, options); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
 func (controller *BotController) HandleBotPreCheckout(c *fiber.Ctx, update *bot.Update) error {
-	locale := update.PreCheckoutQuery.From.LanguageCode
+	locale, err := controller.getUserLocale(update.PreCheckoutQuery.From.Id, update.PreCheckoutQuery.From.LanguageCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get user locale", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get user locale")
+	}
 	acceptCheckout, errorMessage, err := controller.validatePreCheckoutQuery(c, update, locale)
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to validate pre-checkout query", "error", err)
@@ -180,22 +430,32 @@ func (controller *BotController) HandleBotPreCheckout(c *fiber.Ctx, update *bot.
 }
 
 func (controller *BotController) validatePreCheckoutQuery(c *fiber.Ctx, update *bot.Update, locale string) (bool, *string, error) {
-	price, err := controller.getTicketPrice(c)
+	ticketTypeID, ticketCode, err := parseInvoicePayload(update.PreCheckoutQuery.InvoicePayload)
 	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket price", "error", err)
+		HandlerPrintf(c, LOG_WARNING, "Pre-checkout payload is not correct")
+		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_INVALID_TICKET", locale, translation.TemplateData{})
+		if err != nil {
+			return false, nil, err
+		}
+		return false, &message, nil
+	}
+
+	ticketType, err := controller.TicketTypeRepository.GetTicketType(ticketTypeID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket type", "error", err)
 		return false, nil, err
 	}
 
-	if price == nil {
-		HandlerPrintf(c, LOG_ERROR, "Ticket price is not set")
-		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_PRICE_NOT_SET", locale, translation.TemplateData{})
+	if ticketType == nil || !ticketType.Active {
+		HandlerPrintf(c, LOG_WARNING, "Pre-checkout ticket type is not available", "type", ticketTypeID)
+		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_TICKET_NOT_AVAILABLE", locale, translation.TemplateData{})
 		if err != nil {
 			return false, nil, err
 		}
 		return false, &message, nil
 	}
 
-	if update.PreCheckoutQuery.Currency != price.Currency {
+	if update.PreCheckoutQuery.Currency != ticketType.Currency {
 		HandlerPrintf(c, LOG_WARNING, "Pre-checkout currency is not correct")
 		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_INVALID_CURRENCY", locale, translation.TemplateData{})
 		if err != nil {
@@ -204,7 +464,7 @@ func (controller *BotController) validatePreCheckoutQuery(c *fiber.Ctx, update *
 		return false, &message, nil
 	}
 
-	if update.PreCheckoutQuery.TotalAmount != price.Price {
+	if update.PreCheckoutQuery.TotalAmount != ticketType.Price {
 		HandlerPrintf(c, LOG_WARNING, "Pre-checkout price is not correct")
 		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_INVALID_PRICE", locale, translation.TemplateData{})
 		if err != nil {
@@ -213,16 +473,6 @@ func (controller *BotController) validatePreCheckoutQuery(c *fiber.Ctx, update *
 		return false, &message, nil
 	}
 
-	ticketCode, err := uuid.Parse(update.PreCheckoutQuery.InvoicePayload)
-	if err != nil {
-		HandlerPrintf(c, LOG_WARNING, "Pre-checkout payload is not correct")
-		message, err := controller.TranslationProvider.TranslateMessage("PAYMENT_FAIL_INVALID_TICKET", locale, translation.TemplateData{})
-		if err != nil {
-			return false, nil, err
-		}
-		return false, &message, nil
-	}
-
 	ticket, err := controller.TicketRepository.GetTicket(ticketCode.String())
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to get invoice ticket", "error", err)
@@ -241,47 +491,119 @@ func (controller *BotController) validatePreCheckoutQuery(c *fiber.Ctx, update *
 	return true, nil, nil
 }
 
-type TicketPrice struct {
-	Currency string
-	Price    int64
+// Invoice payload carries both ticket type and ticket code, formatted as "{TICKET_TYPE_ID}:{TICKET_CODE}"
+func buildInvoicePayload(ticketTypeID int64, ticketCode uuid.UUID) string {
+	return strconv.FormatInt(ticketTypeID, 10) + ":" + ticketCode.String()
 }
 
-func (controller *BotController) getTicketPrice(c *fiber.Ctx) (*TicketPrice, error) {
-	currency, err := controller.ConfigRepository.GetValue(TICKET_CURRENCY_KEY)
-	if err != nil {
-		return nil, err
+func parseInvoicePayload(payload string) (int64, uuid.UUID, error) {
+	ticketTypeString, ticketCodeString, found := strings.Cut(payload, ":")
+	if !found {
+		return 0, uuid.UUID{}, errors.New("malformed invoice payload")
 	}
 
-	if currency == nil {
-		HandlerPrintf(c, LOG_ERROR, "Ticket currency key not found")
-		return nil, nil
+	ticketTypeID, err := strconv.ParseInt(ticketTypeString, 10, 64)
+	if err != nil {
+		return 0, uuid.UUID{}, err
 	}
 
-	priceString, err := controller.ConfigRepository.GetValue(TICKET_PRICE_KEY)
+	ticketCode, err := uuid.Parse(ticketCodeString)
 	if err != nil {
-		return nil, err
+		return 0, uuid.UUID{}, err
+	}
+
+	return ticketTypeID, ticketCode, nil
+}
+
+// Returns payload from "/start {PAYLOAD}" deep link message, empty if message is not a deep link
+func parseStartPayload(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) != 2 || fields[0] != "/start" {
+		return ""
+	}
+
+	return fields[1]
+}
+
+// Formats price in the smallest units of the currency, e.g. 1050 USD as "10.50 USD"
+func formatTicketPrice(price int64, currencyCode string) string {
+	scale := 2
+	if unit, err := currency.ParseISO(currencyCode); err == nil {
+		scale, _ = currency.Standard.Rounding(unit)
+	}
+
+	if scale == 0 {
+		return fmt.Sprintf("%d %s", price, currencyCode)
+	}
+
+	divisor := int64(math.Pow10(scale))
+	return fmt.Sprintf("%d.%0*d %s", price/divisor, scale, price%divisor, currencyCode)
+}
+
+// Returns object translated to the locale or to the default language, nil if object is not found
+func (controller *BotController) getObject(code string, locale string) (*repository.Object, error) {
+	object, err := controller.ObjectRepository.GetObject(code, locale)
+	if err != nil || object != nil {
+		return object, err
 	}
 
-	if priceString == nil {
-		HandlerPrintf(c, LOG_ERROR, "Ticket price key not found")
+	return controller.ObjectRepository.GetObject(code, translation.DEFAULT_LANGUAGE.String())
+}
+
+// Returns nil if tour id is malformed or tour is not found
+func (controller *BotController) getTour(tourString string, locale string) (*repository.Tour, error) {
+	tourID, err := strconv.ParseInt(tourString, 10, 64)
+	if err != nil {
 		return nil, nil
 	}
 
-	price, err := strconv.ParseInt(*priceString, 10, 64)
+	return controller.TourRepository.GetTour(tourID, locale, translation.DEFAULT_LANGUAGE.String())
+}
+
+func (controller *BotController) getTicketTypeTranslation(ticketType repository.TicketType, locale string) (repository.TicketTypeTranslation, error) {
+	result, err := controller.TicketTypeRepository.GetTicketTypeTranslation(ticketType.ID, locale)
 	if err != nil {
-		return nil, err
+		return repository.TicketTypeTranslation{}, err
 	}
 
-	result := TicketPrice{
-		Currency: *currency,
-		Price:    price,
+	if result == nil {
+		// fallback to default language
+		result, err = controller.TicketTypeRepository.GetTicketTypeTranslation(ticketType.ID, translation.DEFAULT_LANGUAGE.String())
+		if err != nil {
+			return repository.TicketTypeTranslation{}, err
+		}
+	}
+
+	if result == nil {
+		// fallback to generic ticket strings
+		title, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_TITLE", locale, translation.TemplateData{})
+		if err != nil {
+			return repository.TicketTypeTranslation{}, err
+		}
+
+		description, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_DESCRIPTION", locale, translation.TemplateData{})
+		if err != nil {
+			return repository.TicketTypeTranslation{}, err
+		}
+
+		result = &repository.TicketTypeTranslation{Title: title, Description: description}
 	}
 
-	return &result, nil
+	return *result, nil
 }
 
-func (controller *BotController) buildWelcomeMessage(locale string) (string, bot.SendMessageOptions, error) {
-	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_WELCOME", locale, translation.TemplateData{})
+// Welcome message for a venue offers tickets only for that venue
+func (controller *BotController) buildWelcomeMessage(locale string, venue *repository.Venue) (string, bot.SendMessageOptions, error) {
+	messageID := "MESSAGE_WELCOME"
+	messageData := translation.TemplateData{}
+	callbackQuery := BUY_TICKET_QUERY
+	if venue != nil {
+		messageID = "MESSAGE_WELCOME_VENUE"
+		messageData["VENUE"] = venue.Name
+		callbackQuery = BUY_TICKET_VENUE_QUERY_PREFIX + strconv.FormatInt(venue.ID, 10)
+	}
+
+	message, err := controller.TranslationProvider.TranslateMessage(messageID, locale, messageData)
 	if err != nil {
 		return "", bot.SendMessageOptions{}, err
 	}
@@ -297,7 +619,6 @@ func (controller *BotController) buildWelcomeMessage(locale string) (string, bot
 	}
 
 	appURL := controller.WebAppURL
-	callbackQuery := BUY_TICKET_QUERY
 	opts := bot.SendMessageOptions{
 		InlineKeyboard: &bot.InlineKeyboardMarkup{
 			Markup: [][]bot.InlineKeyboardButton{{
@@ -311,6 +632,36 @@ func (controller *BotController) buildWelcomeMessage(locale string) (string, bot
 	return message, opts, nil
 }
 
+func (controller *BotController) buildDeepLinkMessage(locale string, messageID string, title string, appURL string, venueID int64) (string, bot.SendMessageOptions, error) {
+	message, err := controller.TranslationProvider.TranslateMessage(messageID, locale, translation.TemplateData{"TITLE": title})
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
+	openText, err := controller.TranslationProvider.TranslateMessage("BUTTON_OPEN", locale, translation.TemplateData{})
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
+	buyTicketText, err := controller.TranslationProvider.TranslateMessage("BUTTON_BUY_TICKET", locale, translation.TemplateData{})
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
+	callbackQuery := BUY_TICKET_VENUE_QUERY_PREFIX + strconv.FormatInt(venueID, 10)
+	opts := bot.SendMessageOptions{
+		InlineKeyboard: &bot.InlineKeyboardMarkup{
+			Markup: [][]bot.InlineKeyboardButton{{
+				{Text: openText, WebAppURL: &appURL},
+			}, {
+				{Text: buyTicketText, CallbackData: &callbackQuery},
+			}},
+		},
+	}
+
+	return message, opts, nil
+}
+
 func (controller *BotController) buildPurchaseMessage(locale string, ticketCode string) (string, bot.SendMessageOptions, error) {
 	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_PURCHASED_TICKET", locale, translation.TemplateData{"TICKET_CODE": ticketCode})
 	if err != nil {
@@ -322,7 +673,11 @@ func (controller *BotController) buildPurchaseMessage(locale string, ticketCode
 		return "", bot.SendMessageOptions{}, err
 	}
 
-	appURL := controller.WebAppURL + "?ticket=" + ticketCode
+	appURL, err := controller.buildWebAppURL(WEB_APP_TICKET_PARAM, ticketCode)
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
 	opts := bot.SendMessageOptions{
 		InlineKeyboard: &bot.InlineKeyboardMarkup{
 			Markup: [][]bot.InlineKeyboardButton{{
@@ -334,6 +689,61 @@ func (controller *BotController) buildPurchaseMessage(locale string, ticketCode
 	return message, opts, nil
 }
 
+func (controller *BotController) buildVenuesMessage(locale string, venues []repository.Venue) (string, bot.SendMessageOptions, error) {
+	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_CHOOSE_VENUE", locale, translation.TemplateData{})
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
+	markup := [][]bot.InlineKeyboardButton{}
+	for _, venue := range venues {
+		callbackQuery := BUY_TICKET_VENUE_QUERY_PREFIX + strconv.FormatInt(venue.ID, 10)
+		markup = append(markup, []bot.InlineKeyboardButton{{Text: venue.Name, CallbackData: &callbackQuery}})
+	}
+
+	opts := bot.SendMessageOptions{
+		InlineKeyboard: &bot.InlineKeyboardMarkup{
+			Markup: markup,
+		},
+	}
+
+	return message, opts, nil
+}
+
+func (controller *BotController) buildTicketTypesMessage(locale string, ticketTypes []repository.TicketType) (string, bot.SendMessageOptions, error) {
+	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_CHOOSE_TICKET", locale, translation.TemplateData{})
+	if err != nil {
+		return "", bot.SendMessageOptions{}, err
+	}
+
+	markup := [][]bot.InlineKeyboardButton{}
+	for _, ticketType := range ticketTypes {
+		ticketTypeTranslation, err := controller.getTicketTypeTranslation(ticketType, locale)
+		if err != nil {
+			return "", bot.SendMessageOptions{}, err
+		}
+
+		text, err := controller.TranslationProvider.TranslateMessage("BUTTON_TICKET_TYPE", locale, translation.TemplateData{
+			"TITLE": ticketTypeTranslation.Title,
+			"PRICE": formatTicketPrice(ticketType.Price, ticketType.Currency),
+		})
+		if err != nil {
+			return "", bot.SendMessageOptions{}, err
+		}
+
+		callbackQuery := BUY_TICKET_TYPE_QUERY_PREFIX + strconv.FormatInt(ticketType.ID, 10)
+		markup = append(markup, []bot.InlineKeyboardButton{{Text: text, CallbackData: &callbackQuery}})
+	}
+
+	opts := bot.SendMessageOptions{
+		InlineKeyboard: &bot.InlineKeyboardMarkup{
+			Markup: markup,
+		},
+	}
+
+	return message, opts, nil
+}
+
 func (controller *BotController) buildPaymentsDisabledMessage(locale string) (string, bot.SendMessageOptions, error) {
 	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_PAYMENTS_NOT_AVAILABLE", locale, translation.TemplateData{})
 	if err != nil {
@@ -343,6 +753,20 @@ func (controller *BotController) buildPaymentsDisabledMessage(locale string) (st
 	return message, bot.SendMessageOptions{}, nil
 }
 
+// Adds the parameter to the Web App URL keeping its existing query
+func (controller *BotController) buildWebAppURL(param string, value string) (string, error) {
+	appURL, err := url.Parse(controller.WebAppURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := appURL.Query()
+	query.Set(param, value)
+	appURL.RawQuery = query.Encode()
+
+	return appURL.String(), nil
+}
+
 type InvoiceData struct {
 	Title       string
 	Description string
@@ -350,23 +774,23 @@ type InvoiceData struct {
 	Options     bot.SendInvoiceOptions
 }
 
-func (controller *BotController) buildInvoiceData(locale string, price TicketPrice) (InvoiceData, error) {
-	title, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_TITLE", locale, translation.TemplateData{})
+func (controller *BotController) buildInvoiceData(locale string, ticketType repository.TicketType) (InvoiceData, error) {
+	ticketTypeTranslation, err := controller.getTicketTypeTranslation(ticketType, locale)
 	if err != nil {
 		return InvoiceData{}, err
 	}
 
-	description, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_DESCRIPTION", locale, translation.TemplateData{})
+	priceLabel, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_PRICE_PART_PRICE", locale, translation.TemplateData{})
 	if err != nil {
 		return InvoiceData{}, err
 	}
 
-	priceLabel, err := controller.TranslationProvider.TranslateMessage("PAYMENT_TICKET_PRICE_PART_PRICE", locale, translation.TemplateData{})
+	payText, err := controller.TranslationProvider.TranslateMessage("BUTTON_PAY", locale, translation.TemplateData{})
 	if err != nil {
 		return InvoiceData{}, err
 	}
 
-	payText, err := controller.TranslationProvider.TranslateMessage("BUTTON_PAY", locale, translation.TemplateData{})
+	providerToken, err := controller.ConfigRepository.GetVenueValue(ticketType.VenueID, PAYMENTS_TOKEN_CONFIG_KEY)
 	if err != nil {
 		return InvoiceData{}, err
 	}
@@ -378,14 +802,15 @@ func (controller *BotController) buildInvoiceData(locale string, price TicketPri
 				{Text: payText, Pay: &pay},
 			}},
 		},
+		ProviderToken: providerToken,
 	}
 
 	data := InvoiceData{
-		Title:       title,
-		Description: description,
+		Title:       ticketTypeTranslation.Title,
+		Description: ticketTypeTranslation.Description,
 		Price: bot.InvoicePrice{
-			Currency: price.Currency,
-			Parts:    []bot.PricePart{{Label: priceLabel, Amount: price.Price}},
+			Currency: ticketType.Currency,
+			Parts:    []bot.PricePart{{Label: priceLabel, Amount: ticketType.Price}},
 		},
 		Options: opts,
 	}
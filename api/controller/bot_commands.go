@@ -0,0 +1,293 @@
+package controller
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
+)
+
+// Bot commands, any other message is answered with the welcome message
+const (
+	COMMAND_HELP      = "help"
+	COMMAND_MYTICKETS = "mytickets"
+	COMMAND_LANGUAGE  = "language"
+	COMMAND_SUPPORT   = "support"
+)
+
+// Callback query to change the bot language, formatted as "set_language:{LANGUAGE}"
+const SET_LANGUAGE_QUERY_PREFIX = "set_language:"
+
+const MAX_LISTED_TICKETS = 10
+const TICKET_EXPIRY_FORMAT = "02.01.2006 15:04"
+
+// Commands shown in the bot menu with their description message IDs
+var BOT_MENU_COMMANDS = []struct {
+	Command       string
+	DescriptionID string
+}{
+	{Command: COMMAND_HELP, DescriptionID: "COMMAND_HELP_DESCRIPTION"},
+	{Command: COMMAND_MYTICKETS, DescriptionID: "COMMAND_MYTICKETS_DESCRIPTION"},
+	{Command: COMMAND_LANGUAGE, DescriptionID: "COMMAND_LANGUAGE_DESCRIPTION"},
+	{Command: COMMAND_SUPPORT, DescriptionID: "COMMAND_SUPPORT_DESCRIPTION"},
+}
+
+// Handles a bot command, args contain the message text after the command
+type botCommandHandler func(c *fiber.Ctx, update *bot.Update, locale string, args string) error
+
+func (controller *BotController) getCommandHandlers() map[string]botCommandHandler {
+	return map[string]botCommandHandler{
+		COMMAND_HELP:      controller.handleHelpCommand,
+		COMMAND_MYTICKETS: controller.handleMyTicketsCommand,
+		COMMAND_LANGUAGE:  controller.handleLanguageCommand,
+		COMMAND_SUPPORT:   controller.handleSupportCommand,
+	}
+}
+
+// Registers menu commands for each supported language, commands in the default language are shown to other users
+func (controller *BotController) RegisterCommands() error {
+	languages := []string{""}
+	for _, language := range translation.SUPPORTED_LANGUAGES {
+		languages = append(languages, language.String())
+	}
+
+	for _, language := range languages {
+		locale := language
+		if locale == "" {
+			locale = translation.DEFAULT_LANGUAGE.String()
+		}
+
+		commands := []bot.BotCommand{}
+		for _, command := range BOT_MENU_COMMANDS {
+			description, err := controller.TranslationProvider.TranslateMessage(command.DescriptionID, locale, translation.TemplateData{})
+			if err != nil {
+				return err
+			}
+
+			commands = append(commands, bot.BotCommand{Command: command.Command, Description: description})
+		}
+
+		if err := controller.BotProvider.SetMyCommands(commands, language); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (controller *BotController) handleHelpCommand(c *fiber.Ctx, update *bot.Update, locale string, args string) error {
+	return controller.sendTextMessage(c, update.Message.Chat.Id, locale, "MESSAGE_HELP", translation.TemplateData{})
+}
+
+func (controller *BotController) handleMyTicketsCommand(c *fiber.Ctx, update *bot.Update, locale string, args string) error {
+	tickets, err := controller.TicketRepository.GetUserTickets(update.Message.From.Id, MAX_LISTED_TICKETS)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get user tickets", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get user tickets")
+	}
+
+	if len(tickets) == 0 {
+		HandlerPrintf(c, LOG_INFO, "User has no tickets")
+		return controller.sendTextMessage(c, update.Message.Chat.Id, locale, "MESSAGE_NO_TICKETS", translation.TemplateData{})
+	}
+
+	header, err := controller.TranslationProvider.TranslateMessage("MESSAGE_TICKETS", locale, translation.TemplateData{})
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	lines := []string{header}
+	markup := [][]bot.InlineKeyboardButton{}
+	currentTime := time.Now()
+	for index, ticket := range tickets {
+		ticketTypeTranslation, err := controller.getTicketTypeTranslation(ticket.Type, locale)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get ticket type translation", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket type translation")
+		}
+
+		messageID := "TICKET_NOT_ACTIVATED"
+		data := translation.TemplateData{
+			"NUMBER": index + 1,
+			"TITLE":  ticketTypeTranslation.Title,
+			"CODE":   ticket.Code,
+		}
+
+		if ticket.RevokedAt != nil {
+			messageID = "TICKET_REVOKED"
+		} else if ticket.ActivatedAt != nil {
+			expires, err := getTicketExpiry(ticket.Type, *ticket.ActivatedAt)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to get ticket expiry", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket expiry")
+			}
+
+			messageID = "TICKET_ACTIVE"
+			data["EXPIRES"] = expires.Format(TICKET_EXPIRY_FORMAT)
+			if !expires.After(currentTime) {
+				messageID = "TICKET_EXPIRED"
+			}
+		}
+
+		line, err := controller.TranslationProvider.TranslateMessage(messageID, locale, data)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+		}
+		lines = append(lines, line)
+
+		if messageID == "TICKET_EXPIRED" || messageID == "TICKET_REVOKED" {
+			continue
+		}
+
+		buttonText, err := controller.TranslationProvider.TranslateMessage("BUTTON_OPEN_TICKET", locale, data)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+		}
+
+		appURL, err := controller.buildWebAppURL(WEB_APP_TICKET_PARAM, ticket.Code)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to build Web App URL", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to build Web App URL")
+		}
+
+		markup = append(markup, []bot.InlineKeyboardButton{{Text: buttonText, WebAppURL: &appURL}})
+	}
+
+	options := bot.SendMessageOptions{}
+	if len(markup) > 0 {
+		options.InlineKeyboard = &bot.InlineKeyboardMarkup{Markup: markup}
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Responding with user tickets", "count", len(tickets))
+	if err := controller.BotProvider.SendMessage(update.Message.Chat.Id, strings.Join(lines, "\n\n"), options); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *BotController) handleLanguageCommand(c *fiber.Ctx, update *bot.Update, locale string, args string) error {
+	message, err := controller.TranslationProvider.TranslateMessage("MESSAGE_CHOOSE_LANGUAGE", locale, translation.TemplateData{})
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	// Each language is named in that language
+	markup := [][]bot.InlineKeyboardButton{}
+	for _, language := range translation.SUPPORTED_LANGUAGES {
+		name, err := controller.TranslationProvider.TranslateMessage("LANGUAGE_NAME", language.String(), translation.TemplateData{})
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+		}
+
+		callbackQuery := SET_LANGUAGE_QUERY_PREFIX + language.String()
+		markup = append(markup, []bot.InlineKeyboardButton{{Text: name, CallbackData: &callbackQuery}})
+	}
+
+	options := bot.SendMessageOptions{InlineKeyboard: &bot.InlineKeyboardMarkup{Markup: markup}}
+	if err := controller.BotProvider.SendMessage(update.Message.Chat.Id, message, options); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+func (controller *BotController) handleSetLanguageCallback(c *fiber.Ctx, update *bot.Update, language string) error {
+	if !isSupportedLanguage(language) {
+		HandlerPrintf(c, LOG_WARNING, "Language is not supported", "language", language)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Language is not supported")
+	}
+
+	if err := controller.UserRepository.SetUserLanguage(update.CallbackQuery.From.Id, language); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to set user language", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to set user language")
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Changed user language", "language", language)
+	return controller.sendTextMessage(c, update.CallbackQuery.Message.Chat.Id, language, "MESSAGE_LANGUAGE_CHANGED", translation.TemplateData{})
+}
+
+// Forwards the command message to the operator chat, so operators can see the sender and reply directly
+func (controller *BotController) handleSupportCommand(c *fiber.Ctx, update *bot.Update, locale string, args string) error {
+	chatID := update.Message.Chat.Id
+	if controller.SupportChatID == 0 {
+		HandlerPrintf(c, LOG_INFO, "Support chat is not configured")
+		return controller.sendTextMessage(c, chatID, locale, "MESSAGE_SUPPORT_NOT_AVAILABLE", translation.TemplateData{})
+	}
+
+	if args == "" {
+		return controller.sendTextMessage(c, chatID, locale, "MESSAGE_SUPPORT_USAGE", translation.TemplateData{})
+	}
+
+	if err := controller.BotProvider.ForwardMessage(controller.SupportChatID, chatID, update.Message.MessageId); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to forward message to support", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to forward message to support")
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Forwarded message to support")
+	return controller.sendTextMessage(c, chatID, locale, "MESSAGE_SUPPORT_SENT", translation.TemplateData{})
+}
+
+func (controller *BotController) sendTextMessage(c *fiber.Ctx, chatID int64, locale string, messageID string, data translation.TemplateData) error {
+	message, err := controller.TranslationProvider.TranslateMessage(messageID, locale, data)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to prepare message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to prepare message")
+	}
+
+	if err := controller.BotProvider.SendMessage(chatID, message, bot.SendMessageOptions{}); err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to send bot message", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to send bot message")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, nil)
+}
+
+// Language chosen with /language takes precedence over the Telegram client language
+func (controller *BotController) getUserLocale(userID int64, clientLanguage string) (string, error) {
+	user, err := controller.UserRepository.GetUser(userID)
+	if err != nil {
+		return "", err
+	}
+
+	if user != nil && user.Language != "" {
+		return user.Language, nil
+	}
+
+	return clientLanguage, nil
+}
+
+// Returns command name and arguments from "/{COMMAND}[@{BOT_USERNAME}] {ARGS}" message
+func parseCommand(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, "/") {
+		return "", "", false
+	}
+
+	end := strings.IndexFunc(text, unicode.IsSpace)
+	if end == -1 {
+		end = len(text)
+	}
+
+	command, _, _ := strings.Cut(text[1:end], "@")
+	return command, strings.TrimSpace(text[end:]), command != ""
+}
+
+func isSupportedLanguage(value string) bool {
+	for _, language := range translation.SUPPORTED_LANGUAGES {
+		if language.String() == value {
+			return true
+		}
+	}
+
+	return false
+}
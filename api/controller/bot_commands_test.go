@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+func TestParseCommand(t *testing.T) {
+	cases := []struct {
+		text    string
+		command string
+		args    string
+		found   bool
+	}{
+		{text: "/help", command: "help", found: true},
+		{text: "/support@guide_bot  I lost my ticket ", command: "support", args: "I lost my ticket", found: true},
+		{text: "/support\nline 1\nline 2", command: "support", args: "line 1\nline 2", found: true},
+		{text: "help", found: false},
+		{text: "/", found: false},
+	}
+
+	for _, testCase := range cases {
+		command, args, found := parseCommand(testCase.text)
+		if command != testCase.command || args != testCase.args || found != testCase.found {
+			t.Fatalf("%q: unexpected result %q, %q, %v", testCase.text, command, args, found)
+		}
+	}
+}
+
+func TestBotRegisterCommands(t *testing.T) {
+	controller, botProvider, _ := createTestBotController()
+	if err := controller.RegisterCommands(); err != nil {
+		t.Fatalf("failed to register commands: %v", err)
+	}
+
+	// Default commands and commands for each supported language
+	if len(botProvider.Commands) != len(translation.SUPPORTED_LANGUAGES)+1 {
+		t.Fatalf("unexpected commands %+v", botProvider.Commands)
+	}
+
+	commands := botProvider.Commands[""]
+	if len(commands) != len(BOT_MENU_COMMANDS) || commands[0].Command != COMMAND_HELP || commands[0].Description != "COMMAND_HELP_DESCRIPTION" {
+		t.Fatalf("unexpected default commands %+v", commands)
+	}
+}
+
+func TestBotHelpCommand(t *testing.T) {
+	controller, botProvider, _ := createTestBotController()
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/help"}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_HELP" {
+		t.Fatalf("expected help message, got %+v", botProvider.Messages)
+	}
+
+	// Unknown commands are answered with the welcome message
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/unknown"}))
+	if len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_WELCOME" {
+		t.Fatalf("expected welcome message, got %+v", botProvider.Messages)
+	}
+}
+
+func TestBotMyTicketsCommand(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	ticketTypeID := addTestTicketType(repo, 500, true)
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/mytickets"}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_NO_TICKETS" {
+		t.Fatalf("expected no tickets message, got %+v", botProvider.Messages)
+	}
+
+	expiredCode := uuid.New().String()
+	repo.CreateTicket(expiredCode, ticketTypeID, repository.TicketPurchase{BuyerUserID: TEST_USER_ID})
+	repo.ActivateTicket(expiredCode, time.Now().AddDate(0, 0, -10), 0)
+	activeCode := uuid.New().String()
+	repo.CreateTicket(activeCode, ticketTypeID, repository.TicketPurchase{BuyerUserID: TEST_USER_ID})
+	repo.ActivateTicket(activeCode, time.Now(), 0)
+	newCode := uuid.New().String()
+	repo.CreateTicket(newCode, ticketTypeID, repository.TicketPurchase{BuyerUserID: TEST_USER_ID})
+	revokedCode := uuid.New().String()
+	repo.CreateTicket(revokedCode, ticketTypeID, repository.TicketPurchase{BuyerUserID: TEST_USER_ID})
+	repo.RevokeTicket(revokedCode, time.Now())
+	repo.CreateTicket(uuid.New().String(), ticketTypeID, repository.TicketPurchase{BuyerUserID: TEST_USER_ID + 1})
+
+	botProvider.Messages = nil
+	response, _ := sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/mytickets"}))
+	if response.StatusCode != http.StatusOK || len(botProvider.Messages) != 1 {
+		t.Fatalf("unexpected response %d, messages %+v", response.StatusCode, botProvider.Messages)
+	}
+
+	// Newest tickets are listed first
+	message := botProvider.Messages[0]
+	expected := strings.Join([]string{"MESSAGE_TICKETS", "TICKET_REVOKED", "TICKET_NOT_ACTIVATED", "TICKET_ACTIVE", "TICKET_EXPIRED"}, "\n\n")
+	if message.Text != expected {
+		t.Fatalf("unexpected tickets message %q", message.Text)
+	}
+
+	// Expired and revoked tickets can't be opened
+	markup := message.Options.InlineKeyboard.Markup
+	if len(markup) != 2 || !strings.HasSuffix(*markup[0][0].WebAppURL, "?ticket="+newCode) || !strings.HasSuffix(*markup[1][0].WebAppURL, "?ticket="+activeCode) {
+		t.Fatalf("unexpected ticket buttons %+v", markup)
+	}
+}
+
+func TestBotLanguageCommand(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/language"}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_CHOOSE_LANGUAGE" {
+		t.Fatalf("expected language message, got %+v", botProvider.Messages)
+	}
+
+	markup := botProvider.Messages[0].Options.InlineKeyboard.Markup
+	if len(markup) != len(translation.SUPPORTED_LANGUAGES) || *markup[1][0].CallbackData != SET_LANGUAGE_QUERY_PREFIX+"be" {
+		t.Fatalf("unexpected language buttons %+v", markup)
+	}
+
+	response, _ := sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(SET_LANGUAGE_QUERY_PREFIX+"be"))
+	if response.StatusCode != http.StatusOK || len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_LANGUAGE_CHANGED" {
+		t.Fatalf("expected language changed message, got %+v", botProvider.Messages)
+	}
+
+	user, _ := repo.GetUser(TEST_USER_ID)
+	if user == nil || user.Language != "be" {
+		t.Fatalf("user language was not saved, got %+v", user)
+	}
+
+	locale, _ := controller.getUserLocale(TEST_USER_ID, "en")
+	if locale != "be" {
+		t.Fatalf("expected chosen language to be used, got %q", locale)
+	}
+
+	response, _ = sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(SET_LANGUAGE_QUERY_PREFIX+"de"))
+	if response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected unsupported language to be rejected, got %d", response.StatusCode)
+	}
+}
+
+func TestBotSupportCommand(t *testing.T) {
+	controller, botProvider, _ := createTestBotController()
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/support Help"}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_SUPPORT_NOT_AVAILABLE" {
+		t.Fatalf("expected support not available message, got %+v", botProvider.Messages)
+	}
+
+	controller.SupportChatID = -100
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/support"}))
+	if len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_SUPPORT_USAGE" || len(botProvider.ForwardedMessages) != 0 {
+		t.Fatalf("expected support usage message, got %+v", botProvider.Messages)
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/support I can't open my ticket"}))
+	if len(botProvider.Messages) != 3 || botProvider.Messages[2].Text != "MESSAGE_SUPPORT_SENT" {
+		t.Fatalf("expected support sent message, got %+v", botProvider.Messages)
+	}
+
+	forwarded := botProvider.ForwardedMessages
+	if len(forwarded) != 1 || forwarded[0].ChatID != -100 || forwarded[0].FromChatID != TEST_CHAT_ID || forwarded[0].MessageID != 3 {
+		t.Fatalf("unexpected forwarded messages %+v", forwarded)
+	}
+}
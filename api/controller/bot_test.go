@@ -0,0 +1,447 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const TEST_CHAT_ID = 100
+const TEST_USER_ID = 200
+const TEST_VENUE_CODE = "museum"
+
+func createTestBotController() (*BotController, *bot.MemoryBotProvider, *repository.MemoryRepository) {
+	botProvider := bot.CreateMemoryBotProvider()
+	repo := repository.CreateMemoryRepository()
+	controller := BotController{
+		WebAppURL:            "https://guide.test",
+		BotProvider:          botProvider,
+		TranslationProvider:  &testTranslationProvider{},
+		TicketRepository:     repo,
+		TicketTypeRepository: repo,
+		VenueRepository:      repo,
+		ObjectRepository:     repo,
+		TourRepository:       repo,
+		UserRepository:       repo,
+		ConfigRepository:     repo,
+	}
+
+	repo.AddVenue(repository.Venue{Code: TEST_VENUE_CODE, Name: "Museum"})
+	return &controller, botProvider, repo
+}
+
+func createTestUser() map[string]interface{} {
+	return map[string]interface{}{"id": TEST_USER_ID, "is_bot": false, "first_name": "Test", "language_code": "en"}
+}
+
+func createTestCallbackUpdate(data string) map[string]interface{} {
+	return map[string]interface{}{
+		"update_id": 1,
+		"callback_query": map[string]interface{}{
+			"id":            "callback",
+			"from":          createTestUser(),
+			"chat_instance": "instance",
+			"data":          data,
+			"message": map[string]interface{}{
+				"message_id": 1,
+				"date":       0,
+				"chat":       map[string]interface{}{"id": TEST_CHAT_ID, "type": "private"},
+			},
+		},
+	}
+}
+
+func createTestPreCheckoutUpdate(currency string, amount int64, payload string) map[string]interface{} {
+	return map[string]interface{}{
+		"update_id": 2,
+		"pre_checkout_query": map[string]interface{}{
+			"id":              "checkout",
+			"from":            createTestUser(),
+			"currency":        currency,
+			"total_amount":    amount,
+			"invoice_payload": payload,
+		},
+	}
+}
+
+func createTestMessageUpdate(message map[string]interface{}) map[string]interface{} {
+	message["message_id"] = 3
+	message["date"] = 0
+	message["from"] = createTestUser()
+	message["chat"] = map[string]interface{}{"id": TEST_CHAT_ID, "type": "private"}
+
+	return map[string]interface{}{
+		"update_id": 3,
+		"message":   message,
+	}
+}
+
+func createTestPaymentUpdate(currency string, amount int64, payload string) map[string]interface{} {
+	return createTestMessageUpdate(map[string]interface{}{
+		"successful_payment": map[string]interface{}{
+			"currency":                   currency,
+			"total_amount":               amount,
+			"invoice_payload":            payload,
+			"telegram_payment_charge_id": "telegram-charge",
+			"provider_payment_charge_id": "provider-charge",
+		},
+	})
+}
+
+// Adds a ticket type to the test venue
+func addTestTicketType(repo *repository.MemoryRepository, price int64, active bool) int64 {
+	venue, _ := repo.GetVenueByCode(TEST_VENUE_CODE)
+	return repo.AddTicketType(repository.TicketType{
+		VenueID:      venue.ID,
+		Name:         "Standard",
+		Price:        price,
+		Currency:     "USD",
+		Activations:  1,
+		ValidityDays: 1,
+		TimeZone:     "UTC",
+		Active:       active,
+	})
+}
+
+func TestBotWelcomeMessage(t *testing.T) {
+	controller, botProvider, _ := createTestBotController()
+	app := createTestApp(controller)
+
+	response, _ := sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "hello"}))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d", response.StatusCode)
+	}
+
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_WELCOME" {
+		t.Fatalf("expected welcome message, got %+v", botProvider.Messages)
+	}
+
+	if botProvider.Messages[0].ChatID != TEST_CHAT_ID {
+		t.Fatalf("message sent to wrong chat %d", botProvider.Messages[0].ChatID)
+	}
+}
+
+func TestBotVenueDeepLink(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	venue, _ := repo.GetVenueByCode(TEST_VENUE_CODE)
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_VENUE_PREFIX + TEST_VENUE_CODE}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_WELCOME_VENUE" {
+		t.Fatalf("expected venue welcome message, got %+v", botProvider.Messages)
+	}
+
+	button := botProvider.Messages[0].Options.InlineKeyboard.Markup[1][0]
+	if button.CallbackData == nil || *button.CallbackData != BUY_TICKET_VENUE_QUERY_PREFIX+strconv.FormatInt(venue.ID, 10) {
+		t.Fatalf("buy button doesn't point to the venue")
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_VENUE_PREFIX + "unknown"}))
+	if len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_WELCOME" {
+		t.Fatalf("expected generic welcome message for unknown venue, got %+v", botProvider.Messages)
+	}
+}
+
+func TestBotObjectDeepLink(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	venue, _ := repo.GetVenueByCode(TEST_VENUE_CODE)
+	objectID, _ := repo.CreateObject("painting-1", venue.ID)
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: "Painting", AudioPath: "painting-1/audio-en.mp3"})
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_OBJECT_PREFIX + "painting-1"}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_OPEN_OBJECT" {
+		t.Fatalf("expected object message, got %+v", botProvider.Messages)
+	}
+
+	markup := botProvider.Messages[0].Options.InlineKeyboard.Markup
+	if markup[0][0].WebAppURL == nil || *markup[0][0].WebAppURL != "https://guide.test?object=painting-1" {
+		t.Fatalf("open button doesn't point to the object")
+	}
+
+	if markup[1][0].CallbackData == nil || *markup[1][0].CallbackData != BUY_TICKET_VENUE_QUERY_PREFIX+strconv.FormatInt(venue.ID, 10) {
+		t.Fatalf("buy button doesn't point to the object venue")
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_OBJECT_PREFIX + "unknown"}))
+	if len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_WELCOME" {
+		t.Fatalf("expected generic welcome message for unknown object, got %+v", botProvider.Messages)
+	}
+}
+
+func TestBotTourDeepLink(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	venue, _ := repo.GetVenueByCode(TEST_VENUE_CODE)
+	tourID := repo.AddTour(venue.ID, map[string]repository.TourTranslation{"en": {Title: "Highlights"}}, []int64{})
+	app := createTestApp(controller)
+
+	tourString := strconv.FormatInt(tourID, 10)
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_TOUR_PREFIX + tourString}))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_OPEN_TOUR" {
+		t.Fatalf("expected tour message, got %+v", botProvider.Messages)
+	}
+
+	button := botProvider.Messages[0].Options.InlineKeyboard.Markup[0][0]
+	if button.WebAppURL == nil || *button.WebAppURL != "https://guide.test?tour="+tourString {
+		t.Fatalf("open button doesn't point to the tour")
+	}
+
+	for _, payload := range []string{"100", "highlights"} {
+		botProvider.Messages = nil
+		sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start " + START_TOUR_PREFIX + payload}))
+		if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_WELCOME" {
+			t.Fatalf("expected generic welcome message for tour %q, got %+v", payload, botProvider.Messages)
+		}
+	}
+}
+
+func TestBotVenueChoice(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	addTestTicketType(repo, 500, true)
+	galleryID := repo.AddVenue(repository.Venue{Code: "gallery", Name: "Gallery"})
+	galleryTicketID := repo.AddTicketType(repository.TicketType{VenueID: galleryID, Name: "Gallery", Price: 300, Currency: "USD", Activations: 1, ValidityDays: 1, TimeZone: "UTC", Active: true})
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_QUERY))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_CHOOSE_VENUE" {
+		t.Fatalf("expected venues message, got %+v", botProvider.Messages)
+	}
+
+	markup := botProvider.Messages[0].Options.InlineKeyboard.Markup
+	if len(markup) != 2 || markup[1][0].Text != "Gallery" {
+		t.Fatalf("expected both venues, got %+v", markup)
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(*markup[1][0].CallbackData))
+	if len(botProvider.Messages) != 2 || botProvider.Messages[1].Text != "MESSAGE_CHOOSE_TICKET" {
+		t.Fatalf("expected ticket types message, got %+v", botProvider.Messages)
+	}
+
+	ticketTypes := botProvider.Messages[1].Options.InlineKeyboard.Markup
+	if len(ticketTypes) != 1 || *ticketTypes[0][0].CallbackData != BUY_TICKET_TYPE_QUERY_PREFIX+strconv.FormatInt(galleryTicketID, 10) {
+		t.Fatalf("expected only ticket types of the chosen venue, got %+v", ticketTypes)
+	}
+}
+
+func TestBotTicketTypes(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	dayTicketID := addTestTicketType(repo, 500, true)
+	familyTicketID := addTestTicketType(repo, 1250, true)
+	addTestTicketType(repo, 100, false)
+	repo.SetTicketTypeTranslation(dayTicketID, "en", repository.TicketTypeTranslation{Title: "Day ticket", Description: "One day"})
+	repo.SetTicketTypeTranslation(familyTicketID, "en", repository.TicketTypeTranslation{Title: "Family pass", Description: "Whole family"})
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_QUERY))
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_CHOOSE_TICKET" {
+		t.Fatalf("expected ticket types message, got %+v", botProvider.Messages)
+	}
+
+	markup := botProvider.Messages[0].Options.InlineKeyboard.Markup
+	if len(markup) != 2 {
+		t.Fatalf("expected only active ticket types, got %d", len(markup))
+	}
+
+	expected := []string{
+		BUY_TICKET_TYPE_QUERY_PREFIX + strconv.FormatInt(dayTicketID, 10),
+		BUY_TICKET_TYPE_QUERY_PREFIX + strconv.FormatInt(familyTicketID, 10),
+	}
+	for i, row := range markup {
+		if row[0].CallbackData == nil || *row[0].CallbackData != expected[i] {
+			t.Fatalf("unexpected button %d callback data", i)
+		}
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(expected[1]))
+	if len(botProvider.Invoices) != 1 {
+		t.Fatalf("expected one invoice, got %d", len(botProvider.Invoices))
+	}
+
+	invoice := botProvider.Invoices[0]
+	if invoice.Title != "Family pass" || invoice.Description != "Whole family" || invoice.Price.Parts[0].Amount != 1250 {
+		t.Fatalf("invoice doesn't match chosen ticket type: %+v", invoice)
+	}
+}
+
+func TestBotPurchase(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	ticketTypeID := addTestTicketType(repo, 500, true)
+	app := createTestApp(controller)
+
+	response, _ := sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_TYPE_QUERY_PREFIX+strconv.FormatInt(ticketTypeID, 10)))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected callback status %d", response.StatusCode)
+	}
+
+	if len(botProvider.CallbackAnswers) != 1 {
+		t.Fatalf("callback query was not answered")
+	}
+
+	if len(botProvider.Invoices) != 1 {
+		t.Fatalf("expected one invoice, got %d", len(botProvider.Invoices))
+	}
+
+	invoice := botProvider.Invoices[0]
+	if invoice.Price.Currency != "USD" || invoice.Price.Parts[0].Amount != 500 {
+		t.Fatalf("unexpected invoice price %+v", invoice.Price)
+	}
+
+	payloadTicketTypeID, ticketCode, err := parseInvoicePayload(invoice.Payload)
+	if err != nil || payloadTicketTypeID != ticketTypeID {
+		t.Fatalf("invoice payload doesn't include ticket type: %q", invoice.Payload)
+	}
+
+	sendTestJSON(t, app, "POST", "/bot", createTestPreCheckoutUpdate("USD", 500, invoice.Payload))
+	if len(botProvider.PreCheckoutAnswers) != 1 || !botProvider.PreCheckoutAnswers[0].OK {
+		t.Fatalf("expected accepted pre-checkout, got %+v", botProvider.PreCheckoutAnswers)
+	}
+
+	response, _ = sendTestJSON(t, app, "POST", "/bot", createTestPaymentUpdate("USD", 500, invoice.Payload))
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected payment status %d", response.StatusCode)
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode.String())
+	if ticket == nil || ticket.Type.ID != ticketTypeID || ticket.Purchase.BuyerUserID != TEST_USER_ID {
+		t.Fatalf("ticket was not created with configured type and buyer")
+	}
+
+	purchase := ticket.Purchase
+	if purchase.ChatID != TEST_CHAT_ID || purchase.TelegramChargeID != "telegram-charge" || purchase.ProviderChargeID != "provider-charge" ||
+		purchase.Amount != 500 || purchase.Currency != "USD" || purchase.PurchasedAt == nil {
+		t.Fatalf("unexpected ticket purchase %+v", purchase)
+	}
+
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_PURCHASED_TICKET" {
+		t.Fatalf("expected purchase message, got %+v", botProvider.Messages)
+	}
+
+	appURL := botProvider.Messages[0].Options.InlineKeyboard.Markup[0][0].WebAppURL
+	if appURL == nil || !strings.HasSuffix(*appURL, "?ticket="+ticketCode.String()) {
+		t.Fatalf("purchase message doesn't link the ticket")
+	}
+
+	// Same ticket cannot be purchased twice
+	sendTestJSON(t, app, "POST", "/bot", createTestPreCheckoutUpdate("USD", 500, invoice.Payload))
+	if len(botProvider.PreCheckoutAnswers) != 2 || botProvider.PreCheckoutAnswers[1].OK {
+		t.Fatalf("expected rejected pre-checkout for sold ticket")
+	}
+}
+
+func TestBotTrackUser(t *testing.T) {
+	controller, _, repo := createTestBotController()
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestMessageUpdate(map[string]interface{}{"text": "/start"}))
+	user, _ := repo.GetUser(TEST_USER_ID)
+	if user == nil || user.LanguageCode != "en" || user.FirstSeenAt.IsZero() || !user.LastSeenAt.Equal(user.FirstSeenAt) {
+		t.Fatalf("user was not tracked on the first update: %+v", user)
+	}
+
+	firstSeenAt := user.FirstSeenAt
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_QUERY))
+	user, _ = repo.GetUser(TEST_USER_ID)
+	if !user.FirstSeenAt.Equal(firstSeenAt) || user.LastSeenAt.Before(firstSeenAt) {
+		t.Fatalf("expected only last seen time to change: %+v", user)
+	}
+}
+
+func TestBotPreCheckoutValidation(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	ticketTypeID := addTestTicketType(repo, 500, true)
+	inactiveTicketTypeID := addTestTicketType(repo, 500, false)
+	app := createTestApp(controller)
+
+	cases := []struct {
+		currency string
+		amount   int64
+		payload  string
+		message  string
+	}{
+		{"EUR", 500, buildInvoicePayload(ticketTypeID, uuid.New()), "PAYMENT_FAIL_INVALID_CURRENCY"},
+		{"USD", 100, buildInvoicePayload(ticketTypeID, uuid.New()), "PAYMENT_FAIL_INVALID_PRICE"},
+		{"USD", 500, buildInvoicePayload(inactiveTicketTypeID, uuid.New()), "PAYMENT_FAIL_TICKET_NOT_AVAILABLE"},
+		{"USD", 500, buildInvoicePayload(1000, uuid.New()), "PAYMENT_FAIL_TICKET_NOT_AVAILABLE"},
+		{"USD", 500, uuid.NewString(), "PAYMENT_FAIL_INVALID_TICKET"},
+		{"USD", 500, "1:not-a-ticket", "PAYMENT_FAIL_INVALID_TICKET"},
+	}
+
+	for i, test := range cases {
+		sendTestJSON(t, app, "POST", "/bot", createTestPreCheckoutUpdate(test.currency, test.amount, test.payload))
+		answer := botProvider.PreCheckoutAnswers[i]
+		if answer.OK || answer.Options.ErrorMessage == nil || *answer.Options.ErrorMessage != test.message {
+			t.Fatalf("case %d: expected rejection with %s, got %+v", i, test.message, answer)
+		}
+	}
+}
+
+func TestFormatTicketPrice(t *testing.T) {
+	cases := []struct {
+		price    int64
+		currency string
+		expected string
+	}{
+		{1050, "USD", "10.50 USD"},
+		{5, "EUR", "0.05 EUR"},
+		{300, "JPY", "300 JPY"},
+		{12345, "BYN", "123.45 BYN"},
+	}
+
+	for _, test := range cases {
+		if result := formatTicketPrice(test.price, test.currency); result != test.expected {
+			t.Fatalf("expected %q, got %q", test.expected, result)
+		}
+	}
+}
+
+func TestBotPaymentsDisabled(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	addTestTicketType(repo, 500, false)
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_QUERY))
+	if len(botProvider.Invoices) != 0 {
+		t.Fatalf("invoice sent without a price")
+	}
+
+	if len(botProvider.Messages) != 1 || botProvider.Messages[0].Text != "MESSAGE_PAYMENTS_NOT_AVAILABLE" {
+		t.Fatalf("expected payments disabled message, got %+v", botProvider.Messages)
+	}
+}
+
+func TestBotVenuePaymentsToken(t *testing.T) {
+	controller, botProvider, repo := createTestBotController()
+	ticketTypeID := addTestTicketType(repo, 500, true)
+	galleryID := repo.AddVenue(repository.Venue{Code: "gallery", Name: "Gallery"})
+	galleryTicketTypeID := repo.AddTicketType(repository.TicketType{VenueID: galleryID, Price: 700, Currency: "USD", Activations: 1, ValidityDays: 1, TimeZone: "UTC", Active: true})
+	repo.SetVenueValue(galleryID, PAYMENTS_TOKEN_CONFIG_KEY, "gallery-token")
+	app := createTestApp(controller)
+
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_TYPE_QUERY_PREFIX+strconv.FormatInt(ticketTypeID, 10)))
+	sendTestJSON(t, app, "POST", "/bot", createTestCallbackUpdate(BUY_TICKET_TYPE_QUERY_PREFIX+strconv.FormatInt(galleryTicketTypeID, 10)))
+	if len(botProvider.Invoices) != 2 {
+		t.Fatalf("expected two invoices, got %d", len(botProvider.Invoices))
+	}
+
+	if botProvider.Invoices[0].Options.ProviderToken != nil {
+		t.Fatalf("expected venue without token to use the bot payments token")
+	}
+
+	if token := botProvider.Invoices[1].Options.ProviderToken; token == nil || *token != "gallery-token" {
+		t.Fatalf("expected invoice to use the venue payments token")
+	}
+}
+
+func TestBuildWebAppURL(t *testing.T) {
+	controller := BotController{WebAppURL: "https://guide.test/app?mode=compact"}
+
+	appURL, err := controller.buildWebAppURL(WEB_APP_OBJECT_PARAM, "hall 1&2")
+	if err != nil || appURL != "https://guide.test/app?mode=compact&object=hall+1%262" {
+		t.Fatalf("unexpected Web App URL %q: %v", appURL, err)
+	}
+}
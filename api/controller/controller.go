@@ -1,9 +1,11 @@
 package controller
 
 import (
+	"errors"
 	"log/slog"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
 )
 
 type Controller interface {
@@ -34,3 +36,25 @@ func HandlerPrintf(c *fiber.Ctx, severity int, message string, v ...any) {
 	args := append([]any{GetRequestLogGroup(c)}, v...)
 	logger(message, args...)
 }
+
+// Verifies the access token and sends the failure response if it's not valid,
+// handlers return the error if the token is not valid
+func verifyToken(c *fiber.Ctx, provider auth.TokenProvider, token string) (auth.TokenClaims, bool, error) {
+	claims, valid, err := provider.Verify(token)
+	if errors.Is(err, auth.ErrRevocationCheck) {
+		HandlerPrintf(c, LOG_ERROR, "Failed to check token revocation", "error", err)
+		return auth.TokenClaims{}, false, HandlerSendError(c, fiber.StatusInternalServerError, "Failed to check token revocation")
+	}
+
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to parse authorization token", "error", err)
+		return auth.TokenClaims{}, false, HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse authorization token")
+	}
+
+	if !valid {
+		HandlerPrintf(c, LOG_WARNING, "Authorization token is invalid")
+		return auth.TokenClaims{}, false, HandlerSendFailure(c, fiber.StatusUnauthorized, "Authorization token is invalid")
+	}
+
+	return claims, true, nil
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
+)
+
+// Translation provider that returns message IDs as is, so tests don't depend on locales
+type testTranslationProvider struct{}
+
+func (provider *testTranslationProvider) TranslateMessage(messageID string, locale string, templateData translation.TemplateData) (string, error) {
+	return messageID, nil
+}
+
+func createTestApp(controllers ...Controller) *fiber.App {
+	app := fiber.New()
+	for _, controller := range controllers {
+		for _, route := range controller.GetRoutes() {
+			app.Add(route.Method, route.Path, route.Handler)
+		}
+	}
+
+	return app
+}
+
+func sendTestRequest(t *testing.T, app *fiber.App, request *http.Request) (*http.Response, []byte) {
+	t.Helper()
+
+	response, err := app.Test(request, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	return response, body
+}
+
+func sendTestJSON(t *testing.T, app *fiber.App, method string, path string, payload interface{}) (*http.Response, []byte) {
+	t.Helper()
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("failed to marshal payload: %v", err)
+	}
+
+	request := httptest.NewRequest(method, path, bytes.NewReader(data))
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return sendTestRequest(t, app, request)
+}
+
+func parseTestResponse(t *testing.T, body []byte, data interface{}) HandlerResponse {
+	t.Helper()
+
+	response := HandlerResponse{Data: data}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("failed to parse response %q: %v", body, err)
+	}
+
+	return response
+}
+
+// Creates an API token valid for an hour with the scopes
+func createTestToken(t *testing.T, tokenProvider auth.TokenProvider, scopes auth.TokenScopes) string {
+	t.Helper()
+
+	token, err := tokenProvider.Create(auth.TokenClaims{
+		ExpiresAt: time.Now().Add(time.Hour),
+		Audience:  auth.API_AUDIENCE,
+		Scopes:    scopes,
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	return token
+}
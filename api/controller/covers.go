@@ -0,0 +1,234 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/webp"
+	"golang.org/x/image/draw"
+)
+
+// Cover formats negotiated with the Accept header
+const (
+	COVER_FORMAT_JPEG = "jpeg"
+	COVER_FORMAT_WEBP = "webp"
+)
+
+// Requested dimensions are rounded up to one of the sizes, so only a few variants of each cover are ever stored
+var COVER_SIZES = []int{160, 320, 640, 1280, 2048}
+
+const MAX_COVER_SIZE = 2048
+const COVER_QUALITY = 80
+
+// Covers are decoded to memory, so larger images are sent as is
+const MAX_COVER_SOURCE_PIXELS = 25_000_000
+
+var errCoverTooLarge = errors.New("cover is too large to resize")
+
+// Resized and re-encoded cover, zero width or height means it is scaled proportionally
+type coverVariant struct {
+	Width  int
+	Height int
+	Format string
+}
+
+// Streams a cover variant requested with w and h parameters and Accept header,
+// variants are generated on the first request and cached in blob storage
+func sendCover(c *fiber.Ctx, provider blob.BlobProvider, path string) error {
+	c.Vary(fiber.HeaderAccept)
+
+	variant, err := parseCoverVariant(c)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse cover size", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse cover size")
+	}
+
+	// Original is served as is if neither resize nor WebP is requested
+	if variant.Width == 0 && variant.Height == 0 && variant.Format == COVER_FORMAT_JPEG {
+		return sendBlobOrRedirect(c, provider, path)
+	}
+
+	variantPath := getCoverVariantPath(path, variant)
+	_, err = provider.StatBlob(variantPath)
+	if errors.Is(err, blob.ErrNotExist) {
+		err = createCoverVariant(provider, path, variantPath, variant)
+		if errors.Is(err, image.ErrFormat) || errors.Is(err, errCoverTooLarge) || errors.Is(err, webp.ErrInvalidSize) {
+			HandlerPrintf(c, LOG_WARNING, "Cover can't be resized, sending original", "path", path, "error", err)
+			return sendBlobOrRedirect(c, provider, path)
+		}
+	}
+
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get cover variant", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get cover variant")
+	}
+
+	return sendBlobOrRedirect(c, provider, variantPath)
+}
+
+func parseCoverVariant(c *fiber.Ctx) (coverVariant, error) {
+	variant := coverVariant{Format: COVER_FORMAT_JPEG}
+	if acceptsWebP(c.Get(fiber.HeaderAccept)) {
+		variant.Format = COVER_FORMAT_WEBP
+	}
+
+	var err error
+	if variant.Width, err = parseCoverSize(c.Query("w")); err != nil {
+		return coverVariant{}, err
+	}
+
+	if variant.Height, err = parseCoverSize(c.Query("h")); err != nil {
+		return coverVariant{}, err
+	}
+
+	return variant, nil
+}
+
+func parseCoverSize(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+
+	if size <= 0 || size > MAX_COVER_SIZE {
+		return 0, fmt.Errorf("cover size should be between 1 and %d", MAX_COVER_SIZE)
+	}
+
+	for _, coverSize := range COVER_SIZES {
+		if coverSize >= size {
+			return coverSize, nil
+		}
+	}
+
+	return MAX_COVER_SIZE, nil
+}
+
+// WebP is served only if explicitly listed, wildcards are sent by clients without WebP support too
+func acceptsWebP(header string) bool {
+	for _, value := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil || mediaType != "image/webp" {
+			continue
+		}
+
+		quality, err := strconv.ParseFloat(params["q"], 64)
+		return err != nil || quality > 0
+	}
+
+	return false
+}
+
+// Cover paths are unique per upload, so variants never have to be invalidated,
+// they are only deleted together with the cover
+func getCoverVariantPath(path string, variant coverVariant) string {
+	return fmt.Sprintf("%s%dx%d.%s", getCoverVariantsPrefix(path), variant.Width, variant.Height, variant.Format)
+}
+
+func getCoverVariantsPrefix(path string) string {
+	return "variants/" + path + "/"
+}
+
+// Failures are only logged, since orphaned variants are never served
+func deleteCoverVariants(c *fiber.Ctx, provider blob.BlobProvider, path string) {
+	variants, err := provider.ListBlobs(getCoverVariantsPrefix(path))
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to list cover variants", "path", path, "error", err)
+		return
+	}
+
+	for _, variant := range variants {
+		if err := provider.DeleteBlob(variant); err != nil {
+			HandlerPrintf(c, LOG_WARNING, "Failed to delete cover variant", "path", variant, "error", err)
+		}
+	}
+}
+
+func createCoverVariant(provider blob.BlobProvider, path string, variantPath string, variant coverVariant) error {
+	reader, err := provider.ReadBlob(path, blob.ReadBlobOptions{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	// Dimensions are checked before decoding, since a small file may declare a huge image
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	if int64(config.Width)*int64(config.Height) > MAX_COVER_SOURCE_PIXELS {
+		return errCoverTooLarge
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	variantData, err := encodeCoverVariant(source, variant)
+	if err != nil {
+		return err
+	}
+
+	return provider.WriteBlob(variantPath, bytes.NewReader(variantData))
+}
+
+func encodeCoverVariant(source image.Image, variant coverVariant) ([]byte, error) {
+	bounds := getCoverVariantBounds(source.Bounds(), variant)
+	result := image.NewRGBA(bounds)
+
+	// Neither JPEG nor lossy WebP keep the alpha channel, transparent areas are filled with white
+	draw.Draw(result, bounds, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(result, bounds, source, source.Bounds(), draw.Over, nil)
+
+	buffer := bytes.Buffer{}
+	var err error
+	if variant.Format == COVER_FORMAT_WEBP {
+		err = webp.Encode(&buffer, result, &webp.Options{Quality: COVER_QUALITY})
+	} else {
+		err = jpeg.Encode(&buffer, result, &jpeg.Options{Quality: COVER_QUALITY})
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Fits the image into requested dimensions keeping the aspect ratio, images are never upscaled
+func getCoverVariantBounds(source image.Rectangle, variant coverVariant) image.Rectangle {
+	width, height := source.Dx(), source.Dy()
+	scale := 1.0
+	if variant.Width > 0 && variant.Width < width {
+		scale = float64(variant.Width) / float64(width)
+	}
+
+	if variant.Height > 0 && variant.Height < height {
+		scale = min(scale, float64(variant.Height)/float64(height))
+	}
+
+	return image.Rect(0, 0, max(1, int(float64(width)*scale+0.5)), max(1, int(float64(height)*scale+0.5)))
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+	"golang.org/x/image/webp"
+)
+
+const TEST_COVER_PATH = "object/cover.png"
+
+func createTestCoversApp(t *testing.T) (*fiber.App, *blob.MemoryBlobProvider, string) {
+	tokenProvider := auth.CreateMemoryTokenProvider(nil)
+	blobProvider := blob.CreateMemoryBlobProvider()
+	repo := repository.CreateMemoryRepository()
+
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	objectID, _ := repo.CreateObject(TEST_OBJECT_CODE, venueID)
+	repo.SetObjectCovers(objectID, []repository.Cover{{Index: 0, Path: TEST_COVER_PATH}})
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: "Title", AudioPath: "object/audio-en.mp3"})
+
+	cover := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for x := 0; x < 400; x++ {
+		for y := 0; y < 200; y++ {
+			cover.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	png.Encode(&buffer, cover)
+	blobProvider.WriteBlob(TEST_COVER_PATH, &buffer)
+
+	token := createTestToken(t, tokenProvider, auth.TokenScopes{VenueIDs: []int64{venueID}})
+
+	app := createTestApp(&ObjectsController{
+		TokenProvider:    tokenProvider,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+		TourRepository:   repo,
+	})
+
+	return app, blobProvider, token
+}
+
+func sendTestCoverRequest(t *testing.T, app *fiber.App, token string, query string, accept string) (*http.Response, []byte) {
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/covers/0?access-token="+token+query, nil)
+	request.Header.Set(fiber.HeaderAccept, accept)
+	return sendTestRequest(t, app, request)
+}
+
+func TestGetObjectCoverResize(t *testing.T) {
+	app, blobProvider, token := createTestCoversApp(t)
+
+	cases := []struct {
+		query  string
+		width  int
+		height int
+	}{
+		// Requested sizes are rounded up to the nearest cover size
+		{"&w=100", 160, 80},
+		{"&w=161", 320, 160},
+		{"&h=50", 320, 160},
+		{"&w=100&h=20", 160, 80},
+		{"&w=1000", 400, 200},
+	}
+
+	for _, test := range cases {
+		response, body := sendTestCoverRequest(t, app, token, test.query, "")
+		if response.StatusCode != http.StatusOK || response.Header.Get(fiber.HeaderContentType) != "image/jpeg" {
+			t.Fatalf("%s: unexpected response %d with type %q", test.query, response.StatusCode, response.Header.Get(fiber.HeaderContentType))
+		}
+
+		config, format, err := image.DecodeConfig(bytes.NewReader(body))
+		if err != nil || format != "jpeg" || config.Width != test.width || config.Height != test.height {
+			t.Fatalf("%s: expected %dx%d jpeg, got %dx%d %s: %v", test.query, test.width, test.height, config.Width, config.Height, format, err)
+		}
+	}
+
+	if _, err := blobProvider.StatBlob("variants/" + TEST_COVER_PATH + "/160x0.jpeg"); err != nil {
+		t.Fatalf("expected variant to be cached: %v", err)
+	}
+
+	for _, query := range []string{"&w=0", "&h=-1", "&w=5000", "&w=abc"} {
+		response, _ := sendTestCoverRequest(t, app, token, query, "")
+		if response.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected bad request, got %d", query, response.StatusCode)
+		}
+	}
+}
+
+func TestGetObjectCoverTooLarge(t *testing.T) {
+	app, blobProvider, token := createTestCoversApp(t)
+
+	// PNG header declares a huge image, which is not decoded
+	buffer := bytes.Buffer{}
+	png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 1, 1)))
+	data := buffer.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	blobProvider.WriteBlob(TEST_COVER_PATH, bytes.NewReader(data))
+
+	response, body := sendTestCoverRequest(t, app, token, "&w=160", "image/webp")
+	if response.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("expected original cover, got %d", response.StatusCode)
+	}
+}
+
+func TestGetObjectCoverWebP(t *testing.T) {
+	app, blobProvider, token := createTestCoversApp(t)
+
+	response, body := sendTestCoverRequest(t, app, token, "&w=200", "image/avif,image/webp,*/*")
+	if response.StatusCode != http.StatusOK || response.Header.Get(fiber.HeaderContentType) != "image/webp" {
+		t.Fatalf("unexpected response %d with type %q", response.StatusCode, response.Header.Get(fiber.HeaderContentType))
+	}
+
+	if response.Header.Get(fiber.HeaderVary) != fiber.HeaderAccept {
+		t.Fatalf("expected Vary: Accept, got %q", response.Header.Get(fiber.HeaderVary))
+	}
+
+	config, err := webp.DecodeConfig(bytes.NewReader(body))
+	if err != nil || config.Width != 320 || config.Height != 160 {
+		t.Fatalf("expected 320x160 webp, got %dx%d: %v", config.Width, config.Height, err)
+	}
+
+	if _, err := blobProvider.StatBlob("variants/" + TEST_COVER_PATH + "/320x0.webp"); err != nil {
+		t.Fatalf("expected variant to be cached: %v", err)
+	}
+
+	// Same URL without WebP in Accept gets JPEG, so caches must key on Accept
+	response, _ = sendTestCoverRequest(t, app, token, "&w=200", "image/webp;q=0, image/*")
+	if response.Header.Get(fiber.HeaderContentType) != "image/jpeg" || response.Header.Get(fiber.HeaderVary) != fiber.HeaderAccept {
+		t.Fatalf("expected jpeg varying on Accept, got %q with Vary %q", response.Header.Get(fiber.HeaderContentType), response.Header.Get(fiber.HeaderVary))
+	}
+
+	// Original is converted to WebP without resizing
+	response, body = sendTestCoverRequest(t, app, token, "", "image/webp")
+	if config, err := webp.DecodeConfig(bytes.NewReader(body)); err != nil || config.Width != 400 || response.Header.Get(fiber.HeaderContentType) != "image/webp" {
+		t.Fatalf("expected original size webp, got %d: %v", config.Width, err)
+	}
+}
+
+func TestGetObjectCoverCachedVariant(t *testing.T) {
+	app, blobProvider, token := createTestCoversApp(t)
+
+	// Later requests are served from blob storage without resizing
+	blobProvider.WriteBlob("variants/"+TEST_COVER_PATH+"/160x0.jpeg", strings.NewReader("cached"))
+	response, body := sendTestCoverRequest(t, app, token, "&w=50", "")
+	if response.StatusCode != http.StatusOK || string(body) != "cached" {
+		t.Fatalf("expected cached variant, got %d: %s", response.StatusCode, body)
+	}
+
+	// Original is sent if neither resize nor WebP is requested
+	response, body = sendTestCoverRequest(t, app, token, "", "image/*")
+	original, _ := blobProvider.ReadBlob(TEST_COVER_PATH, blob.ReadBlobOptions{})
+	data, _ := io.ReadAll(original)
+	if response.StatusCode != http.StatusOK || !bytes.Equal(body, data) {
+		t.Fatalf("expected original cover, got %d", response.StatusCode)
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+)
+
+// Key set can be cached by verifiers, new keys must be published this long before they sign tokens
+const KEY_SET_MAX_AGE = 300
+
+type KeysController struct {
+	KeySetProvider auth.KeySetProvider
+}
+
+func (controller *KeysController) GetRoutes() []Route {
+	return []Route{
+		{
+			Method:  "GET",
+			Path:    "/.well-known/jwks.json",
+			Handler: controller.HandleGetKeySet,
+		},
+	}
+}
+
+// Key set is served as is instead of JSend, since JWT libraries expect RFC 7517 format
+func (controller *KeysController) HandleGetKeySet(c *fiber.Ctx) error {
+	keySet, err := controller.KeySetProvider.GetKeySet()
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get key set", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get key set")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(KEY_SET_MAX_AGE))
+	return c.JSON(keySet)
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+)
+
+// Restores a verification key from the key set the way a partner service would
+func parseTestPublicKey(t *testing.T, key auth.PublicKey) any {
+	switch key.KeyType {
+	case "RSA":
+		n, _ := base64.RawURLEncoding.DecodeString(key.N)
+		e, _ := base64.RawURLEncoding.DecodeString(key.E)
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "OKP":
+		x, _ := base64.RawURLEncoding.DecodeString(key.X)
+		return ed25519.PublicKey(x)
+	}
+
+	t.Fatalf("unexpected key type %q", key.KeyType)
+	return nil
+}
+
+func TestGetKeySet(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	secretKey, _ := auth.CreateHMACKey("", "secret")
+	keys := []auth.JWTKey{
+		secretKey,
+		{ID: "rsa", Method: jwt.SigningMethodRS256, SignKey: rsaKey, VerifyKey: &rsaKey.PublicKey},
+		{ID: "ed", Method: jwt.SigningMethodEdDSA, SignKey: edKey, VerifyKey: edKey.Public()},
+	}
+
+	for _, signingKeyID := range []string{"rsa", "ed"} {
+		tokenProvider, err := auth.CreateJWTTokenProvider(keys, signingKeyID, nil)
+		if err != nil {
+			t.Fatalf("failed to create token provider: %v", err)
+		}
+
+		app := createTestApp(&KeysController{KeySetProvider: tokenProvider})
+		request := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", response.StatusCode, body)
+		}
+
+		// Secret key is never published
+		keySet := auth.KeySet{}
+		json.Unmarshal(body, &keySet)
+		if len(keySet.Keys) != 2 || keySet.Keys[0].KeyID != "ed" || keySet.Keys[1].KeyID != "rsa" {
+			t.Fatalf("unexpected key set %s", body)
+		}
+
+		published := map[string]auth.PublicKey{}
+		for _, key := range keySet.Keys {
+			published[key.KeyID] = key
+		}
+
+		token := createTestToken(t, tokenProvider, auth.TokenScopes{VenueIDs: []int64{1}})
+		_, err = jwt.Parse(token, func(token *jwt.Token) (any, error) {
+			key := published[token.Header["kid"].(string)]
+			if token.Method.Alg() != key.Algorithm {
+				t.Fatalf("token algorithm %q doesn't match key algorithm %q", token.Method.Alg(), key.Algorithm)
+			}
+			return parseTestPublicKey(t, key), nil
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to verify token with published key: %v", signingKeyID, err)
+		}
+	}
+}
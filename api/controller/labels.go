@@ -0,0 +1,314 @@
+package controller
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gofiber/fiber/v2"
+	"github.com/skip2/go-qrcode"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// QR code formats served by GET /admin/objects/:code/qr.:format
+const (
+	QR_FORMAT_PNG = "png"
+	QR_FORMAT_SVG = "svg"
+)
+
+const DEFAULT_QR_PNG_SIZE = 512
+const MAX_QR_PNG_SIZE = 2048
+
+// Mini App start parameter is passed by Telegram only if it consists of these characters
+var START_PARAM_REGEXP = regexp.MustCompile(`^[A-Za-z0-9_-]{1,512}$`)
+
+// Label sheet layout in millimeters, A4 page fits 3 columns and 4 rows of labels
+const (
+	LABEL_SHEET_COLUMNS       = 3
+	LABEL_SHEET_ROWS          = 4
+	LABEL_SHEET_MARGIN        = 10.0
+	LABEL_QR_SIZE             = 50.0
+	LABEL_TITLE_FONT_SIZE     = 10.0
+	LABEL_TITLE_LINE_HEIGHT   = 4.5
+	LABEL_TITLE_MAX_LINES     = 3
+	LABEL_TITLE_PADDING       = 4.0
+	LABEL_SHEET_FONT          = "goregular"
+	LABEL_TITLE_ELLIPSIS      = "…"
+	LABEL_SHEET_OBJECTS_LIMIT = 1000
+)
+
+// Response header with the cursor of the next venue label sheet, absent on the last sheet
+const LABEL_SHEET_NEXT_CURSOR_HEADER = "X-Next-Cursor"
+
+type label struct {
+	Code  string
+	Title string
+}
+
+func (controller *AdminController) HandleGetObjectQR(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	format := c.Params("format")
+	if format != QR_FORMAT_PNG && format != QR_FORMAT_SVG {
+		HandlerPrintf(c, LOG_WARNING, "QR code format is not supported", "format", format)
+		return HandlerSendFailure(c, fiber.StatusNotFound, "QR code format is not supported")
+	}
+
+	size := DEFAULT_QR_PNG_SIZE
+	if value := c.Query("size"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > MAX_QR_PNG_SIZE {
+			HandlerPrintf(c, LOG_WARNING, "QR code size is not valid", "size", value)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "QR code size is not valid")
+		}
+		size = parsed
+	}
+
+	objectCode := c.Params("code")
+	objectID, err := controller.ObjectRepository.GetObjectID(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
+
+	if objectID == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
+
+	code, err := controller.createObjectQR(objectCode)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to create QR code", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if format == QR_FORMAT_SVG {
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+		return c.SendString(renderQRSVG(code))
+	}
+
+	data, err := code.PNG(size)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to render QR code", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to render QR code")
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	return c.Send(data)
+}
+
+func (controller *AdminController) HandleGetVenueLabels(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	venue, err := controller.VenueRepository.GetVenueByCode(c.Params("code"))
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get venue", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get venue")
+	}
+
+	if venue == nil {
+		HandlerPrintf(c, LOG_WARNING, "Venue not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Venue not found")
+	}
+
+	options := repository.ListObjectsOptions{
+		Language: c.Query("language"),
+		Fallback: translation.DEFAULT_LANGUAGE.String(),
+		Limit:    MAX_OBJECTS_PAGE_SIZE,
+	}
+
+	// Cursor is the ID of the last object on the previous sheet
+	if value := c.Query("cursor"); value != "" {
+		options.AfterID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || options.AfterID < 0 {
+			HandlerPrintf(c, LOG_WARNING, "Cursor is not valid", "cursor", value)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Cursor is not valid")
+		}
+	}
+
+	// List one extra object to find out if there is a next sheet
+	objects := []repository.Object{}
+	for len(objects) <= LABEL_SHEET_OBJECTS_LIMIT {
+		page, err := controller.ObjectRepository.ListObjects([]int64{venue.ID}, options)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to list objects", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to list objects")
+		}
+
+		objects = append(objects, page...)
+		if len(page) < options.Limit {
+			break
+		}
+		options.AfterID = page[len(page)-1].ID
+	}
+
+	if len(objects) > LABEL_SHEET_OBJECTS_LIMIT {
+		objects = objects[:LABEL_SHEET_OBJECTS_LIMIT]
+		c.Set(LABEL_SHEET_NEXT_CURSOR_HEADER, strconv.FormatInt(objects[LABEL_SHEET_OBJECTS_LIMIT-1].ID, 10))
+	}
+
+	labels := []label{}
+	for _, object := range objects {
+		labels = append(labels, label{Code: object.Code, Title: object.Title})
+	}
+
+	return controller.sendLabelSheet(c, labels)
+}
+
+func (controller *AdminController) HandleGetTourLabels(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	tourID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse tour id", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse tour id")
+	}
+
+	tour, err := controller.TourRepository.GetTour(tourID, c.Query("language"), translation.DEFAULT_LANGUAGE.String())
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get tour", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get tour")
+	}
+
+	if tour == nil {
+		HandlerPrintf(c, LOG_WARNING, "Tour not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Tour not found")
+	}
+
+	labels := []label{}
+	for _, stop := range tour.Stops {
+		labels = append(labels, label{Code: stop.Code, Title: stop.Title})
+	}
+
+	return controller.sendLabelSheet(c, labels)
+}
+
+func (controller *AdminController) sendLabelSheet(c *fiber.Ctx, labels []label) error {
+	data, err := controller.renderLabelSheet(labels)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to render label sheet", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Rendered label sheet", "count", len(labels))
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	return c.Send(data)
+}
+
+// QR code contains a Mini App direct link if its URL is configured, otherwise just the object code
+func (controller *AdminController) createObjectQR(objectCode string) (*qrcode.QRCode, error) {
+	content := objectCode
+	if controller.MiniAppURL != "" {
+		if !START_PARAM_REGEXP.MatchString(objectCode) {
+			return nil, fmt.Errorf("object code %q can't be passed in a Mini App link", objectCode)
+		}
+
+		link, err := url.Parse(controller.MiniAppURL)
+		if err != nil {
+			return nil, err
+		}
+
+		query := link.Query()
+		query.Set("startapp", objectCode)
+		link.RawQuery = query.Encode()
+		content = link.String()
+	}
+
+	return qrcode.New(content, qrcode.Medium)
+}
+
+func renderQRSVG(code *qrcode.QRCode) string {
+	bitmap := code.Bitmap()
+	size := len(bitmap)
+
+	builder := strings.Builder{}
+	fmt.Fprintf(&builder, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&builder, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&builder, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	builder.WriteString(`"/></svg>`)
+
+	return builder.String()
+}
+
+// Renders A4 pages of labels, each label is a QR code with the title under it
+func (controller *AdminController) renderLabelSheet(labels []label) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(false, 0)
+	// Go font covers Latin and Cyrillic scripts of the supported languages
+	pdf.AddUTF8FontFromBytes(LABEL_SHEET_FONT, "", goregular.TTF)
+	pdf.SetFont(LABEL_SHEET_FONT, "", LABEL_TITLE_FONT_SIZE)
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	labelWidth := (pageWidth - 2*LABEL_SHEET_MARGIN) / LABEL_SHEET_COLUMNS
+	labelHeight := (pageHeight - 2*LABEL_SHEET_MARGIN) / LABEL_SHEET_ROWS
+	perPage := LABEL_SHEET_COLUMNS * LABEL_SHEET_ROWS
+
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	for i, label := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+
+		code, err := controller.createObjectQR(label.Code)
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := code.PNG(DEFAULT_QR_PNG_SIZE)
+		if err != nil {
+			return nil, err
+		}
+
+		column := i % LABEL_SHEET_COLUMNS
+		row := i % perPage / LABEL_SHEET_COLUMNS
+		left := LABEL_SHEET_MARGIN + float64(column)*labelWidth
+		top := LABEL_SHEET_MARGIN + float64(row)*labelHeight
+
+		name := "qr-" + strconv.Itoa(i)
+		options := fpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(data))
+		pdf.ImageOptions(name, left+(labelWidth-LABEL_QR_SIZE)/2, top, LABEL_QR_SIZE, LABEL_QR_SIZE, false, options, 0, "")
+
+		lines := pdf.SplitText(label.Title, labelWidth-2*LABEL_TITLE_PADDING)
+		if len(lines) > LABEL_TITLE_MAX_LINES {
+			lines = lines[:LABEL_TITLE_MAX_LINES]
+			lines[len(lines)-1] += LABEL_TITLE_ELLIPSIS
+		}
+
+		for j, line := range lines {
+			pdf.SetXY(left, top+LABEL_QR_SIZE+float64(j)*LABEL_TITLE_LINE_HEIGHT)
+			pdf.CellFormat(labelWidth, LABEL_TITLE_LINE_HEIGHT, line, "", 0, "C", false, 0, "")
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	if err := pdf.Output(&buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
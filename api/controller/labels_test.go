@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const TEST_ADMIN_TOKEN = "admin-token"
+
+func createTestLabelsApp(t *testing.T, miniAppURL string) (*fiber.App, int64) {
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+
+	objectIDs := []int64{}
+	for i := 0; i < 14; i++ {
+		code := "object-" + strconv.Itoa(i)
+		objectID, _ := repo.CreateObject(code, venueID)
+		repo.SetObjectTranslation(objectID, repository.ObjectTranslation{
+			Language: "en", Title: strings.Repeat("Very long title ", i), AudioPath: code + "/audio-en.mp3",
+		})
+		repo.SetObjectTranslation(objectID, repository.ObjectTranslation{
+			Language: "be", Title: "Назва " + strconv.Itoa(i), AudioPath: code + "/audio-be.mp3",
+		})
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	tourID := repo.AddTour(venueID, map[string]repository.TourTranslation{"en": {Title: "Highlights"}}, objectIDs[:3])
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		BlobProvider:     blob.CreateMemoryBlobProvider(),
+		ObjectRepository: repo,
+		VenueRepository:  repo,
+		TourRepository:   repo,
+		MiniAppURL:       miniAppURL,
+	})
+
+	return app, tourID
+}
+
+func sendTestAdminRequest(t *testing.T, app *fiber.App, path string) (*http.Response, []byte) {
+	request := httptest.NewRequest("GET", path, nil)
+	request.Header.Set(fiber.HeaderAuthorization, TEST_ADMIN_TOKEN)
+	return sendTestRequest(t, app, request)
+}
+
+func TestGetObjectQR(t *testing.T) {
+	app, _ := createTestLabelsApp(t, "https://t.me/bot/app")
+
+	response, body := sendTestAdminRequest(t, app, "/admin/objects/object-1/qr.png?size=256")
+	if response.StatusCode != fiber.StatusOK || response.Header.Get(fiber.HeaderContentType) != "image/png" {
+		t.Fatalf("unexpected response %d: %s", response.StatusCode, body)
+	}
+
+	image, err := png.Decode(bytes.NewReader(body))
+	if err != nil || image.Bounds().Dx() != 256 {
+		t.Fatalf("unexpected QR code image: %v", err)
+	}
+
+	response, body = sendTestAdminRequest(t, app, "/admin/objects/object-1/qr.svg")
+	if response.StatusCode != fiber.StatusOK || !strings.HasPrefix(string(body), "<svg") {
+		t.Fatalf("unexpected response %d: %s", response.StatusCode, body)
+	}
+
+	cases := map[string]int{
+		"/admin/objects/missing/qr.png":              fiber.StatusNotFound,
+		"/admin/objects/object-1/qr.gif":             fiber.StatusNotFound,
+		"/admin/objects/object-1/qr.png?size=100000": fiber.StatusBadRequest,
+	}
+
+	for path, status := range cases {
+		if response, _ := sendTestAdminRequest(t, app, path); response.StatusCode != status {
+			t.Fatalf("%s: expected status %d, got %d", path, status, response.StatusCode)
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/admin/objects/object-1/qr.png", nil)
+	if response, _ := sendTestRequest(t, app, request); response.StatusCode != fiber.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", response.StatusCode)
+	}
+}
+
+func TestCreateObjectQR(t *testing.T) {
+	controller := AdminController{MiniAppURL: "https://t.me/bot/app"}
+	if _, err := controller.createObjectQR("object 1"); err == nil {
+		t.Fatalf("expected code with spaces to be rejected")
+	}
+
+	// Without Mini App link any code is encoded as is
+	controller.MiniAppURL = ""
+	if _, err := controller.createObjectQR("object 1"); err != nil {
+		t.Fatalf("failed to create QR code: %v", err)
+	}
+}
+
+func TestGetLabels(t *testing.T) {
+	app, tourID := createTestLabelsApp(t, "")
+
+	paths := []string{
+		"/admin/venues/museum/labels.pdf",
+		"/admin/venues/museum/labels.pdf?language=be",
+		"/admin/tours/" + strconv.FormatInt(tourID, 10) + "/labels.pdf",
+	}
+
+	for _, path := range paths {
+		response, body := sendTestAdminRequest(t, app, path)
+		if response.StatusCode != fiber.StatusOK || response.Header.Get(fiber.HeaderContentType) != "application/pdf" {
+			t.Fatalf("%s: unexpected response %d: %s", path, response.StatusCode, body)
+		}
+
+		if !bytes.HasPrefix(body, []byte("%PDF")) {
+			t.Fatalf("%s: expected PDF document", path)
+		}
+	}
+
+	cases := map[string]int{
+		"/admin/venues/unknown/labels.pdf":               fiber.StatusNotFound,
+		"/admin/tours/100/labels.pdf":                    fiber.StatusNotFound,
+		"/admin/tours/tour/labels.pdf":                   fiber.StatusBadRequest,
+		"/admin/venues/museum/labels.pdf?cursor=invalid": fiber.StatusBadRequest,
+	}
+
+	for path, status := range cases {
+		if response, _ := sendTestAdminRequest(t, app, path); response.StatusCode != status {
+			t.Fatalf("%s: expected status %d, got %d", path, status, response.StatusCode)
+		}
+	}
+}
+
+func TestGetVenueLabelsPagination(t *testing.T) {
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	for i := 0; i <= LABEL_SHEET_OBJECTS_LIMIT; i++ {
+		objectID, _ := repo.CreateObject("object-"+strconv.Itoa(i), venueID)
+		repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: "Object", AudioPath: "audio.mp3"})
+	}
+
+	app := createTestApp(&AdminController{
+		AdminToken:       TEST_ADMIN_TOKEN,
+		ObjectRepository: repo,
+		VenueRepository:  repo,
+	})
+
+	response, _ := sendTestAdminRequest(t, app, "/admin/venues/museum/labels.pdf")
+	cursor := response.Header.Get(LABEL_SHEET_NEXT_CURSOR_HEADER)
+	if response.StatusCode != fiber.StatusOK || cursor == "" {
+		t.Fatalf("expected first sheet with next cursor, got %d %q", response.StatusCode, cursor)
+	}
+
+	response, _ = sendTestAdminRequest(t, app, "/admin/venues/museum/labels.pdf?cursor="+cursor)
+	if response.StatusCode != fiber.StatusOK || response.Header.Get(LABEL_SHEET_NEXT_CURSOR_HEADER) != "" {
+		t.Fatalf("expected last sheet without next cursor, got %d", response.StatusCode)
+	}
+}
@@ -1,9 +1,11 @@
 package controller
 
 import (
-	"errors"
-	"fmt"
+	"bufio"
+	"html"
+	"io"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -14,14 +16,41 @@ import (
 	"github.com/st-matskevich/audio-guide-bot/api/repository"
 )
 
+// Transcript formats served by GET /objects/:code/transcript
+const (
+	TRANSCRIPT_FORMAT_TEXT = "text"
+	TRANSCRIPT_FORMAT_VTT  = "vtt"
+)
+
+const DEFAULT_OBJECTS_PAGE_SIZE = 20
+const MAX_OBJECTS_PAGE_SIZE = 100
+
+// Page of objects list, NextCursor is nil on the last page
+// Listed object includes its code to open it, object response doesn't since the code is already known
+type ObjectsPageItem struct {
+	repository.Object
+	Code string `json:"code"`
+}
+
+type ObjectsPage struct {
+	Objects    []ObjectsPageItem `json:"objects"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
 type ObjectsController struct {
 	TokenProvider    auth.TokenProvider
 	BlobProvider     blob.BlobProvider
 	ObjectRepository repository.ObjectRepository
+	TourRepository   repository.TourRepository
 }
 
 func (controller *ObjectsController) GetRoutes() []Route {
 	return []Route{
+		{
+			Method:  "GET",
+			Path:    "/objects",
+			Handler: controller.HandleGetObjects,
+		},
 		{
 			Method:  "GET",
 			Path:    "/objects/:code",
@@ -37,26 +66,88 @@ func (controller *ObjectsController) GetRoutes() []Route {
 			Path:    "/objects/:code/audio",
 			Handler: controller.HandleGetObjectAudio,
 		},
+		{
+			Method:  "GET",
+			Path:    "/objects/:code/audio/:track",
+			Handler: controller.HandleGetObjectAudioTrack,
+		},
+		{
+			Method:  "GET",
+			Path:    "/objects/:code/transcript",
+			Handler: controller.HandleGetObjectTranscript,
+		},
 	}
 }
 
-func (controller *ObjectsController) HandleGetObject(c *fiber.Ctx) error {
+func (controller *ObjectsController) HandleGetObjects(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
-	_, tokenValid, err := controller.TokenProvider.Verify(authHeader)
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
+	if !tokenValid {
+		return err
+	}
 
+	// Tokens scoped only to tours can open their stops, but not list venue objects
+	if len(claims.Scopes.VenueIDs) == 0 {
+		HandlerPrintf(c, LOG_WARNING, "Token scope doesn't include a venue")
+		return HandlerSendFailure(c, fiber.StatusForbidden, "Token scope doesn't include a venue")
+	}
+
+	limit := DEFAULT_OBJECTS_PAGE_SIZE
+	if value := c.Query("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MAX_OBJECTS_PAGE_SIZE {
+			HandlerPrintf(c, LOG_WARNING, "Page size is not valid", "limit", value)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Page size is not valid")
+		}
+	}
+
+	// Cursor is the ID of the last object on the previous page
+	afterID := int64(0)
+	if value := c.Query("cursor"); value != "" {
+		afterID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || afterID < 0 {
+			HandlerPrintf(c, LOG_WARNING, "Cursor is not valid", "cursor", value)
+			return HandlerSendFailure(c, fiber.StatusBadRequest, "Cursor is not valid")
+		}
+	}
+
+	// Request one extra object to find out if there is a next page
+	objects, err := controller.ObjectRepository.ListObjects(claims.Scopes.VenueIDs, repository.ListObjectsOptions{
+		Language: c.Query("language"),
+		Fallback: translation.DEFAULT_LANGUAGE.String(),
+		Search:   strings.TrimSpace(c.Query("search")),
+		AfterID:  afterID,
+		Limit:    limit + 1,
+	})
 	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Failed to parse authorization token", "error", err)
-		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse authorization token")
+		HandlerPrintf(c, LOG_ERROR, "Failed to list objects", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to list objects")
+	}
+
+	page := ObjectsPage{Objects: []ObjectsPageItem{}}
+	if len(objects) > limit {
+		objects = objects[:limit]
+		cursor := strconv.FormatInt(objects[limit-1].ID, 10)
+		page.NextCursor = &cursor
+	}
+
+	for _, object := range objects {
+		page.Objects = append(page.Objects, ObjectsPageItem{Object: object, Code: object.Code})
 	}
 
+	return HandlerSendSuccess(c, fiber.StatusOK, page)
+}
+
+func (controller *ObjectsController) HandleGetObject(c *fiber.Ctx) error {
+	authHeader := c.Get("Authorization")
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
 	if !tokenValid {
-		HandlerPrintf(c, LOG_WARNING, "Authorization token is invalid")
-		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Authorization token is invalid")
+		return err
 	}
 
 	objectCode := c.Params("code")
 	language := c.Query("language")
-	object, err := controller.getObject(c, objectCode, language)
+	object, err := controller.getObject(c, claims, objectCode, language)
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
@@ -74,21 +165,14 @@ func (controller *ObjectsController) HandleGetObjectCover(c *fiber.Ctx) error {
 	// Resources are loaded by HTML components thus it's not possible to pass an access token as a header
 	// URL parameter have to be used instead
 	authHeader := c.Query("access-token")
-	_, tokenValid, err := controller.TokenProvider.Verify(authHeader)
-
-	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Failed to parse authorization token", "error", err)
-		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse authorization token")
-	}
-
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
 	if !tokenValid {
-		HandlerPrintf(c, LOG_WARNING, "Authorization token is invalid")
-		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Authorization token is invalid")
+		return err
 	}
 
 	objectCode := c.Params("code")
 	language := c.Query("language")
-	object, err := controller.getObject(c, objectCode, language)
+	object, err := controller.getObject(c, claims, objectCode, language)
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
@@ -118,37 +202,46 @@ func (controller *ObjectsController) HandleGetObjectCover(c *fiber.Ctx) error {
 		return HandlerSendFailure(c, fiber.StatusNotFound, "Cover not found")
 	}
 
-	reader, err := controller.BlobProvider.ReadBlob(coverPath, blob.ReadBlobOptions{})
-	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
-		return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
-	}
-
-	c.Type(filepath.Ext(coverPath))
-	c.Status(fiber.StatusOK)
-
-	return c.SendStream(reader)
+	return sendCover(c, controller.BlobProvider, coverPath)
 }
 
 func (controller *ObjectsController) HandleGetObjectAudio(c *fiber.Ctx) error {
 	// Resources are loaded by HTML components thus it's not possible to pass an access token as a header
 	// URL parameter have to be used instead
 	authHeader := c.Query("access-token")
-	_, tokenValid, err := controller.TokenProvider.Verify(authHeader)
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
+	if !tokenValid {
+		return err
+	}
 
+	objectCode := c.Params("code")
+	language := c.Query("language")
+	object, err := controller.getObject(c, claims, objectCode, language)
 	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Failed to parse authorization token", "error", err)
-		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse authorization token")
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
 	}
 
+	if object == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
+
+	return sendBlobOrRedirect(c, controller.BlobProvider, object.AudioPath)
+}
+
+func (controller *ObjectsController) HandleGetObjectAudioTrack(c *fiber.Ctx) error {
+	// Resources are loaded by HTML components thus it's not possible to pass an access token as a header
+	// URL parameter have to be used instead
+	authHeader := c.Query("access-token")
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
 	if !tokenValid {
-		HandlerPrintf(c, LOG_WARNING, "Authorization token is invalid")
-		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Authorization token is invalid")
+		return err
 	}
 
 	objectCode := c.Params("code")
 	language := c.Query("language")
-	object, err := controller.getObject(c, objectCode, language)
+	object, err := controller.getObject(c, claims, objectCode, language)
 	if err != nil {
 		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
@@ -159,61 +252,88 @@ func (controller *ObjectsController) HandleGetObjectAudio(c *fiber.Ctx) error {
 		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
 	}
 
-	rangesHeader := c.Get(fiber.HeaderRange)
-	if rangesHeader != "" {
-		blobStat, err := controller.BlobProvider.StatBlob(object.AudioPath)
-		if err != nil {
-			HandlerPrintf(c, LOG_ERROR, "Blob stat failed", "error", err)
-			return HandlerSendError(c, fiber.StatusInternalServerError, "Blob stat failed")
+	trackName := c.Params("track")
+	for _, track := range object.Tracks {
+		if track.Name == trackName {
+			return sendBlobOrRedirect(c, controller.BlobProvider, track.AudioPath)
 		}
+	}
 
-		units, ranges, err := controller.parseRange(rangesHeader, blobStat.Size)
-		if err != nil {
-			HandlerPrintf(c, LOG_WARNING, "Failed to parse range header", "error", err)
-			return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse range header")
-		}
+	HandlerPrintf(c, LOG_WARNING, "Audio track not found", "track", trackName)
+	return HandlerSendFailure(c, fiber.StatusNotFound, "Audio track not found")
+}
 
-		if units != "bytes" {
-			HandlerPrintf(c, LOG_WARNING, "Incorrect range units", "error", units)
-			return HandlerSendFailure(c, fiber.StatusRequestedRangeNotSatisfiable, "Incorrect range units")
-		}
+func (controller *ObjectsController) HandleGetObjectTranscript(c *fiber.Ctx) error {
+	// Resources are loaded by HTML components thus it's not possible to pass an access token as a header
+	// URL parameter have to be used instead
+	authHeader := c.Query("access-token")
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
+	if !tokenValid {
+		return err
+	}
 
-		if len(ranges) < 1 {
-			HandlerPrintf(c, LOG_WARNING, "No ranges provided")
-			return HandlerSendFailure(c, fiber.StatusRequestedRangeNotSatisfiable, "No ranges provided")
-		}
+	format := c.Query("format", TRANSCRIPT_FORMAT_TEXT)
+	if format != TRANSCRIPT_FORMAT_TEXT && format != TRANSCRIPT_FORMAT_VTT {
+		HandlerPrintf(c, LOG_WARNING, "Transcript format is not valid", "format", format)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Transcript format is not valid")
+	}
 
-		readOptions := blob.ReadBlobOptions{}
-		readOptions.Range = &ranges[0]
+	objectCode := c.Params("code")
+	language := c.Query("language")
+	object, err := controller.getObject(c, claims, objectCode, language)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get object", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get object")
+	}
 
-		reader, err := controller.BlobProvider.ReadBlob(object.AudioPath, readOptions)
-		if err != nil {
-			HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
-			return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
-		}
+	if object == nil {
+		HandlerPrintf(c, LOG_WARNING, "Object not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Object not found")
+	}
 
-		c.Type(filepath.Ext(object.AudioPath))
-		c.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", readOptions.Range.Start, readOptions.Range.End, blobStat.Size))
-		c.Set("Accept-Ranges", "bytes")
-		c.Status(fiber.StatusPartialContent)
+	if object.TranscriptPath == "" {
+		HandlerPrintf(c, LOG_WARNING, "Transcript not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Transcript not found")
+	}
 
-		return c.SendStream(reader)
-	} else {
-		reader, err := controller.BlobProvider.ReadBlob(object.AudioPath, blob.ReadBlobOptions{})
-		if err != nil {
-			HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
-			return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
-		}
+	// Plain text transcript has no cue timings, so it can't be served as WebVTT
+	isVTT := strings.EqualFold(filepath.Ext(object.TranscriptPath), ".vtt")
+	if format == TRANSCRIPT_FORMAT_VTT && !isVTT {
+		HandlerPrintf(c, LOG_WARNING, "Transcript has no WebVTT cues")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Transcript has no WebVTT cues")
+	}
+
+	reader, err := controller.BlobProvider.ReadBlob(object.TranscriptPath, blob.ReadBlobOptions{})
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Blob read failed", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Blob read failed")
+	}
 
-		c.Type(filepath.Ext(object.AudioPath))
-		c.Set("Accept-Ranges", "bytes")
+	if format == TRANSCRIPT_FORMAT_VTT {
+		c.Set(fiber.HeaderContentType, "text/vtt; charset=utf-8")
 		c.Status(fiber.StatusOK)
+		return c.SendStream(reader)
+	}
 
+	if !isVTT {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		c.Status(fiber.StatusOK)
 		return c.SendStream(reader)
 	}
+
+	defer reader.Close()
+	text, err := convertVTTToText(reader)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to convert transcript", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to convert transcript")
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.Status(fiber.StatusOK).SendString(text)
 }
 
-func (controller *ObjectsController) getObject(c *fiber.Ctx, code string, language string) (*repository.Object, error) {
+// Returns nil if object is not found or belongs to a venue that token doesn't give access to
+func (controller *ObjectsController) getObject(c *fiber.Ctx, claims auth.TokenClaims, code string, language string) (*repository.Object, error) {
 	object, err := controller.ObjectRepository.GetObject(code, language)
 	if err != nil {
 		return nil, err
@@ -229,47 +349,67 @@ func (controller *ObjectsController) getObject(c *fiber.Ctx, code string, langua
 		}
 	}
 
+	if object == nil {
+		return nil, nil
+	}
+
+	inScope, err := controller.isObjectInScope(claims, object)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inScope {
+		HandlerPrintf(c, LOG_WARNING, "Object is out of token scope", "venue", object.VenueID)
+		return nil, nil
+	}
+
 	return object, nil
 }
 
-func (controller *ObjectsController) parseRange(header string, size int64) (string, []blob.BlobRange, error) {
-	if header == "" || !strings.Contains(header, "=") {
-		return "", nil, errors.New("malformed range header string")
+// Object is in the token scope if its venue is, or if it's a stop of one of the token tours
+func (controller *ObjectsController) isObjectInScope(claims auth.TokenClaims, object *repository.Object) (bool, error) {
+	if claims.Scopes.HasVenue(object.VenueID) {
+		return true, nil
 	}
 
-	data := strings.Split(header, "=")
-	const expectedDataParts = 2
-	if len(data) != expectedDataParts {
-		return "", nil, errors.New("malformed range header string")
+	if len(claims.Scopes.TourIDs) == 0 {
+		return false, nil
 	}
 
-	units := data[0]
-	ranges := []blob.BlobRange{}
-	arr := strings.Split(data[1], ",")
-	for i := 0; i < len(arr); i++ {
-		item := strings.Split(arr[i], "-")
-		if len(item) == 1 {
-			return "", nil, errors.New("malformed range header string")
-		}
-		start, startErr := strconv.ParseInt(item[0], 10, 64)
-		end, endErr := strconv.ParseInt(item[1], 10, 64)
-		if startErr != nil { // -nnn
-			start = size - end
-			end = size - 1
-		} else if endErr != nil { // nnn-
-			end = size - 1
-		}
-		if end > size-1 { // limit last-byte-pos to current length
-			end = size - 1
-		}
-		if start > end || start < 0 {
-			continue
+	return controller.TourRepository.IsTourStop(claims.Scopes.TourIDs, object.ID)
+}
+
+var VTT_TAG_REGEXP = regexp.MustCompile(`<[^>]*>`)
+
+// Extracts cue payloads from WebVTT file, cue tags and blocks other than cues are dropped
+func convertVTTToText(reader io.Reader) (string, error) {
+	scanner := bufio.NewScanner(reader)
+	lines := []string{}
+	inCue := false
+	skipBlock := true // header block up to the first blank line
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			inCue = false
+			skipBlock = false
+		case skipBlock:
+		case inCue:
+			text := strings.TrimSpace(html.UnescapeString(VTT_TAG_REGEXP.ReplaceAllString(line, "")))
+			if text != "" {
+				lines = append(lines, text)
+			}
+		case strings.Contains(line, "-->"):
+			inCue = true
+		case strings.HasPrefix(line, "NOTE") || line == "STYLE" || line == "REGION":
+			skipBlock = true
 		}
-		ranges = append(ranges, blob.BlobRange{
-			Start: start,
-			End:   end,
-		})
+		// other lines outside of cues are cue identifiers
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", err
 	}
 
-	return units, ranges, nil
+	return strings.Join(lines, "\n"), nil
 }
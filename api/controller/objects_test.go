@@ -0,0 +1,434 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const TEST_OBJECT_CODE = "object"
+const TEST_OBJECT_AUDIO = "0123456789"
+const TEST_OBJECT_TRANSCRIPT = "WEBVTT\n\n00:00.000 --> 00:02.000\nHello\n"
+const TEST_SECOND_OBJECT_CODE = "second"
+const TEST_OTHER_VENUE_OBJECT_CODE = "other"
+
+func createTestObjectsApp(t *testing.T) (*fiber.App, string) {
+	tokenProvider := auth.CreateMemoryTokenProvider(nil)
+	blobProvider := blob.CreateMemoryBlobProvider()
+	repo := repository.CreateMemoryRepository()
+
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	objectID, _ := repo.CreateObject(TEST_OBJECT_CODE, venueID)
+	repo.SetObjectCovers(objectID, []repository.Cover{{Index: 0, Path: "object/cover.jpg"}})
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{
+		Language: "en", Title: "Title", Description: "Description", Author: "Author", Location: "Hall 1",
+		Tags: []string{"painting"}, AudioPath: "object/audio-en.mp3", AudioDurationMs: 90000, TranscriptPath: "object/transcript-en.vtt",
+	})
+	repo.SetObjectTranslation(objectID, repository.ObjectTranslation{
+		Language: "be", Title: "Назва", Description: "Апісанне", Author: "Аўтар", Location: "Зала 1",
+		Tags: []string{"карціна"}, AudioPath: "object/audio-be.mp3", TranscriptPath: "object/transcript-be.txt",
+	})
+	repo.SetObjectTracks(objectID, "en", []repository.AudioTrack{
+		{Name: "kids", Index: 1, DurationMs: 60000, AudioPath: "object/kids-en.mp3"},
+		{Name: "short", Index: 0, DurationMs: 30000, AudioPath: "object/short-en.mp3"},
+	})
+	blobProvider.WriteBlob("object/kids-en.mp3", strings.NewReader("kids-audio"))
+	blobProvider.WriteBlob("object/short-en.mp3", strings.NewReader("short-audio"))
+	year := 1890
+	repo.SetObjectYear(objectID, &year)
+	blobProvider.WriteBlob("object/cover.jpg", strings.NewReader("cover"))
+	blobProvider.WriteBlob("object/audio-en.mp3", strings.NewReader(TEST_OBJECT_AUDIO))
+	blobProvider.WriteBlob("object/audio-be.mp3", strings.NewReader("be-audio"))
+	blobProvider.WriteBlob("object/transcript-en.vtt", strings.NewReader(TEST_OBJECT_TRANSCRIPT))
+	blobProvider.WriteBlob("object/transcript-be.txt", strings.NewReader("Тэкст"))
+
+	secondObjectID, _ := repo.CreateObject(TEST_SECOND_OBJECT_CODE, venueID)
+	repo.SetObjectTranslation(secondObjectID, repository.ObjectTranslation{Language: "en", Title: "Second", AudioPath: "object/audio-en.mp3"})
+
+	otherVenueID := repo.AddVenue(repository.Venue{Code: "gallery", Name: "Gallery"})
+	otherObjectID, _ := repo.CreateObject(TEST_OTHER_VENUE_OBJECT_CODE, otherVenueID)
+	repo.SetObjectCovers(otherObjectID, []repository.Cover{{Index: 0, Path: "object/cover.jpg"}})
+	repo.SetObjectTranslation(otherObjectID, repository.ObjectTranslation{Language: "en", Title: "Other", AudioPath: "object/audio-en.mp3"})
+
+	token := createTestToken(t, tokenProvider, auth.TokenScopes{VenueIDs: []int64{venueID}})
+
+	app := createTestApp(&ObjectsController{
+		TokenProvider:    tokenProvider,
+		BlobProvider:     blobProvider,
+		ObjectRepository: repo,
+		TourRepository:   repo,
+	})
+
+	return app, token
+}
+
+func TestGetObjectLanguageFallback(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	cases := map[string]string{
+		"en": "Title",
+		"be": "Назва",
+		"ru": "Title",
+	}
+
+	for language, title := range cases {
+		request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"?language="+language, nil)
+		request.Header.Set("Authorization", token)
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d: %s", response.StatusCode, body)
+		}
+
+		object := repository.Object{}
+		parseTestResponse(t, body, &object)
+		if object.Title != title {
+			t.Fatalf("language %s: expected title %q, got %q", language, title, object.Title)
+		}
+
+		if strings.Contains(string(body), `"code"`) {
+			t.Fatalf("object response must not include the code: %s", body)
+		}
+	}
+}
+
+func TestGetObjectMetadata(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	cases := map[string]repository.Object{
+		"be": {Description: "Апісанне", Author: "Аўтар", Location: "Зала 1", Tags: []string{"карціна"}},
+		"ru": {Description: "Description", Author: "Author", Location: "Hall 1", Tags: []string{"painting"}, AudioDurationMs: 90000},
+	}
+
+	for language, expected := range cases {
+		request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"?language="+language, nil)
+		request.Header.Set("Authorization", token)
+		_, body := sendTestRequest(t, app, request)
+
+		object := repository.Object{}
+		parseTestResponse(t, body, &object)
+		if object.Description != expected.Description || object.Author != expected.Author || object.Location != expected.Location ||
+			object.AudioDurationMs != expected.AudioDurationMs {
+			t.Fatalf("language %s: unexpected metadata %+v", language, object)
+		}
+
+		if len(object.Tags) != 1 || object.Tags[0] != expected.Tags[0] {
+			t.Fatalf("language %s: unexpected tags %v", language, object.Tags)
+		}
+
+		if object.Year == nil || *object.Year != 1890 {
+			t.Fatalf("language %s: unexpected year %v", language, object.Year)
+		}
+	}
+}
+
+func TestGetObjectAuthorization(t *testing.T) {
+	app, _ := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE, nil)
+	request.Header.Set("Authorization", "invalid")
+	response, _ := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", response.StatusCode)
+	}
+
+	request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio?access-token=invalid", nil)
+	response, _ = sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", response.StatusCode)
+	}
+}
+
+func TestGetObjectNotFound(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects/unknown?language=en", nil)
+	request.Header.Set("Authorization", token)
+	response, _ := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found, got %d", response.StatusCode)
+	}
+}
+
+func TestGetObjectOtherVenue(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	for _, path := range []string{"", "/covers/0", "/audio"} {
+		request := httptest.NewRequest("GET", "/objects/"+TEST_OTHER_VENUE_OBJECT_CODE+path+"?access-token="+token, nil)
+		request.Header.Set("Authorization", token)
+		response, _ := sendTestRequest(t, app, request)
+		if response.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s: expected object of another venue to be not found, got %d", path, response.StatusCode)
+		}
+	}
+}
+
+func TestGetObjectCover(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/covers/0?access-token="+token, nil)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusOK || string(body) != "cover" {
+		t.Fatalf("unexpected cover response %d: %s", response.StatusCode, body)
+	}
+
+	if response.Header.Get(fiber.HeaderContentType) != "image/jpeg" {
+		t.Fatalf("unexpected cover content type %q", response.Header.Get(fiber.HeaderContentType))
+	}
+
+	request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/covers/1?access-token="+token, nil)
+	response, _ = sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected missing cover to be not found, got %d", response.StatusCode)
+	}
+}
+
+func TestGetObjectCoverRedirect(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/covers/0?redirect=true&access-token="+token, nil)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusFound {
+		t.Fatalf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	if !strings.HasPrefix(response.Header.Get(fiber.HeaderLocation), "https://blobs.test/object/cover.jpg?") {
+		t.Fatalf("unexpected redirect location %q", response.Header.Get(fiber.HeaderLocation))
+	}
+
+	if response.Header.Get(fiber.HeaderCacheControl) != "no-store" {
+		t.Fatalf("expected redirect not to be cached, got %q", response.Header.Get(fiber.HeaderCacheControl))
+	}
+
+	request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/covers/0?redirect=true&access-token=invalid", nil)
+	response, _ = sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", response.StatusCode)
+	}
+}
+
+func TestGetObjectAudioRange(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	cases := []struct {
+		header       string
+		status       int
+		body         string
+		contentRange string
+	}{
+		{"", http.StatusOK, TEST_OBJECT_AUDIO, ""},
+		{"bytes=2-5", http.StatusPartialContent, "2345", "bytes 2-5/10"},
+		{"bytes=7-", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=-3", http.StatusPartialContent, "789", "bytes 7-9/10"},
+		{"bytes=5-100", http.StatusPartialContent, "56789", "bytes 5-9/10"},
+		{"items=0-1", http.StatusRequestedRangeNotSatisfiable, "", ""},
+	}
+
+	for _, test := range cases {
+		request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio?language=en&access-token="+token, nil)
+		if test.header != "" {
+			request.Header.Set(fiber.HeaderRange, test.header)
+		}
+
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != test.status {
+			t.Fatalf("range %q: expected status %d, got %d", test.header, test.status, response.StatusCode)
+		}
+
+		if test.status == http.StatusRequestedRangeNotSatisfiable {
+			continue
+		}
+
+		if string(body) != test.body {
+			t.Fatalf("range %q: expected body %q, got %q", test.header, test.body, body)
+		}
+
+		if response.Header.Get(fiber.HeaderContentRange) != test.contentRange {
+			t.Fatalf("range %q: expected content range %q, got %q", test.header, test.contentRange, response.Header.Get(fiber.HeaderContentRange))
+		}
+	}
+}
+
+func TestGetObjectsPagination(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects?language=be&limit=1", nil)
+	request.Header.Set("Authorization", token)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	page := ObjectsPage{}
+	parseTestResponse(t, body, &page)
+	if len(page.Objects) != 1 || page.Objects[0].Code != TEST_OBJECT_CODE || page.Objects[0].Title != "Назва" {
+		t.Fatalf("unexpected first page %+v", page.Objects)
+	}
+
+	if page.NextCursor == nil {
+		t.Fatalf("expected next page cursor")
+	}
+
+	request = httptest.NewRequest("GET", "/objects?language=be&limit=1&cursor="+*page.NextCursor, nil)
+	request.Header.Set("Authorization", token)
+	_, body = sendTestRequest(t, app, request)
+
+	page = ObjectsPage{}
+	parseTestResponse(t, body, &page)
+	if len(page.Objects) != 1 || page.Objects[0].Code != TEST_SECOND_OBJECT_CODE || page.Objects[0].Title != "Second" {
+		t.Fatalf("unexpected second page %+v", page.Objects)
+	}
+
+	if page.NextCursor != nil {
+		t.Fatalf("expected last page to have no cursor")
+	}
+
+	for _, query := range []string{"limit=0", "limit=1000", "cursor=invalid"} {
+		request = httptest.NewRequest("GET", "/objects?"+query, nil)
+		request.Header.Set("Authorization", token)
+		response, _ = sendTestRequest(t, app, request)
+		if response.StatusCode != http.StatusBadRequest {
+			t.Fatalf("%s: expected bad request, got %d", query, response.StatusCode)
+		}
+	}
+}
+
+func TestGetObjectsSearch(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	cases := map[string][]string{
+		"titl":   {TEST_OBJECT_CODE},
+		"SECOND": {TEST_SECOND_OBJECT_CODE},
+		"%":      {},
+		"":       {TEST_OBJECT_CODE, TEST_SECOND_OBJECT_CODE},
+	}
+
+	for search, codes := range cases {
+		request := httptest.NewRequest("GET", "/objects?language=en&search="+url.QueryEscape(search), nil)
+		request.Header.Set("Authorization", token)
+		_, body := sendTestRequest(t, app, request)
+
+		page := ObjectsPage{}
+		parseTestResponse(t, body, &page)
+		if len(page.Objects) != len(codes) {
+			t.Fatalf("search %q: expected %d objects, got %+v", search, len(codes), page.Objects)
+		}
+
+		for i, code := range codes {
+			if page.Objects[i].Code != code {
+				t.Fatalf("search %q: expected object %s, got %s", search, code, page.Objects[i].Code)
+			}
+		}
+	}
+}
+
+func TestGetObjectTranscript(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	cases := []struct {
+		query       string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"language=en&format=vtt", http.StatusOK, "text/vtt; charset=utf-8", TEST_OBJECT_TRANSCRIPT},
+		{"language=en&format=text", http.StatusOK, fiber.MIMETextPlainCharsetUTF8, "Hello"},
+		{"language=be", http.StatusOK, fiber.MIMETextPlainCharsetUTF8, "Тэкст"},
+		{"language=be&format=vtt", http.StatusNotFound, "", ""},
+		{"language=en&format=srt", http.StatusBadRequest, "", ""},
+	}
+
+	for _, test := range cases {
+		request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/transcript?access-token="+token+"&"+test.query, nil)
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != test.status {
+			t.Fatalf("%s: expected status %d, got %d", test.query, test.status, response.StatusCode)
+		}
+
+		if test.status != http.StatusOK {
+			continue
+		}
+
+		if response.Header.Get(fiber.HeaderContentType) != test.contentType || string(body) != test.body {
+			t.Fatalf("%s: unexpected transcript %q of type %q", test.query, body, response.Header.Get(fiber.HeaderContentType))
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/objects/"+TEST_SECOND_OBJECT_CODE+"/transcript?access-token="+token, nil)
+	response, _ := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected object without transcript to be not found, got %d", response.StatusCode)
+	}
+}
+
+func TestConvertVTTToText(t *testing.T) {
+	vtt := `WEBVTT - Guide
+Kind: captions
+
+NOTE curator comment
+spanning lines
+
+STYLE
+::cue { color: yellow }
+
+intro
+00:00.000 --> 00:02.000 align:start
+<v Guide>Welcome to the <b>museum</b>!</v>
+
+00:02.000 --> 00:04.000
+Tom &amp; Jerry
+were here
+`
+
+	text, err := convertVTTToText(strings.NewReader(vtt))
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	expected := "Welcome to the museum!\nTom & Jerry\nwere here"
+	if text != expected {
+		t.Fatalf("expected %q, got %q", expected, text)
+	}
+}
+
+func TestGetObjectAudioTracks(t *testing.T) {
+	app, token := createTestObjectsApp(t)
+
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"?language=en", nil)
+	request.Header.Set("Authorization", token)
+	_, body := sendTestRequest(t, app, request)
+
+	object := repository.Object{}
+	parseTestResponse(t, body, &object)
+	if len(object.Tracks) != 2 || object.Tracks[0].Name != "short" || object.Tracks[1].DurationMs != 60000 {
+		t.Fatalf("expected tracks in order, got %+v", object.Tracks)
+	}
+
+	request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio/kids?language=en&access-token="+token, nil)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusOK || string(body) != "kids-audio" {
+		t.Fatalf("unexpected track response %d: %s", response.StatusCode, body)
+	}
+
+	request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio/kids?language=en&access-token="+token, nil)
+	request.Header.Set(fiber.HeaderRange, "bytes=0-3")
+	response, body = sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusPartialContent || string(body) != "kids" {
+		t.Fatalf("unexpected track range response %d: %s", response.StatusCode, body)
+	}
+
+	// Tracks belong to a translation, Belarusian translation has none
+	for _, query := range []string{"unknown?language=en", "kids?language=be"} {
+		request = httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE+"/audio/"+query+"&access-token="+token, nil)
+		response, _ = sendTestRequest(t, app, request)
+		if response.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s: expected track to be not found, got %d", query, response.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Refunds the ticket payment if the payment provider supports refunds through Bot API and revokes the ticket,
+// other payments must be refunded in the provider dashboard with the returned provider charge ID
+func (controller *AdminController) HandleRefundTicket(c *fiber.Ctx) error {
+	if !controller.isAuthorized(c) {
+		HandlerPrintf(c, LOG_WARNING, "Admin token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Admin token is invalid")
+	}
+
+	ticketCode, err := uuid.Parse(c.Params("code"))
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse ticket code", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse ticket code")
+	}
+
+	ticket, err := controller.TicketRepository.GetTicket(ticketCode.String())
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket")
+	}
+
+	if ticket == nil {
+		HandlerPrintf(c, LOG_WARNING, "Ticket not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Ticket not found")
+	}
+
+	if ticket.RevokedAt != nil {
+		HandlerPrintf(c, LOG_WARNING, "Ticket already revoked")
+		return HandlerSendFailure(c, fiber.StatusConflict, "Ticket already revoked")
+	}
+
+	// Revocation is made first and works as a lock, so concurrent requests can't refund the payment twice.
+	// Postgres keeps microseconds, the timestamp is truncated to match the stored one on restore
+	revokedAt := time.Now().Truncate(time.Microsecond)
+	revoked, err := controller.TicketRepository.RevokeTicket(ticket.Code, revokedAt)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to revoke ticket", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to revoke ticket")
+	}
+
+	if !revoked {
+		HandlerPrintf(c, LOG_WARNING, "Ticket already revoked")
+		return HandlerSendFailure(c, fiber.StatusConflict, "Ticket already revoked")
+	}
+
+	// Tickets bought before purchases were tracked can only be revoked
+	purchase := ticket.Purchase
+	refunded := false
+	if purchase.TelegramChargeID != "" {
+		refunded, err = controller.BotProvider.RefundPayment(purchase.BuyerUserID, purchase.TelegramChargeID, purchase.Currency)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to refund payment", "error", err)
+
+			// Ticket stays valid if the payment is not refunded, so the refund can be retried
+			restored, restoreErr := controller.TicketRepository.RestoreTicket(ticket.Code, revokedAt)
+			if restoreErr != nil || !restored {
+				HandlerPrintf(c, LOG_ERROR, "Failed to restore ticket after failed refund", "ticket", ticket.Code, "error", restoreErr)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Ticket is revoked, but payment is not refunded")
+			}
+
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to refund payment, ticket is not revoked")
+		}
+	}
+
+	result := struct {
+		Refunded         bool      `json:"refunded"`
+		RevokedAt        time.Time `json:"revoked_at"`
+		ProviderChargeID string    `json:"provider_payment_charge_id,omitempty"`
+	}{
+		Refunded:         refunded,
+		RevokedAt:        revokedAt,
+		ProviderChargeID: purchase.ProviderChargeID,
+	}
+
+	HandlerPrintf(c, LOG_INFO, "Revoked ticket", "ticket", ticket.Code, "refunded", refunded)
+	return HandlerSendSuccess(c, fiber.StatusOK, result)
+}
@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+type testRefundResult struct {
+	Refunded         bool   `json:"refunded"`
+	ProviderChargeID string `json:"provider_payment_charge_id"`
+}
+
+func createTestRefundsApp() (*fiber.App, *auth.MemoryTokenProvider, *bot.MemoryBotProvider, *repository.MemoryRepository) {
+	repo := repository.CreateMemoryRepository()
+	tokenProvider := auth.CreateMemoryTokenProvider(repo)
+	botProvider := bot.CreateMemoryBotProvider()
+	app := createTestApp(
+		&TicketsController{
+			TokenProvider:          tokenProvider,
+			TicketRepository:       repo,
+			RefreshTokenRepository: repo,
+		},
+		&AdminController{
+			AdminToken:       TEST_ADMIN_TOKEN,
+			TicketRepository: repo,
+			BotProvider:      botProvider,
+		},
+	)
+
+	return app, tokenProvider, botProvider, repo
+}
+
+func refundTestTicket(t *testing.T, app *fiber.App, ticketCode string) (int, testRefundResult) {
+	request := httptest.NewRequest("POST", "/admin/tickets/"+ticketCode+"/refund", nil)
+	request.Header.Set(fiber.HeaderAuthorization, TEST_ADMIN_TOKEN)
+
+	result := testRefundResult{}
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode == http.StatusOK {
+		parseTestResponse(t, body, &result)
+	}
+
+	return response.StatusCode, result
+}
+
+func TestRefundTicket(t *testing.T) {
+	app, tokenProvider, botProvider, repo := createTestRefundsApp()
+	ticketCode := uuid.NewString()
+	ticketTypeID := repo.AddTicketType(repository.TicketType{Activations: 2, ValidityDays: 1, TimeZone: "UTC"})
+	repo.CreateTicket(ticketCode, ticketTypeID, repository.TicketPurchase{
+		BuyerUserID: TEST_USER_ID, TelegramChargeID: "telegram-charge", Amount: 50, Currency: bot.STARS_CURRENCY,
+	})
+
+	status, token := exchangeTestTicket(t, app, ticketCode)
+	if status != http.StatusCreated {
+		t.Fatalf("unexpected status %d", status)
+	}
+
+	status, result := refundTestTicket(t, app, ticketCode)
+	if status != http.StatusOK || !result.Refunded {
+		t.Fatalf("unexpected refund response %d: %+v", status, result)
+	}
+
+	if len(botProvider.Refunds) != 1 || botProvider.Refunds[0].UserID != TEST_USER_ID || botProvider.Refunds[0].TelegramChargeID != "telegram-charge" {
+		t.Fatalf("unexpected refunds %+v", botProvider.Refunds)
+	}
+
+	if _, valid, _ := tokenProvider.Verify(token); valid {
+		t.Fatalf("expected token of the revoked ticket to be rejected")
+	}
+
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusForbidden {
+		t.Fatalf("expected revoked ticket to be forbidden, got %d", status)
+	}
+
+	if status, _ := refundTestTicket(t, app, ticketCode); status != http.StatusConflict {
+		t.Fatalf("expected second refund to conflict, got %d", status)
+	}
+
+	if len(botProvider.Refunds) != 1 {
+		t.Fatalf("payment was refunded twice")
+	}
+}
+
+func TestRefundTicketProviderPayment(t *testing.T) {
+	app, _, botProvider, repo := createTestRefundsApp()
+	ticketCode := uuid.NewString()
+	ticketTypeID := repo.AddTicketType(repository.TicketType{Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+	repo.CreateTicket(ticketCode, ticketTypeID, repository.TicketPurchase{
+		BuyerUserID: TEST_USER_ID, TelegramChargeID: "telegram-charge", ProviderChargeID: "provider-charge", Amount: 500, Currency: "USD",
+	})
+
+	// Payment is refunded manually, but the ticket is still revoked
+	status, result := refundTestTicket(t, app, ticketCode)
+	if status != http.StatusOK || result.Refunded || result.ProviderChargeID != "provider-charge" {
+		t.Fatalf("unexpected refund response %d: %+v", status, result)
+	}
+
+	if len(botProvider.Refunds) != 0 {
+		t.Fatalf("unexpected refunds %+v", botProvider.Refunds)
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode)
+	if ticket.RevokedAt == nil {
+		t.Fatalf("ticket was not revoked")
+	}
+
+	cases := map[string]int{
+		uuid.NewString(): http.StatusNotFound,
+		"not-a-ticket":   http.StatusBadRequest,
+	}
+
+	for code, expected := range cases {
+		if status, _ := refundTestTicket(t, app, code); status != expected {
+			t.Fatalf("%s: expected status %d, got %d", code, expected, status)
+		}
+	}
+}
+
+func TestRefundTicketFailedRefund(t *testing.T) {
+	app, _, botProvider, repo := createTestRefundsApp()
+	ticketCode := uuid.NewString()
+	ticketTypeID := repo.AddTicketType(repository.TicketType{Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+	repo.CreateTicket(ticketCode, ticketTypeID, repository.TicketPurchase{
+		BuyerUserID: TEST_USER_ID, TelegramChargeID: "telegram-charge", Amount: 50, Currency: bot.STARS_CURRENCY,
+	})
+
+	// Ticket stays valid if the payment is not refunded, so the refund can be retried
+	botProvider.RefundError = errors.New("refund failed")
+	if status, _ := refundTestTicket(t, app, ticketCode); status != http.StatusInternalServerError {
+		t.Fatalf("expected failed refund to return internal error, got %d", status)
+	}
+
+	if ticket, _ := repo.GetTicket(ticketCode); ticket.RevokedAt != nil {
+		t.Fatalf("expected ticket to stay valid after failed refund")
+	}
+
+	botProvider.RefundError = nil
+	if status, result := refundTestTicket(t, app, ticketCode); status != http.StatusOK || !result.Refunded {
+		t.Fatalf("unexpected retried refund response %d: %+v", status, result)
+	}
+}
+
+type testFailingRevocationList struct{}
+
+func (list testFailingRevocationList) IsTicketRevoked(ticketID int64) (bool, error) {
+	return false, errors.New("database is down")
+}
+
+func TestRevocationCheckError(t *testing.T) {
+	tokenProvider := auth.CreateMemoryTokenProvider(testFailingRevocationList{})
+	repo := repository.CreateMemoryRepository()
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	repo.CreateObject(TEST_OBJECT_CODE, venueID)
+
+	token, err := tokenProvider.Create(auth.TokenClaims{
+		TicketID: 1, ExpiresAt: time.Now().Add(time.Hour), Audience: auth.API_AUDIENCE, Scopes: auth.TokenScopes{VenueIDs: []int64{venueID}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	app := createTestApp(&ObjectsController{TokenProvider: tokenProvider, ObjectRepository: repo, TourRepository: repo})
+	request := httptest.NewRequest("GET", "/objects/"+TEST_OBJECT_CODE, nil)
+	request.Header.Set("Authorization", token)
+	if response, _ := sendTestRequest(t, app, request); response.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected failed revocation check to return internal error, got %d", response.StatusCode)
+	}
+}
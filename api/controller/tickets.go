@@ -1,6 +1,11 @@
 package controller
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,9 +14,22 @@ import (
 	"github.com/st-matskevich/audio-guide-bot/api/repository"
 )
 
+// Mini App passes its init data in "Authorization: tma <initData>" header
+const INIT_DATA_AUTH_SCHEME = "tma "
+
+// Access tokens are short-lived and renewed with refresh tokens until the ticket expires
+const ACCESS_TOKEN_LIFETIME = time.Hour
+const REFRESH_TOKEN_BYTES = 32
+
+// Devices of the user share tokens in CloudStorage and may refresh the same token at once,
+// reuse within this period is reported as a conflict instead of revoking the family
+const REFRESH_TOKEN_REUSE_GRACE = 30 * time.Second
+
 type TicketsController struct {
-	TokenProvider    auth.TokenProvider
-	TicketRepository repository.TicketRepository
+	TokenProvider          auth.TokenProvider
+	InitDataValidator      auth.InitDataValidator
+	TicketRepository       repository.TicketRepository
+	RefreshTokenRepository repository.RefreshTokenRepository
 }
 
 func (controller *TicketsController) GetRoutes() []Route {
@@ -21,6 +39,11 @@ func (controller *TicketsController) GetRoutes() []Route {
 			Path:    "/tickets/:code/token",
 			Handler: controller.HandleExchangeTicketForToken,
 		},
+		{
+			Method:  "POST",
+			Path:    "/tokens/refresh",
+			Handler: controller.HandleRefreshToken,
+		},
 	}
 }
 
@@ -31,21 +54,179 @@ func (controller *TicketsController) HandleExchangeTicketForToken(c *fiber.Ctx)
 		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse input")
 	}
 
-	active, err := controller.TicketRepository.ActivateTicket(ticketCode.String())
+	// Tickets exchanged with init data are bound to the Telegram user,
+	// the same user can get another token without another activation
+	userID := int64(0)
+	if initData, found := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), INIT_DATA_AUTH_SCHEME); found && initData != "" {
+		// Malformed init data is as untrusted as init data with a wrong signature
+		user, valid, err := controller.InitDataValidator.Validate(initData)
+		if err != nil || !valid {
+			HandlerPrintf(c, LOG_WARNING, "Init data is invalid", "error", err)
+			return HandlerSendFailure(c, fiber.StatusUnauthorized, "Init data is invalid")
+		}
+
+		userID = user.ID
+	}
+
+	ticket, err := controller.TicketRepository.GetTicket(ticketCode.String())
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket")
+	}
+
+	if ticket == nil {
+		HandlerPrintf(c, LOG_WARNING, "Requested ticket not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Requested ticket not found")
+	}
+
+	if ticket.RevokedAt != nil {
+		HandlerPrintf(c, LOG_WARNING, "Requested ticket revoked")
+		return HandlerSendFailure(c, fiber.StatusForbidden, "Requested ticket revoked")
+	}
+
+	// Validity window starts with the first activation
+	currentTime := time.Now()
+	activatedAt := currentTime
+	if ticket.ActivatedAt != nil {
+		activatedAt = *ticket.ActivatedAt
+	}
+
+	expires, err := getTicketExpiry(ticket.Type, activatedAt)
 	if err != nil {
-		HandlerPrintf(c, LOG_ERROR, "Failed to activate ticket", "error", err)
-		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to activate ticket")
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket expiry", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket expiry")
 	}
 
-	if !active {
-		HandlerPrintf(c, LOG_WARNING, "Requested ticket already activated")
-		return HandlerSendFailure(c, fiber.StatusForbidden, "Requested ticket already activated")
+	if !expires.After(currentTime) {
+		HandlerPrintf(c, LOG_WARNING, "Requested ticket expired")
+		return HandlerSendFailure(c, fiber.StatusForbidden, "Requested ticket expired")
+	}
+
+	holder := false
+	if userID != 0 {
+		holder, err = controller.TicketRepository.IsTicketHolder(ticket.ID, userID)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get ticket holder", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket holder")
+		}
+	} else {
+		// Anonymous exchange of a bound ticket would bypass the holder binding
+		bound, err := controller.TicketRepository.HasTicketHolders(ticket.ID)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get ticket holders", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket holders")
+		}
+
+		if bound {
+			HandlerPrintf(c, LOG_WARNING, "Requested ticket is bound to a Telegram user")
+			return HandlerSendFailure(c, fiber.StatusUnauthorized, "Requested ticket requires init data")
+		}
+	}
+
+	if holder {
+		HandlerPrintf(c, LOG_INFO, "Re-issuing token to ticket holder")
+	} else {
+		active, err := controller.TicketRepository.ActivateTicket(ticketCode.String(), currentTime, userID)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to activate ticket", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to activate ticket")
+		}
+
+		if !active {
+			HandlerPrintf(c, LOG_WARNING, "Requested ticket activations limit reached")
+			return HandlerSendFailure(c, fiber.StatusForbidden, "Requested ticket activations limit reached")
+		}
+	}
+
+	// Every exchange starts a new family of refresh tokens
+	return controller.sendTokens(c, *ticket, userID, uuid.NewString(), currentTime, expires)
+}
+
+// Exchanges a refresh token for a new access token and a new refresh token of the same family,
+// reuse of an exchanged refresh token revokes the family, since the token might be stolen
+func (controller *TicketsController) HandleRefreshToken(c *fiber.Ctx) error {
+	request := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{}
+
+	if err := c.BodyParser(&request); err != nil || request.RefreshToken == "" {
+		HandlerPrintf(c, LOG_ERROR, "Failed to parse input", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse input")
 	}
 
 	currentTime := time.Now()
-	expires := time.Date(currentTime.Year(), currentTime.Month(), currentTime.Day()+1, 0, 0, 0, 0, time.UTC)
+	tokenHash := hashRefreshToken(request.RefreshToken)
+	refreshToken, err := controller.RefreshTokenRepository.UseRefreshToken(tokenHash, currentTime)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to use refresh token", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to use refresh token")
+	}
+
+	if refreshToken == nil {
+		previous, err := controller.RefreshTokenRepository.GetRefreshToken(tokenHash)
+		if err != nil {
+			HandlerPrintf(c, LOG_ERROR, "Failed to get refresh token", "error", err)
+			return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get refresh token")
+		}
+
+		if previous != nil && previous.RevokedAt == nil && previous.UsedAt != nil && currentTime.Sub(*previous.UsedAt) < REFRESH_TOKEN_REUSE_GRACE {
+			HandlerPrintf(c, LOG_WARNING, "Refresh token was just used", "family", previous.FamilyID)
+			return HandlerSendFailure(c, fiber.StatusConflict, "Refresh token was just used")
+		}
+
+		if previous != nil {
+			HandlerPrintf(c, LOG_WARNING, "Refresh token reuse detected", "family", previous.FamilyID)
+			err := controller.RefreshTokenRepository.RevokeRefreshTokenFamily(previous.FamilyID, currentTime)
+			if err != nil {
+				HandlerPrintf(c, LOG_ERROR, "Failed to revoke refresh tokens", "error", err)
+				return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to revoke refresh tokens")
+			}
+		}
+
+		HandlerPrintf(c, LOG_WARNING, "Refresh token is invalid")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Refresh token is invalid")
+	}
+
+	if !refreshToken.ExpiresAt.After(currentTime) {
+		HandlerPrintf(c, LOG_WARNING, "Refresh token expired")
+		return HandlerSendFailure(c, fiber.StatusUnauthorized, "Refresh token expired")
+	}
+
+	ticket, err := controller.TicketRepository.GetTicketByID(refreshToken.TicketID)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get ticket", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get ticket")
+	}
+
+	if ticket == nil {
+		HandlerPrintf(c, LOG_WARNING, "Requested ticket not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Requested ticket not found")
+	}
+
+	if ticket.RevokedAt != nil {
+		HandlerPrintf(c, LOG_WARNING, "Requested ticket revoked")
+		return HandlerSendFailure(c, fiber.StatusForbidden, "Requested ticket revoked")
+	}
+
+	return controller.sendTokens(c, *ticket, refreshToken.UserID, refreshToken.FamilyID, currentTime, refreshToken.ExpiresAt)
+}
+
+// Sends an access token valid for ACCESS_TOKEN_LIFETIME, but not after the ticket expires,
+// and a refresh token of the family, that renews access tokens until the ticket expires
+func (controller *TicketsController) sendTokens(c *fiber.Ctx, ticket repository.Ticket, userID int64, familyID string, issuedAt time.Time, expires time.Time) error {
+	accessExpires := issuedAt.Add(ACCESS_TOKEN_LIFETIME)
+	if accessExpires.After(expires) {
+		accessExpires = expires
+	}
+
 	claims := auth.TokenClaims{
-		ExpiresAt: expires,
+		ID:        uuid.NewString(),
+		TicketID:  ticket.ID,
+		IssuedAt:  issuedAt,
+		ExpiresAt: accessExpires,
+		Audience:  auth.API_AUDIENCE,
+		Scopes:    auth.TokenScopes{VenueIDs: []int64{ticket.Type.VenueID}},
+		UserID:    userID,
 	}
 
 	tokenString, err := controller.TokenProvider.Create(claims)
@@ -54,10 +235,61 @@ func (controller *TicketsController) HandleExchangeTicketForToken(c *fiber.Ctx)
 		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to sign token")
 	}
 
+	refreshTokenString, err := createRefreshToken()
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to generate refresh token", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to generate refresh token")
+	}
+
+	refreshToken := repository.RefreshToken{
+		FamilyID:  familyID,
+		TicketID:  ticket.ID,
+		UserID:    userID,
+		CreatedAt: issuedAt,
+		ExpiresAt: expires,
+	}
+
+	err = controller.RefreshTokenRepository.CreateRefreshToken(hashRefreshToken(refreshTokenString), refreshToken)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to save refresh token", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to save refresh token")
+	}
+
 	result := struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}{}
 
 	result.Token = tokenString
+	result.RefreshToken = refreshTokenString
 	return HandlerSendSuccess(c, fiber.StatusCreated, result)
 }
+
+func createRefreshToken() (string, error) {
+	token := make([]byte, REFRESH_TOKEN_BYTES)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// Only hashes of refresh tokens are stored, so a leaked database doesn't leak usable tokens
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// Ticket is valid until the midnight in the ticket type time zone,
+// that comes after the ticket type validity days since the activation
+func getTicketExpiry(ticketType repository.TicketType, activatedAt time.Time) (time.Time, error) {
+	location, err := time.LoadLocation(ticketType.TimeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	local := activatedAt.In(location)
+	expires := time.Date(local.Year(), local.Month(), local.Day()+ticketType.ValidityDays, 0, 0, 0, 0, location)
+
+	return expires, nil
+}
@@ -0,0 +1,350 @@
+package controller
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+const TEST_BOT_TOKEN = "123456:bot-token"
+
+func createTestTicketsApp() (*fiber.App, *auth.MemoryTokenProvider, *repository.MemoryRepository) {
+	repo := repository.CreateMemoryRepository()
+	tokenProvider := auth.CreateMemoryTokenProvider(repo)
+	initDataValidator, _ := auth.CreateTelegramInitDataValidator(TEST_BOT_TOKEN, auth.DEFAULT_INIT_DATA_MAX_AGE)
+	app := createTestApp(&TicketsController{
+		TokenProvider:          tokenProvider,
+		InitDataValidator:      initDataValidator,
+		TicketRepository:       repo,
+		RefreshTokenRepository: repo,
+	})
+
+	return app, tokenProvider, repo
+}
+
+func createTestTicket(repo *repository.MemoryRepository, ticketType repository.TicketType) string {
+	ticketCode := uuid.NewString()
+	repo.CreateTicket(ticketCode, repo.AddTicketType(ticketType), repository.TicketPurchase{BuyerUserID: TEST_USER_ID})
+	return ticketCode
+}
+
+// Signs init data of the user the same way Telegram does
+func createTestInitData(userID int64, authDate time.Time, botToken string) string {
+	values := url.Values{}
+	values.Set("auth_date", strconv.FormatInt(authDate.Unix(), 10))
+	values.Set("query_id", "query")
+	values.Set("user", `{"id":`+strconv.FormatInt(userID, 10)+`,"first_name":"Test","language_code":"en"}`)
+
+	checkString := "auth_date=" + values.Get("auth_date") + "\nquery_id=" + values.Get("query_id") + "\nuser=" + values.Get("user")
+	secret := hmac.New(sha256.New, []byte("WebAppData"))
+	secret.Write([]byte(botToken))
+	mac := hmac.New(sha256.New, secret.Sum(nil))
+	mac.Write([]byte(checkString))
+	values.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+
+	return values.Encode()
+}
+
+type testTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func sendTestTokenRequest(t *testing.T, app *fiber.App, request *http.Request) (int, testTokens) {
+	result := testTokens{}
+	request.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode == http.StatusCreated {
+		parseTestResponse(t, body, &result)
+	}
+
+	return response.StatusCode, result
+}
+
+func exchangeTestTicket(t *testing.T, app *fiber.App, ticketCode string) (int, string) {
+	return exchangeTestTicketWithInitData(t, app, ticketCode, "")
+}
+
+func exchangeTestTicketWithInitData(t *testing.T, app *fiber.App, ticketCode string, initData string) (int, string) {
+	request := httptest.NewRequest("POST", "/tickets/"+ticketCode+"/token", strings.NewReader("{}"))
+	if initData != "" {
+		request.Header.Set(fiber.HeaderAuthorization, INIT_DATA_AUTH_SCHEME+initData)
+	}
+
+	status, result := sendTestTokenRequest(t, app, request)
+	return status, result.Token
+}
+
+func exchangeTestTicketForTokens(t *testing.T, app *fiber.App, ticketCode string) (int, testTokens) {
+	request := httptest.NewRequest("POST", "/tickets/"+ticketCode+"/token", strings.NewReader("{}"))
+	return sendTestTokenRequest(t, app, request)
+}
+
+func refreshTestToken(t *testing.T, app *fiber.App, refreshToken string) (int, testTokens) {
+	request := httptest.NewRequest("POST", "/tokens/refresh", strings.NewReader(`{"refresh_token":"`+refreshToken+`"}`))
+	return sendTestTokenRequest(t, app, request)
+}
+
+func TestTicketActivation(t *testing.T) {
+	app, tokenProvider, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{VenueID: 7, Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+
+	status, tokens := exchangeTestTicketForTokens(t, app, ticketCode)
+	if status != http.StatusCreated {
+		t.Fatalf("unexpected status %d", status)
+	}
+
+	claims, valid, _ := tokenProvider.Verify(tokens.Token)
+	if !valid {
+		t.Fatalf("issued token is not valid")
+	}
+
+	// Single day ticket expires at the next midnight, access token expires earlier if the midnight is far
+	now := time.Now().UTC()
+	expires := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	if claims.ExpiresAt.After(expires) || claims.ExpiresAt.After(now.Add(ACCESS_TOKEN_LIFETIME)) {
+		t.Fatalf("expected token to expire before %v, got %v", expires, claims.ExpiresAt)
+	}
+
+	refreshToken, _ := repo.GetRefreshToken(hashRefreshToken(tokens.RefreshToken))
+	if refreshToken == nil || !refreshToken.ExpiresAt.Equal(expires) {
+		t.Fatalf("expected refresh token to expire at %v, got %+v", expires, refreshToken)
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode)
+	if claims.TicketID != ticket.ID || claims.ID == "" || claims.IssuedAt.IsZero() || claims.Audience != auth.API_AUDIENCE {
+		t.Fatalf("unexpected token claims %+v", claims)
+	}
+
+	if !claims.Scopes.HasVenue(7) || len(claims.Scopes.VenueIDs) != 1 || len(claims.Scopes.TourIDs) != 0 {
+		t.Fatalf("expected token to be scoped to ticket venue, got %+v", claims.Scopes)
+	}
+
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusForbidden {
+		t.Fatalf("expected second activation to be forbidden, got %d", status)
+	}
+
+	if status, _ := exchangeTestTicket(t, app, uuid.NewString()); status != http.StatusNotFound {
+		t.Fatalf("expected unknown ticket to be not found, got %d", status)
+	}
+
+	if status, _ := exchangeTestTicket(t, app, "not-a-ticket"); status != http.StatusBadRequest {
+		t.Fatalf("expected malformed ticket to be rejected, got %d", status)
+	}
+}
+
+func TestTicketMultipleActivations(t *testing.T) {
+	app, _, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 3, ValidityDays: 3, TimeZone: "Europe/Minsk"})
+
+	location, _ := time.LoadLocation("Europe/Minsk")
+	now := time.Now().In(location)
+	expires := time.Date(now.Year(), now.Month(), now.Day()+3, 0, 0, 0, 0, location)
+
+	for i := 0; i < 3; i++ {
+		status, tokens := exchangeTestTicketForTokens(t, app, ticketCode)
+		if status != http.StatusCreated {
+			t.Fatalf("activation %d: unexpected status %d", i, status)
+		}
+
+		// All activations share the validity window of the first one
+		refreshToken, _ := repo.GetRefreshToken(hashRefreshToken(tokens.RefreshToken))
+		if !refreshToken.ExpiresAt.Equal(expires) {
+			t.Fatalf("activation %d: expected refresh token to expire at %v, got %v", i, expires, refreshToken.ExpiresAt)
+		}
+	}
+
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusForbidden {
+		t.Fatalf("expected activation over the limit to be forbidden, got %d", status)
+	}
+}
+
+func TestTicketExpired(t *testing.T) {
+	app, _, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 2, ValidityDays: 1, TimeZone: "UTC"})
+	repo.ActivateTicket(ticketCode, time.Now().Add(-48*time.Hour), 0)
+
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusForbidden {
+		t.Fatalf("expected expired ticket to be forbidden, got %d", status)
+	}
+}
+
+func TestTicketInitData(t *testing.T) {
+	app, tokenProvider, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+	initData := createTestInitData(TEST_USER_ID, time.Now(), TEST_BOT_TOKEN)
+
+	status, token := exchangeTestTicketWithInitData(t, app, ticketCode, initData)
+	if status != http.StatusCreated {
+		t.Fatalf("unexpected status %d", status)
+	}
+
+	claims, _, _ := tokenProvider.Verify(token)
+	if claims.UserID != TEST_USER_ID {
+		t.Fatalf("expected token to be bound to the user, got %d", claims.UserID)
+	}
+
+	// Holder gets another token, e.g. on another device, without another activation
+	if status, _ := exchangeTestTicketWithInitData(t, app, ticketCode, initData); status != http.StatusCreated {
+		t.Fatalf("expected token to be re-issued to ticket holder, got %d", status)
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode)
+	if ticket.Activations != 1 {
+		t.Fatalf("expected single activation, got %d", ticket.Activations)
+	}
+
+	otherInitData := createTestInitData(TEST_USER_ID+1, time.Now(), TEST_BOT_TOKEN)
+	if status, _ := exchangeTestTicketWithInitData(t, app, ticketCode, otherInitData); status != http.StatusForbidden {
+		t.Fatalf("expected another user to be forbidden, got %d", status)
+	}
+
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusUnauthorized {
+		t.Fatalf("expected exchange without init data to be unauthorized, got %d", status)
+	}
+}
+
+func TestTicketInitDataAnonymousExchange(t *testing.T) {
+	app, _, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 3, ValidityDays: 1, TimeZone: "UTC"})
+
+	// Tickets exchanged without init data stay anonymous until a Telegram user binds them
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusCreated {
+		t.Fatalf("unexpected anonymous exchange status %d", status)
+	}
+
+	initData := createTestInitData(TEST_USER_ID, time.Now(), TEST_BOT_TOKEN)
+	if status, _ := exchangeTestTicketWithInitData(t, app, ticketCode, initData); status != http.StatusCreated {
+		t.Fatalf("unexpected exchange status %d", status)
+	}
+
+	// Activations left can't be used to bypass the binding
+	if status, _ := exchangeTestTicket(t, app, ticketCode); status != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous exchange of bound ticket to be unauthorized, got %d", status)
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode)
+	if ticket.Activations != 2 {
+		t.Fatalf("expected 2 activations, got %d", ticket.Activations)
+	}
+}
+
+func TestTicketInitDataValidation(t *testing.T) {
+	app, _, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+
+	cases := map[string]int{
+		createTestInitData(TEST_USER_ID, time.Now(), "654321:other-token"):                  http.StatusUnauthorized,
+		createTestInitData(TEST_USER_ID, time.Now().Add(-48*time.Hour), TEST_BOT_TOKEN):     http.StatusUnauthorized,
+		createTestInitData(TEST_USER_ID, time.Now(), TEST_BOT_TOKEN) + "&query_id=tampered": http.StatusUnauthorized,
+		"auth_date=1&user=%7B%7D": http.StatusUnauthorized,
+	}
+
+	for initData, expected := range cases {
+		if status, _ := exchangeTestTicketWithInitData(t, app, ticketCode, initData); status != expected {
+			t.Fatalf("%s: expected status %d, got %d", initData, expected, status)
+		}
+	}
+
+	ticket, _ := repo.GetTicket(ticketCode)
+	if ticket.Activations != 0 {
+		t.Fatalf("ticket was activated with invalid init data")
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	app, tokenProvider, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{VenueID: 7, Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+
+	_, tokens := exchangeTestTicketForTokens(t, app, ticketCode)
+	status, refreshed := refreshTestToken(t, app, tokens.RefreshToken)
+	if status != http.StatusCreated || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatalf("expected refresh token to be rotated, got %d: %+v", status, refreshed)
+	}
+
+	claims, valid, _ := tokenProvider.Verify(refreshed.Token)
+	ticket, _ := repo.GetTicket(ticketCode)
+	if !valid || claims.TicketID != ticket.ID || !claims.Scopes.HasVenue(7) {
+		t.Fatalf("unexpected refreshed token claims %+v", claims)
+	}
+
+	// Refresh doesn't count as an activation
+	if ticket.Activations != 1 {
+		t.Fatalf("expected single activation, got %d", ticket.Activations)
+	}
+
+	status, next := refreshTestToken(t, app, refreshed.RefreshToken)
+	if status != http.StatusCreated {
+		t.Fatalf("expected rotated refresh token to be valid, got %d", status)
+	}
+
+	// Reuse right after the exchange is a concurrent refresh on another device, the family stays valid
+	if status, _ := refreshTestToken(t, app, tokens.RefreshToken); status != http.StatusConflict {
+		t.Fatalf("expected just used refresh token to conflict, got %d", status)
+	}
+
+	// Reuse of a token exchanged before the grace period revokes the whole family
+	current, _ := repo.GetRefreshToken(hashRefreshToken(next.RefreshToken))
+	repo.CreateRefreshToken(hashRefreshToken("stale"), repository.RefreshToken{
+		FamilyID: current.FamilyID, TicketID: ticket.ID, ExpiresAt: time.Now().Add(time.Hour),
+	})
+	repo.UseRefreshToken(hashRefreshToken("stale"), time.Now().Add(-2*REFRESH_TOKEN_REUSE_GRACE))
+	if status, _ := refreshTestToken(t, app, "stale"); status != http.StatusUnauthorized {
+		t.Fatalf("expected reused refresh token to be rejected, got %d", status)
+	}
+
+	if status, _ := refreshTestToken(t, app, next.RefreshToken); status != http.StatusUnauthorized {
+		t.Fatalf("expected refresh token family to be revoked, got %d", status)
+	}
+
+	if status, _ := refreshTestToken(t, app, "unknown"); status != http.StatusUnauthorized {
+		t.Fatalf("expected unknown refresh token to be rejected, got %d", status)
+	}
+
+	if status, _ := refreshTestToken(t, app, ""); status != http.StatusBadRequest {
+		t.Fatalf("expected empty refresh token to be rejected, got %d", status)
+	}
+}
+
+func TestRefreshTokenValidity(t *testing.T) {
+	app, tokenProvider, repo := createTestTicketsApp()
+	ticketCode := createTestTicket(repo, repository.TicketType{Activations: 1, ValidityDays: 1, TimeZone: "UTC"})
+	initData := createTestInitData(TEST_USER_ID, time.Now(), TEST_BOT_TOKEN)
+	exchangeTestTicketWithInitData(t, app, ticketCode, initData)
+	ticket, _ := repo.GetTicket(ticketCode)
+
+	repo.CreateRefreshToken(hashRefreshToken("active"), repository.RefreshToken{
+		FamilyID: "active", TicketID: ticket.ID, UserID: TEST_USER_ID, ExpiresAt: time.Now().Add(time.Hour),
+	})
+	repo.CreateRefreshToken(hashRefreshToken("expired"), repository.RefreshToken{
+		FamilyID: "expired", TicketID: ticket.ID, ExpiresAt: time.Now().Add(-time.Hour),
+	})
+
+	if status, _ := refreshTestToken(t, app, "expired"); status != http.StatusUnauthorized {
+		t.Fatalf("expected expired refresh token to be rejected, got %d", status)
+	}
+
+	// Access token never outlives the refresh token
+	status, tokens := refreshTestToken(t, app, "active")
+	claims, _, _ := tokenProvider.Verify(tokens.Token)
+	if status != http.StatusCreated || claims.UserID != TEST_USER_ID || claims.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("unexpected refreshed token %d: %+v", status, claims)
+	}
+
+	repo.RevokeTicket(ticketCode, time.Now())
+	if status, _ := refreshTestToken(t, app, tokens.RefreshToken); status != http.StatusForbidden {
+		t.Fatalf("expected refresh of revoked ticket to be forbidden, got %d", status)
+	}
+}
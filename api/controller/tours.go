@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+type ToursController struct {
+	TokenProvider  auth.TokenProvider
+	TourRepository repository.TourRepository
+}
+
+func (controller *ToursController) GetRoutes() []Route {
+	return []Route{
+		{
+			Method:  "GET",
+			Path:    "/tours",
+			Handler: controller.HandleGetTours,
+		},
+		{
+			Method:  "GET",
+			Path:    "/tours/:id",
+			Handler: controller.HandleGetTour,
+		},
+	}
+}
+
+func (controller *ToursController) HandleGetTours(c *fiber.Ctx) error {
+	authHeader := c.Get("Authorization")
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
+	if !tokenValid {
+		return err
+	}
+
+	language := c.Query("language")
+	fallback := translation.DEFAULT_LANGUAGE.String()
+	// Tours scoped separately are listed together with tours of the scoped venues
+	tours, err := controller.TourRepository.GetTours(claims.Scopes.VenueIDs, claims.Scopes.TourIDs, language, fallback)
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get tours", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get tours")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, tours)
+}
+
+func (controller *ToursController) HandleGetTour(c *fiber.Ctx) error {
+	authHeader := c.Get("Authorization")
+	claims, tokenValid, err := verifyToken(c, controller.TokenProvider, authHeader)
+	if !tokenValid {
+		return err
+	}
+
+	tourID, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		HandlerPrintf(c, LOG_WARNING, "Failed to parse tour id", "error", err)
+		return HandlerSendFailure(c, fiber.StatusBadRequest, "Failed to parse tour id")
+	}
+
+	language := c.Query("language")
+	tour, err := controller.TourRepository.GetTour(tourID, language, translation.DEFAULT_LANGUAGE.String())
+	if err != nil {
+		HandlerPrintf(c, LOG_ERROR, "Failed to get tour", "error", err)
+		return HandlerSendError(c, fiber.StatusInternalServerError, "Failed to get tour")
+	}
+
+	if tour == nil || !(claims.Scopes.HasVenue(tour.VenueID) || claims.Scopes.HasTour(tour.ID)) {
+		HandlerPrintf(c, LOG_WARNING, "Tour not found")
+		return HandlerSendFailure(c, fiber.StatusNotFound, "Tour not found")
+	}
+
+	return HandlerSendSuccess(c, fiber.StatusOK, tour)
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
+	"github.com/st-matskevich/audio-guide-bot/api/repository"
+)
+
+func createTestToursApp(t *testing.T) (*fiber.App, string, int64, int64) {
+	tokenProvider := auth.CreateMemoryTokenProvider(nil)
+	repo := repository.CreateMemoryRepository()
+
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	firstID, _ := repo.CreateObject("first", venueID)
+	repo.SetObjectCovers(firstID, []repository.Cover{{Index: 0, Path: "first/0.jpg"}, {Index: 1, Path: "first/1.jpg"}})
+	repo.SetObjectTranslation(firstID, repository.ObjectTranslation{Language: "en", Title: "First", AudioPath: "first/en.mp3"})
+	repo.SetObjectTranslation(firstID, repository.ObjectTranslation{Language: "be", Title: "Першы", AudioPath: "first/be.mp3"})
+	secondID, _ := repo.CreateObject("second", venueID)
+	repo.SetObjectTranslation(secondID, repository.ObjectTranslation{Language: "en", Title: "Second", AudioPath: "second/en.mp3"})
+
+	tourID := repo.AddTour(venueID, map[string]repository.TourTranslation{
+		"en": {Title: "Highlights", Description: "Best of the museum"},
+	}, []int64{secondID, firstID})
+
+	otherVenueID := repo.AddVenue(repository.Venue{Code: "gallery", Name: "Gallery"})
+	otherTourID := repo.AddTour(otherVenueID, map[string]repository.TourTranslation{
+		"en": {Title: "Gallery", Description: "Another venue"},
+	}, []int64{})
+
+	token := createTestToken(t, tokenProvider, auth.TokenScopes{VenueIDs: []int64{venueID}})
+
+	app := createTestApp(&ToursController{
+		TokenProvider:  tokenProvider,
+		TourRepository: repo,
+	})
+
+	return app, token, tourID, otherTourID
+}
+
+func TestGetTours(t *testing.T) {
+	app, token, tourID, _ := createTestToursApp(t)
+
+	request := httptest.NewRequest("GET", "/tours?language=be", nil)
+	request.Header.Set("Authorization", token)
+	response, body := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", response.StatusCode, body)
+	}
+
+	tours := []repository.Tour{}
+	parseTestResponse(t, body, &tours)
+	if len(tours) != 1 || tours[0].ID != tourID || tours[0].Title != "Highlights" {
+		t.Fatalf("expected only tours of token venue, got %+v", tours)
+	}
+
+	stops := tours[0].Stops
+	if len(stops) != 2 || stops[0].Code != "second" || stops[1].Code != "first" {
+		t.Fatalf("expected stops in route order, got %+v", stops)
+	}
+
+	if stops[0].Title != "Second" || stops[1].Title != "Першы" {
+		t.Fatalf("expected stop titles with language fallback, got %+v", stops)
+	}
+
+	if len(stops[1].Covers) != 2 || stops[1].Covers[1].Index != 1 {
+		t.Fatalf("expected stop cover indexes, got %+v", stops[1].Covers)
+	}
+}
+
+func TestGetTour(t *testing.T) {
+	app, token, tourID, otherTourID := createTestToursApp(t)
+
+	cases := map[string]int{
+		strconv.FormatInt(tourID, 10):      http.StatusOK,
+		strconv.FormatInt(otherTourID, 10): http.StatusNotFound,
+		"100":                              http.StatusNotFound,
+		"invalid":                          http.StatusBadRequest,
+	}
+
+	for id, status := range cases {
+		request := httptest.NewRequest("GET", "/tours/"+id, nil)
+		request.Header.Set("Authorization", token)
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != status {
+			t.Fatalf("tour %s: expected status %d, got %d: %s", id, status, response.StatusCode, body)
+		}
+	}
+
+	request := httptest.NewRequest("GET", "/tours/"+strconv.FormatInt(tourID, 10), nil)
+	request.Header.Set("Authorization", "invalid")
+	response, _ := sendTestRequest(t, app, request)
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", response.StatusCode)
+	}
+}
+
+func TestTourScopedToken(t *testing.T) {
+	tokenProvider := auth.CreateMemoryTokenProvider(nil)
+	repo := repository.CreateMemoryRepository()
+
+	venueID := repo.AddVenue(repository.Venue{Code: "museum", Name: "Museum"})
+	stopID, _ := repo.CreateObject("stop", venueID)
+	repo.SetObjectTranslation(stopID, repository.ObjectTranslation{Language: "en", Title: "Stop", AudioPath: "stop/en.mp3"})
+	otherID, _ := repo.CreateObject("other", venueID)
+	repo.SetObjectTranslation(otherID, repository.ObjectTranslation{Language: "en", Title: "Other", AudioPath: "other/en.mp3"})
+
+	tourID := repo.AddTour(venueID, map[string]repository.TourTranslation{"en": {Title: "Highlights"}}, []int64{stopID})
+	otherTourID := repo.AddTour(venueID, map[string]repository.TourTranslation{"en": {Title: "Other"}}, []int64{otherID})
+
+	app := createTestApp(
+		&ToursController{TokenProvider: tokenProvider, TourRepository: repo},
+		&ObjectsController{TokenProvider: tokenProvider, ObjectRepository: repo, TourRepository: repo},
+	)
+	token := createTestToken(t, tokenProvider, auth.TokenScopes{TourIDs: []int64{tourID}})
+
+	request := httptest.NewRequest("GET", "/tours", nil)
+	request.Header.Set("Authorization", token)
+	response, body := sendTestRequest(t, app, request)
+	tours := []repository.Tour{}
+	parseTestResponse(t, body, &tours)
+	if response.StatusCode != http.StatusOK || len(tours) != 1 || tours[0].ID != tourID {
+		t.Fatalf("expected only the token tour, got %d: %+v", response.StatusCode, tours)
+	}
+
+	// Tour scope unlocks only the tour and its stops
+	cases := map[string]int{
+		"/tours/" + strconv.FormatInt(tourID, 10):      http.StatusOK,
+		"/tours/" + strconv.FormatInt(otherTourID, 10): http.StatusNotFound,
+		"/objects/stop":  http.StatusOK,
+		"/objects/other": http.StatusNotFound,
+		"/objects":       http.StatusForbidden,
+	}
+
+	for path, status := range cases {
+		request := httptest.NewRequest("GET", path, nil)
+		request.Header.Set("Authorization", token)
+		response, body := sendTestRequest(t, app, request)
+		if response.StatusCode != status {
+			t.Fatalf("%s: expected status %d, got %d: %s", path, status, response.StatusCode, body)
+		}
+	}
+}
+
+func TestMultipleVenuesToken(t *testing.T) {
+	tokenProvider := auth.CreateMemoryTokenProvider(nil)
+	repo := repository.CreateMemoryRepository()
+
+	venueIDs := []int64{}
+	for _, code := range []string{"museum", "gallery"} {
+		venueID := repo.AddVenue(repository.Venue{Code: code, Name: code})
+		objectID, _ := repo.CreateObject(code, venueID)
+		repo.SetObjectTranslation(objectID, repository.ObjectTranslation{Language: "en", Title: code, AudioPath: code + "/en.mp3"})
+		repo.AddTour(venueID, map[string]repository.TourTranslation{"en": {Title: code}}, []int64{objectID})
+		venueIDs = append(venueIDs, venueID)
+	}
+
+	app := createTestApp(
+		&ToursController{TokenProvider: tokenProvider, TourRepository: repo},
+		&ObjectsController{TokenProvider: tokenProvider, ObjectRepository: repo, TourRepository: repo},
+	)
+	token := createTestToken(t, tokenProvider, auth.TokenScopes{VenueIDs: venueIDs})
+
+	request := httptest.NewRequest("GET", "/tours", nil)
+	request.Header.Set("Authorization", token)
+	_, body := sendTestRequest(t, app, request)
+	tours := []repository.Tour{}
+	parseTestResponse(t, body, &tours)
+	if len(tours) != 2 {
+		t.Fatalf("expected tours of both venues, got %+v", tours)
+	}
+
+	request = httptest.NewRequest("GET", "/objects", nil)
+	request.Header.Set("Authorization", token)
+	_, body = sendTestRequest(t, app, request)
+	page := ObjectsPage{}
+	parseTestResponse(t, body, &page)
+	if len(page.Objects) != 2 {
+		t.Fatalf("expected objects of both venues, got %+v", page.Objects)
+	}
+}
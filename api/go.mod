@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/PaulSonOfLars/gotgbot/v2 v2.0.0-rc.22
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/gofiber/fiber/v2 v2.50.0
 	github.com/golang-jwt/jwt/v5 v5.0.0
 	github.com/golang-migrate/migrate/v4 v4.16.2
@@ -11,7 +12,9 @@ require (
 	github.com/lib/pq v1.10.9
 	github.com/minio/minio-go/v7 v7.0.63
 	github.com/nicksnyder/go-i18n/v2 v2.2.2
-	golang.org/x/text v0.14.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	golang.org/x/image v0.18.0
+	golang.org/x/text v0.16.0
 )
 
 require (
@@ -1,20 +1,29 @@
 package main
 
 import (
+	"archive/zip"
 	"log/slog"
 	"os"
+	"strconv"
+	"time"
+	// Embed time zones database, since the service runs in a scratch container
+	_ "time/tzdata"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/st-matskevich/audio-guide-bot/api/catalog"
 	"github.com/st-matskevich/audio-guide-bot/api/controller"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/auth"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/blob"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/bot"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/db"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/media"
 	"github.com/st-matskevich/audio-guide-bot/api/provider/translation"
 	"github.com/st-matskevich/audio-guide-bot/api/repository"
 )
 
+const BODY_LIMIT = 64 * 1024 * 1024
+
 func main() {
 	opts := slog.HandlerOptions{
 		ReplaceAttr: controller.CreateGCPLoggerAdapter(),
@@ -47,7 +56,62 @@ func main() {
 		os.Exit(0)
 	}
 
-	app := fiber.New()
+	// S3_CONNECTION_STRING is read for deployments configured before other blob storages were supported
+	blobURL := os.Getenv("BLOB_CONNECTION_STRING")
+	if blobURL == "" {
+		blobURL = os.Getenv("S3_CONNECTION_STRING")
+	}
+	blobProvider, err := blob.CreateBlobProvider(blobURL)
+	if err != nil {
+		slog.Error("Blob provider initialization error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Blob provider initialized")
+
+	mediaProvider, err := media.CreateNativeMediaProvider()
+	if err != nil {
+		slog.Error("Media provider initialization error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Media provider initialized")
+
+	repository := repository.Repository{DBProvider: dbProvider}
+
+	// Import or export the catalog if --import or --export is passed
+	if len(args) > 1 && (args[0] == "--import" || args[0] == "--export") {
+		catalog := catalog.Catalog{
+			BlobProvider:     blobProvider,
+			MediaProvider:    mediaProvider,
+			ObjectRepository: &repository,
+			VenueRepository:  &repository,
+		}
+
+		if args[0] == "--import" {
+			slog.Info("Running in catalog import mode", "path", args[1])
+			count, err := importCatalog(&catalog, args[1])
+			if err != nil {
+				slog.Error("Catalog import failed", "error", err, "imported", count)
+				os.Exit(1)
+			}
+
+			slog.Info("Successfully imported catalog", "objects", count)
+		} else {
+			slog.Info("Running in catalog export mode", "path", args[1])
+			count, err := exportCatalog(&catalog, args[1])
+			if err != nil {
+				slog.Error("Catalog export failed", "error", err)
+				os.Exit(1)
+			}
+
+			slog.Info("Successfully exported catalog", "objects", count)
+		}
+		os.Exit(0)
+	}
+
+	app := fiber.New(fiber.Config{
+		// Admin API accepts audio uploads, that are larger than the default limit
+		BodyLimit: BODY_LIMIT,
+	})
 
 	// Setup CORS if CORS_ALLOWED_ORIGINS is provided
 	corsOrigins := os.Getenv("CORS_ALLOWED_ORIGINS")
@@ -69,22 +133,44 @@ func main() {
 	}
 	slog.Info("Telegram API initialized")
 
-	s3URL := os.Getenv("S3_CONNECTION_STRING")
-	blobProvider, err := blob.CreateS3BlobProvider(s3URL)
+	jwtKeys, err := loadJWTKeys(os.Getenv("JWT_SECRET"), os.Getenv("JWT_KEYS_PATH"))
 	if err != nil {
-		slog.Error("S3 blob provider initialization error", "error", err)
+		slog.Error("JWT keys loading error", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("S3 blob provider initialized")
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	tokenProvier, err := auth.CreateJWTTokenProvider(jwtSecret)
+	tokenProvier, err := auth.CreateJWTTokenProvider(jwtKeys, os.Getenv("JWT_SIGNING_KEY_ID"), &repository)
 	if err != nil {
 		slog.Error("JWT token provider initialization error", "error", err)
 		os.Exit(1)
 	}
+
+	// Tokens issued before venues were introduced unlock the venue existing objects were assigned to
+	defaultVenue, err := repository.GetDefaultVenue()
+	if err != nil {
+		slog.Error("Default venue loading error", "error", err)
+		os.Exit(1)
+	}
+	if defaultVenue != nil {
+		tokenProvier.LegacyVenueID = defaultVenue.ID
+	}
+
+	if value := os.Getenv("JWT_LEGACY_TOKENS_UNTIL"); value != "" {
+		tokenProvier.LegacyTokensUntil, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			slog.Error("JWT legacy tokens cutover parsing error", "error", err)
+			os.Exit(1)
+		}
+	}
 	slog.Info("JWT token provider initialized")
 
+	initDataValidator, err := auth.CreateTelegramInitDataValidator(botToken, auth.DEFAULT_INIT_DATA_MAX_AGE)
+	if err != nil {
+		slog.Error("Init data validator initialization error", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("Init data validator initialized")
+
 	translationProvier, err := translation.CreateI18NTranslationProvider()
 	if err != nil {
 		slog.Error("Translation provider initialization error", "error", err)
@@ -92,25 +178,78 @@ func main() {
 	}
 	slog.Info("Translation provider initialized")
 
-	webAppURL := os.Getenv("TELEGRAM_WEB_APP_URL")
-	repository := repository.Repository{DBProvider: dbProvider}
+	supportChatID := int64(0)
+	if value := os.Getenv("TELEGRAM_SUPPORT_CHAT_ID"); value != "" {
+		supportChatID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			slog.Error("Support chat id parsing error", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	botController := &controller.BotController{
+		WebAppURL:            os.Getenv("TELEGRAM_WEB_APP_URL"),
+		BotProvider:          botProvider,
+		TranslationProvider:  translationProvier,
+		TicketRepository:     &repository,
+		TicketTypeRepository: &repository,
+		VenueRepository:      &repository,
+		ObjectRepository:     &repository,
+		TourRepository:       &repository,
+		UserRepository:       &repository,
+		ConfigRepository:     &repository,
+		SupportChatID:        supportChatID,
+	}
+
+	// Commands are only shown in the bot menu, so the bot keeps working without them
+	if err := botController.RegisterCommands(); err != nil {
+		slog.Warn("Bot commands registration error", "error", err)
+	} else {
+		slog.Info("Bot commands registered")
+	}
+
+	miniAppURL := os.Getenv("TELEGRAM_MINI_APP_URL")
+	adminToken := os.Getenv("ADMIN_TOKEN")
 	controllers := []controller.Controller{
-		&controller.BotController{
-			WebAppURL:           webAppURL,
-			BotProvider:         botProvider,
-			TranslationProvider: translationProvier,
-			TicketRepository:    &repository,
-			ConfigRepository:    &repository,
-		},
+		botController,
 		&controller.TicketsController{
-			TokenProvider:    tokenProvier,
-			TicketRepository: &repository,
+			TokenProvider:          tokenProvier,
+			InitDataValidator:      initDataValidator,
+			TicketRepository:       &repository,
+			RefreshTokenRepository: &repository,
 		},
 		&controller.ObjectsController{
 			TokenProvider:    tokenProvier,
 			BlobProvider:     blobProvider,
 			ObjectRepository: &repository,
+			TourRepository:   &repository,
 		},
+		&controller.ToursController{
+			TokenProvider:  tokenProvier,
+			TourRepository: &repository,
+		},
+		&controller.KeysController{
+			KeySetProvider: tokenProvier,
+		},
+		&controller.AdminController{
+			AdminToken:       adminToken,
+			BlobProvider:     blobProvider,
+			ObjectRepository: &repository,
+			VenueRepository:  &repository,
+			MediaProvider:    mediaProvider,
+			TourRepository:   &repository,
+			TicketRepository: &repository,
+			BotProvider:      botProvider,
+			MiniAppURL:       miniAppURL,
+		},
+	}
+
+	// Providers without own HTTP endpoint serve signed blob URLs through the API
+	if urlVerifier, ok := blobProvider.(blob.BlobURLVerifier); ok {
+		controllers = append(controllers, &controller.BlobsController{
+			BlobProvider: blobProvider,
+			URLVerifier:  urlVerifier,
+		})
 	}
 
 	for _, controller := range controllers {
@@ -127,3 +266,61 @@ func main() {
 	err = app.Listen(":" + port)
 	slog.Error("API HTTP server exited", "error", err)
 }
+
+// Imports either a ZIP archive or a directory with the same layout
+func importCatalog(catalog *catalog.Catalog, path string) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if info.IsDir() {
+		return catalog.Import(os.DirFS(path))
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return 0, err
+	}
+	defer archive.Close()
+
+	return catalog.Import(archive)
+}
+
+func exportCatalog(catalog *catalog.Catalog, path string) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := catalog.Export(file)
+	if err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	return count, file.Close()
+}
+
+// JWT_SECRET is a key without ID, so tokens signed with it before key rotation was introduced stay valid,
+// keys from JWT_KEYS_PATH directory are added to it
+func loadJWTKeys(secret string, path string) ([]auth.JWTKey, error) {
+	keys := []auth.JWTKey{}
+	if secret != "" {
+		key, err := auth.CreateHMACKey("", secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if path != "" {
+		loaded, err := auth.LoadJWTKeys(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, loaded...)
+	}
+
+	return keys, nil
+}
@@ -1,12 +1,100 @@
 package auth
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Audience of tokens that grant access to the API
+const API_AUDIENCE = "audio-guide-api"
+
+// Venues and tours the token unlocks, venue scope includes all tours and objects of the venue
+type TokenScopes struct {
+	VenueIDs []int64 `json:"venues,omitempty"`
+	TourIDs  []int64 `json:"tours,omitempty"`
+}
+
+func (scopes TokenScopes) HasVenue(venueID int64) bool {
+	return slices.Contains(scopes.VenueIDs, venueID)
+}
+
+func (scopes TokenScopes) HasTour(tourID int64) bool {
+	return slices.Contains(scopes.TourIDs, tourID)
+}
 
 type TokenClaims struct {
+	// Unique token ID
+	ID string
+	// Ticket the token was minted from, zero for tokens issued before tickets could be revoked
+	TicketID  int64
+	IssuedAt  time.Time
 	ExpiresAt time.Time
+	Audience  string
+	Scopes    TokenScopes
+	// Telegram user that holds the token, zero if unknown
+	UserID int64
 }
 
 type TokenProvider interface {
 	Create(claims TokenClaims) (string, error)
+	// Only tokens issued for API_AUDIENCE are valid
 	Verify(token string) (TokenClaims, bool, error)
 }
+
+// Source of revoked tickets, tokens minted from them are rejected by TokenProvider.Verify
+type RevocationList interface {
+	IsTicketRevoked(ticketID int64) (bool, error)
+}
+
+// Returned by TokenProvider.Verify if the revocation list can't be read, the token itself may be valid
+var ErrRevocationCheck = errors.New("failed to check token revocation")
+
+// Returns false if the token was minted from a revoked ticket
+func checkRevocation(revocationList RevocationList, claims TokenClaims) (bool, error) {
+	if revocationList == nil || claims.TicketID == 0 {
+		return true, nil
+	}
+
+	revoked, err := revocationList.IsTicketRevoked(claims.TicketID)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrRevocationCheck, err)
+	}
+
+	return !revoked, nil
+}
+
+// Telegram user of validated Mini App init data
+type TelegramUser struct {
+	ID           int64  `json:"id"`
+	LanguageCode string `json:"language_code"`
+}
+
+type InitDataValidator interface {
+	// Returns false if init data signature is invalid or it's too old
+	Validate(initData string) (TelegramUser, bool, error)
+}
+
+// Public key in JSON Web Key format, https://datatracker.ietf.org/doc/html/rfc7517
+type PublicKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA modulus and exponent
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Curve and public key of EdDSA keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type KeySet struct {
+	Keys []PublicKey `json:"keys"`
+}
+
+// Publishes public keys, so other services can verify tokens on their own
+type KeySetProvider interface {
+	GetKeySet() (KeySet, error)
+}
@@ -2,35 +2,82 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var JWT_SIGN_METHOD = jwt.SigningMethodHS256
+// Tokens issued before audience was introduced expired at the next UTC midnight, so they live at most a day
+const LEGACY_TOKEN_LIFETIME = 24 * time.Hour
 
+type JWTClaims struct {
+	jwt.RegisteredClaims
+	Scopes TokenScopes `json:"scopes"`
+	UserID int64       `json:"telegram_user_id,omitempty"`
+	// Venue of tokens issued before scopes were introduced, replaced with a venue scope on verification
+	VenueID int64 `json:"venue_id,omitempty"`
+}
+
+// Tokens are signed with the signing key and verified with any key matching their kid header,
+// so keys can be rotated by adding a new signing key and keeping the old one until its tokens expire
 type JWTTokenProvider struct {
-	JWTSecret []byte
+	Keys           map[string]JWTKey
+	SigningKeyID   string
+	RevocationList RevocationList
+	// Venue of tokens issued before venues were introduced, they have neither scopes nor venue claim
+	LegacyVenueID int64
+	// Tokens issued before audience was introduced have none, they are accepted only if they expire before this time
+	LegacyTokensUntil time.Time
 }
 
 func (provider *JWTTokenProvider) Create(claims TokenClaims) (string, error) {
-	jwtClaims := jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(claims.ExpiresAt),
+	jwtClaims := JWTClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        claims.ID,
+			IssuedAt:  jwt.NewNumericDate(claims.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(claims.ExpiresAt),
+		},
+		Scopes: claims.Scopes,
+		UserID: claims.UserID,
+	}
+
+	if claims.TicketID != 0 {
+		jwtClaims.Subject = strconv.FormatInt(claims.TicketID, 10)
+	}
+
+	if claims.Audience != "" {
+		jwtClaims.Audience = jwt.ClaimStrings{claims.Audience}
+	}
+
+	key := provider.Keys[provider.SigningKeyID]
+	token := jwt.NewWithClaims(key.Method, jwtClaims)
+	// Tokens signed with the key without ID are verified with it as before key rotation was introduced
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
 	}
 
-	token := jwt.NewWithClaims(JWT_SIGN_METHOD, jwtClaims)
-	tokenString, err := token.SignedString(provider.JWTSecret)
+	tokenString, err := token.SignedString(key.SignKey)
 
 	return tokenString, err
 }
 
 func (provider *JWTTokenProvider) Verify(token string) (TokenClaims, bool, error) {
-	jwtClaims := jwt.RegisteredClaims{}
+	jwtClaims := JWTClaims{}
 	jwtToken, err := jwt.ParseWithClaims(token, &jwtClaims, func(token *jwt.Token) (any, error) {
-		if token.Method != JWT_SIGN_METHOD {
+		keyID, _ := token.Header["kid"].(string)
+		key, found := provider.Keys[keyID]
+		if !found {
+			return nil, fmt.Errorf("unknown key %q", keyID)
+		}
+
+		if token.Method != key.Method {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(provider.JWTSecret), nil
+		return key.VerifyKey, nil
 	})
 
 	if err != nil {
@@ -41,24 +88,103 @@ func (provider *JWTTokenProvider) Verify(token string) (TokenClaims, bool, error
 		return TokenClaims{}, false, nil
 	}
 
-	if jwtClaims.ExpiresAt.Time.Before(time.Now()) {
+	// Every issued token expires, tokens without expiration are never valid
+	if jwtClaims.ExpiresAt == nil || jwtClaims.ExpiresAt.Time.Before(time.Now()) {
+		return TokenClaims{}, false, nil
+	}
+
+	// Tokens issued before audience was introduced have none, newer tokens without it can't be legacy ones
+	if len(jwtClaims.Audience) == 0 {
+		if !jwtClaims.ExpiresAt.Time.Before(provider.LegacyTokensUntil) {
+			return TokenClaims{}, false, nil
+		}
+	} else if !slices.Contains(jwtClaims.Audience, API_AUDIENCE) {
 		return TokenClaims{}, false, nil
 	}
 
 	result := TokenClaims{
+		ID:        jwtClaims.ID,
 		ExpiresAt: jwtClaims.ExpiresAt.Time,
+		Audience:  API_AUDIENCE,
+		Scopes:    jwtClaims.Scopes,
+		UserID:    jwtClaims.UserID,
+	}
+
+	if jwtClaims.IssuedAt != nil {
+		result.IssuedAt = jwtClaims.IssuedAt.Time
+	}
+
+	if len(result.Scopes.VenueIDs) == 0 && len(result.Scopes.TourIDs) == 0 {
+		switch {
+		case jwtClaims.VenueID != 0:
+			result.Scopes.VenueIDs = []int64{jwtClaims.VenueID}
+		case provider.LegacyVenueID != 0:
+			result.Scopes.VenueIDs = []int64{provider.LegacyVenueID}
+		}
+	}
+
+	if jwtClaims.Subject != "" {
+		result.TicketID, err = strconv.ParseInt(jwtClaims.Subject, 10, 64)
+		if err != nil {
+			return TokenClaims{}, false, nil
+		}
+	}
+
+	valid, err := checkRevocation(provider.RevocationList, result)
+	if err != nil || !valid {
+		return TokenClaims{}, false, err
 	}
 
 	return result, true, nil
 }
 
-func CreateJWTTokenProvider(secret string) (TokenProvider, error) {
-	if secret == "" {
-		return nil, errors.New("secret is empty")
+// Returns public keys that verify tokens, symmetric keys are never published
+func (provider *JWTTokenProvider) GetKeySet() (KeySet, error) {
+	result := KeySet{Keys: []PublicKey{}}
+	for _, key := range provider.Keys {
+		if key.Method == jwt.SigningMethodHS256 {
+			continue
+		}
+
+		publicKey, err := key.getPublicKey()
+		if err != nil {
+			return KeySet{}, err
+		}
+
+		result.Keys = append(result.Keys, publicKey)
 	}
 
+	slices.SortFunc(result.Keys, func(a PublicKey, b PublicKey) int {
+		return strings.Compare(a.KeyID, b.KeyID)
+	})
+
+	return result, nil
+}
+
+// Tokens are signed with the key with signingKeyID and checked against the revocation list on every verification, if it's provided,
+// tokens without audience issued by older versions are accepted for LEGACY_TOKEN_LIFETIME after the provider is created
+func CreateJWTTokenProvider(keys []JWTKey, signingKeyID string, revocationList RevocationList) (*JWTTokenProvider, error) {
 	provider := JWTTokenProvider{
-		JWTSecret: []byte(secret),
+		Keys:              map[string]JWTKey{},
+		SigningKeyID:      signingKeyID,
+		RevocationList:    revocationList,
+		LegacyTokensUntil: time.Now().Add(LEGACY_TOKEN_LIFETIME),
+	}
+
+	for _, key := range keys {
+		if _, found := provider.Keys[key.ID]; found {
+			return nil, fmt.Errorf("key %q is duplicated", key.ID)
+		}
+		provider.Keys[key.ID] = key
+	}
+
+	signingKey, found := provider.Keys[signingKeyID]
+	if !found {
+		return nil, fmt.Errorf("signing key %q not found", signingKeyID)
+	}
+
+	if signingKey.SignKey == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
 	}
 
 	return &provider, nil
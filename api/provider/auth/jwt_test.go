@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func createTestRSAKey(t *testing.T, id string) JWTKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+
+	return JWTKey{ID: id, Method: jwt.SigningMethodRS256, SignKey: privateKey, VerifyKey: &privateKey.PublicKey}
+}
+
+func createTestEdDSAKey(t *testing.T, id string) JWTKey {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+
+	return JWTKey{ID: id, Method: jwt.SigningMethodEdDSA, SignKey: privateKey, VerifyKey: publicKey}
+}
+
+func createTestJWT(t *testing.T, provider TokenProvider) string {
+	token, err := provider.Create(TokenClaims{
+		ExpiresAt: time.Now().Add(time.Hour),
+		Audience:  API_AUDIENCE,
+		Scopes:    TokenScopes{VenueIDs: []int64{1}},
+	})
+	if err != nil {
+		t.Fatalf("failed to create token: %v", err)
+	}
+
+	return token
+}
+
+func TestJWTKeyRotation(t *testing.T) {
+	secretKey, _ := CreateHMACKey("", "secret")
+	rsaKey := createTestRSAKey(t, "rsa")
+	edKey := createTestEdDSAKey(t, "ed")
+
+	oldProvider, _ := CreateJWTTokenProvider([]JWTKey{secretKey}, "", nil)
+	oldToken := createTestJWT(t, oldProvider)
+
+	// New signing key is added while the old one still verifies its tokens
+	rotatedProvider, err := CreateJWTTokenProvider([]JWTKey{secretKey, rsaKey, edKey}, "ed", nil)
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	newToken := createTestJWT(t, rotatedProvider)
+	for _, token := range []string{oldToken, newToken} {
+		if claims, valid, err := rotatedProvider.Verify(token); !valid || err != nil || !claims.Scopes.HasVenue(1) {
+			t.Fatalf("expected token to be valid after rotation: %v", err)
+		}
+	}
+
+	parsed, _, _ := jwt.NewParser().ParseUnverified(newToken, &JWTClaims{})
+	if parsed.Header["kid"] != "ed" || parsed.Method != jwt.SigningMethodEdDSA {
+		t.Fatalf("unexpected token header %+v", parsed.Header)
+	}
+
+	// Tokens of a removed key are rejected
+	newProvider, _ := CreateJWTTokenProvider([]JWTKey{edKey}, "ed", nil)
+	if _, valid, _ := newProvider.Verify(oldToken); valid {
+		t.Fatalf("expected token of removed key to be rejected")
+	}
+
+	// Verification key can't sign, but verifies tokens of its private key
+	rsaProvider, _ := CreateJWTTokenProvider([]JWTKey{rsaKey}, "rsa", nil)
+	verifyKey := JWTKey{ID: "rsa", Method: jwt.SigningMethodRS256, VerifyKey: rsaKey.VerifyKey}
+	if _, err := CreateJWTTokenProvider([]JWTKey{verifyKey}, "rsa", nil); err == nil {
+		t.Fatalf("expected verification key to be rejected as signing key")
+	}
+
+	verifyProvider, _ := CreateJWTTokenProvider([]JWTKey{verifyKey, edKey}, "ed", nil)
+	if _, valid, err := verifyProvider.Verify(createTestJWT(t, rsaProvider)); !valid || err != nil {
+		t.Fatalf("expected token to be verified with public key: %v", err)
+	}
+
+	// Key ID can't be used to verify a token with another algorithm
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}})
+	forged.Header["kid"] = "rsa"
+	forgedToken, _ := forged.SignedString([]byte("secret"))
+	if _, valid, _ := verifyProvider.Verify(forgedToken); valid {
+		t.Fatalf("expected token with unexpected algorithm to be rejected")
+	}
+}
+
+func TestJWTLegacyTokens(t *testing.T) {
+	secretKey, _ := CreateHMACKey("", "secret")
+	provider, _ := CreateJWTTokenProvider([]JWTKey{secretKey}, "", nil)
+	provider.LegacyVenueID = 1
+
+	expires := jwt.NewNumericDate(time.Now().Add(time.Hour))
+	cases := []struct {
+		claims   JWTClaims
+		expected int64
+	}{
+		// Token issued before venues were introduced
+		{JWTClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expires}}, 1},
+		// Token issued before scopes were introduced
+		{JWTClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: expires}, VenueID: 2}, 2},
+	}
+
+	for _, test := range cases {
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, test.claims).SignedString([]byte("secret"))
+		claims, valid, err := provider.Verify(token)
+		if !valid || err != nil || len(claims.Scopes.VenueIDs) != 1 || !claims.Scopes.HasVenue(test.expected) {
+			t.Fatalf("expected legacy token to unlock venue %d, got %+v", test.expected, claims.Scopes)
+		}
+	}
+}
+
+func TestJWTLegacyTokensCutover(t *testing.T) {
+	secretKey, _ := CreateHMACKey("", "secret")
+	provider, _ := CreateJWTTokenProvider([]JWTKey{secretKey}, "", nil)
+	provider.LegacyVenueID = 1
+
+	claims := JWTClaims{RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))}, VenueID: 2}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+
+	// Tokens without audience are rejected if they expire after the cutover
+	cutovers := []time.Time{{}, time.Now().Add(30 * time.Minute)}
+	for _, cutover := range cutovers {
+		provider.LegacyTokensUntil = cutover
+		if _, valid, _ := provider.Verify(token); valid {
+			t.Fatalf("expected token without audience to be rejected with cutover %v", cutover)
+		}
+	}
+}
+
+func TestJWTTokenWithoutExpiration(t *testing.T) {
+	rsaKey := createTestRSAKey(t, "rsa")
+	provider, _ := CreateJWTTokenProvider([]JWTKey{rsaKey}, "rsa", nil)
+
+	token := jwt.NewWithClaims(rsaKey.Method, JWTClaims{RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{API_AUDIENCE}}})
+	token.Header["kid"] = rsaKey.ID
+	tokenString, err := token.SignedString(rsaKey.SignKey)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	if _, valid, _ := provider.Verify(tokenString); valid {
+		t.Fatalf("expected token without expiration to be rejected")
+	}
+}
+
+func TestLoadJWTKeys(t *testing.T) {
+	path := t.TempDir()
+	rsaKey := createTestRSAKey(t, "rsa")
+	edKey := createTestEdDSAKey(t, "ed")
+
+	rsaData, _ := x509.MarshalPKCS8PrivateKey(rsaKey.SignKey)
+	edData, _ := x509.MarshalPKIXPublicKey(edKey.VerifyKey)
+	files := map[string][]byte{
+		"rsa.pem":   pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: rsaData}),
+		"ed.pem":    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: edData}),
+		"notes.txt": []byte("not a key"),
+	}
+
+	for name, data := range files {
+		os.WriteFile(filepath.Join(path, name), data, 0600)
+	}
+
+	keys, err := LoadJWTKeys(path)
+	if err != nil || len(keys) != 2 {
+		t.Fatalf("unexpected keys %+v: %v", keys, err)
+	}
+
+	loaded := map[string]JWTKey{}
+	for _, key := range keys {
+		loaded[key.ID] = key
+	}
+
+	if loaded["rsa"].Method != jwt.SigningMethodRS256 || loaded["rsa"].SignKey == nil {
+		t.Fatalf("expected RSA signing key, got %+v", loaded["rsa"])
+	}
+
+	if loaded["ed"].Method != jwt.SigningMethodEdDSA || loaded["ed"].SignKey != nil {
+		t.Fatalf("expected EdDSA verification key, got %+v", loaded["ed"])
+	}
+
+	if _, err := ParseJWTKey("invalid", []byte("not a key")); err == nil {
+		t.Fatalf("expected invalid key to be rejected")
+	}
+}
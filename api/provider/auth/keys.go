@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const JWT_KEY_FILE_EXTENSION = ".pem"
+
+// Key that signs and verifies tokens, keys without SignKey can only verify
+type JWTKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	SignKey   any
+	VerifyKey any
+}
+
+func (key JWTKey) getPublicKey() (PublicKey, error) {
+	result := PublicKey{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+	switch verifyKey := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		result.KeyType = "RSA"
+		result.N = base64.RawURLEncoding.EncodeToString(verifyKey.N.Bytes())
+		result.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(verifyKey.E)).Bytes())
+	case ed25519.PublicKey:
+		result.KeyType = "OKP"
+		result.Curve = "Ed25519"
+		result.X = base64.RawURLEncoding.EncodeToString(verifyKey)
+	default:
+		return PublicKey{}, fmt.Errorf("key %q can't be published", key.ID)
+	}
+
+	return result, nil
+}
+
+// Symmetric HS256 key, it's never published
+func CreateHMACKey(id string, secret string) (JWTKey, error) {
+	if secret == "" {
+		return JWTKey{}, errors.New("secret is empty")
+	}
+
+	return JWTKey{ID: id, Method: jwt.SigningMethodHS256, SignKey: []byte(secret), VerifyKey: []byte(secret)}, nil
+}
+
+// Parses PEM encoded RSA or Ed25519 key, private keys are used to sign with RS256 or EdDSA, public keys only verify
+func ParseJWTKey(id string, data []byte) (JWTKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return JWTKey{}, fmt.Errorf("key %q is not PEM encoded", id)
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("PEM block %q is not supported", block.Type)
+	}
+
+	if err != nil {
+		return JWTKey{}, fmt.Errorf("failed to parse key %q: %w", id, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return JWTKey{ID: id, Method: jwt.SigningMethodRS256, SignKey: key, VerifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return JWTKey{ID: id, Method: jwt.SigningMethodRS256, VerifyKey: key}, nil
+	case ed25519.PrivateKey:
+		return JWTKey{ID: id, Method: jwt.SigningMethodEdDSA, SignKey: key, VerifyKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return JWTKey{ID: id, Method: jwt.SigningMethodEdDSA, VerifyKey: key}, nil
+	}
+
+	return JWTKey{}, fmt.Errorf("key %q type is not supported", id)
+}
+
+// Loads keys from PEM files in the directory, the file name without extension is the key ID
+func LoadJWTKeys(path string) ([]JWTKey, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []JWTKey{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != JWT_KEY_FILE_EXTENSION {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ParseJWTKey(strings.TrimSuffix(entry.Name(), JWT_KEY_FILE_EXTENSION), data)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, key)
+	}
+
+	return result, nil
+}
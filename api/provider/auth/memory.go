@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// In-memory implementation of TokenProvider for tests,
+// tokens are opaque strings mapped to the claims they were created with
+type MemoryTokenProvider struct {
+	mutex          sync.Mutex
+	tokens         map[string]TokenClaims
+	revocationList RevocationList
+}
+
+func (provider *MemoryTokenProvider) Create(claims TokenClaims) (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	token := "token-" + strconv.Itoa(len(provider.tokens)+1)
+	provider.tokens[token] = claims
+
+	return token, nil
+}
+
+func (provider *MemoryTokenProvider) Verify(token string) (TokenClaims, bool, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	claims, found := provider.tokens[token]
+	if !found || claims.ExpiresAt.Before(time.Now()) || claims.Audience != API_AUDIENCE {
+		return TokenClaims{}, false, nil
+	}
+
+	valid, err := checkRevocation(provider.revocationList, claims)
+	if err != nil || !valid {
+		return TokenClaims{}, false, err
+	}
+
+	return claims, true, nil
+}
+
+func CreateMemoryTokenProvider(revocationList RevocationList) *MemoryTokenProvider {
+	return &MemoryTokenProvider{
+		tokens:         map[string]TokenClaims{},
+		revocationList: revocationList,
+	}
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key of init data signature is derived from the bot token with this seed
+// https://core.telegram.org/bots/webapps#validating-data-received-via-the-mini-app
+const INIT_DATA_KEY_SEED = "WebAppData"
+
+// Mini App keeps the same init data while it's open, so it's accepted for a day
+const DEFAULT_INIT_DATA_MAX_AGE = 24 * time.Hour
+
+type TelegramInitDataValidator struct {
+	SecretKey []byte
+	MaxAge    time.Duration
+}
+
+func (validator *TelegramInitDataValidator) Validate(initData string) (TelegramUser, bool, error) {
+	values, err := url.ParseQuery(initData)
+	if err != nil {
+		return TelegramUser{}, false, err
+	}
+
+	hash := values.Get("hash")
+	if hash == "" {
+		return TelegramUser{}, false, errors.New("init data hash is missing")
+	}
+
+	// Data-check string is all fields except hash sorted by key, formatted as key=value and joined with line feeds
+	keys := []string{}
+	for key, value := range values {
+		// Only the first value is signed, repeated fields could be used to smuggle unsigned ones
+		if len(value) != 1 {
+			return TelegramUser{}, false, errors.New("init data field is repeated")
+		}
+
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	lines := []string{}
+	for _, key := range keys {
+		lines = append(lines, key+"="+values.Get(key))
+	}
+
+	mac := hmac.New(sha256.New, validator.SecretKey)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return TelegramUser{}, false, nil
+	}
+
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return TelegramUser{}, false, err
+	}
+
+	if time.Since(time.Unix(authDate, 0)) > validator.MaxAge {
+		return TelegramUser{}, false, nil
+	}
+
+	user := TelegramUser{}
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil {
+		return TelegramUser{}, false, err
+	}
+
+	if user.ID == 0 {
+		return TelegramUser{}, false, errors.New("init data user is missing")
+	}
+
+	return user, true, nil
+}
+
+func CreateTelegramInitDataValidator(botToken string, maxAge time.Duration) (InitDataValidator, error) {
+	if botToken == "" {
+		return nil, errors.New("bot token is empty")
+	}
+
+	mac := hmac.New(sha256.New, []byte(INIT_DATA_KEY_SEED))
+	mac.Write([]byte(botToken))
+
+	validator := TelegramInitDataValidator{
+		SecretKey: mac.Sum(nil),
+		MaxAge:    maxAge,
+	}
+
+	return &validator, nil
+}
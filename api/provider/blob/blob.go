@@ -1,9 +1,23 @@
 package blob
 
-import "io"
+import (
+	"errors"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Returned by StatBlob if blob with such name doesn't exist
+var ErrNotExist = errors.New("blob does not exist")
+
+// Returned by GetBlobURL if provider is not configured to issue URLs
+var ErrBlobURLNotSupported = errors.New("blob URLs are not supported")
 
 type StatBlobResult struct {
 	Size int64
+	// Opaque unquoted validator that changes whenever blob content changes
+	ETag         string
+	LastModified time.Time
 }
 
 type BlobRange struct {
@@ -19,4 +33,30 @@ type BlobProvider interface {
 	ReadBlob(name string, options ReadBlobOptions) (io.ReadCloser, error)
 	WriteBlob(name string, reader io.Reader) error
 	StatBlob(name string) (StatBlobResult, error)
+	// Deleting a blob that doesn't exist is not an error
+	DeleteBlob(name string) error
+	// Returns names of all blobs starting with the prefix
+	ListBlobs(prefix string) ([]string, error)
+	// Returns a URL to read the blob directly, valid for the given duration
+	GetBlobURL(name string, expires time.Duration) (string, error)
+}
+
+// Implemented by providers which URLs are served by the API itself
+type BlobURLVerifier interface {
+	VerifyBlobURL(name string, query url.Values) bool
+}
+
+// Creates a provider based on the connection string scheme,
+// file:// URLs are served from the local filesystem, any other URLs from S3
+func CreateBlobProvider(URL string) (BlobProvider, error) {
+	urlObject, err := url.Parse(URL)
+	if err != nil {
+		return nil, err
+	}
+
+	if urlObject.Scheme == "file" {
+		return CreateFilesystemBlobProvider(URL)
+	}
+
+	return CreateS3BlobProvider(URL)
 }
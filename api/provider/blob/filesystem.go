@@ -0,0 +1,241 @@
+package blob
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Query parameters of filesystem blob URLs
+const (
+	FILESYSTEM_URL_EXPIRES   = "expires"
+	FILESYSTEM_URL_SIGNATURE = "signature"
+)
+
+type FilesystemBlobProvider struct {
+	root string
+	// Blob URLs are issued only if both base URL and secret are configured
+	baseURL string
+	secret  []byte
+}
+
+type filesystemBlobReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (reader *filesystemBlobReader) Close() error {
+	return reader.file.Close()
+}
+
+func (provider *FilesystemBlobProvider) ReadBlob(name string, options ReadBlobOptions) (io.ReadCloser, error) {
+	file, err := os.Open(provider.getPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotExist
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Range == nil {
+		return file, nil
+	}
+
+	if options.Range.Start < 0 || options.Range.End < options.Range.Start {
+		file.Close()
+		return nil, errors.New("invalid blob range")
+	}
+
+	if _, err := file.Seek(options.Range.Start, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Range end is inclusive, same as in HTTP Range header
+	reader := filesystemBlobReader{
+		Reader: io.LimitReader(file, options.Range.End-options.Range.Start+1),
+		file:   file,
+	}
+
+	return &reader, nil
+}
+
+func (provider *FilesystemBlobProvider) WriteBlob(name string, reader io.Reader) error {
+	blobPath := provider.getPath(name)
+	directory := filepath.Dir(blobPath)
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first, so readers never see a partially written blob
+	file, err := os.CreateTemp(directory, ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), blobPath)
+}
+
+func (provider *FilesystemBlobProvider) StatBlob(name string) (StatBlobResult, error) {
+	info, err := os.Stat(provider.getPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return StatBlobResult{}, ErrNotExist
+	}
+
+	if err != nil {
+		return StatBlobResult{}, err
+	}
+
+	if info.IsDir() {
+		return StatBlobResult{}, errors.New("blob is a directory")
+	}
+
+	// Blobs are written atomically, so modification time and size identify the content
+	return StatBlobResult{
+		Size:         info.Size(),
+		ETag:         fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size()),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (provider *FilesystemBlobProvider) DeleteBlob(name string) error {
+	err := os.Remove(provider.getPath(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// Names are listed in their cleaned form, the same way they are resolved by other methods
+func (provider *FilesystemBlobProvider) ListBlobs(prefix string) ([]string, error) {
+	cleaned := strings.TrimPrefix(path.Clean("/"+prefix), "/")
+	directory := path.Dir("/" + cleaned)
+	if strings.HasSuffix(prefix, "/") && cleaned != "" {
+		cleaned += "/"
+		directory = "/" + cleaned
+	}
+
+	result := []string{}
+	err := filepath.WalkDir(provider.getPath(directory), func(file string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		// Temporary files of blobs being written are skipped
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), ".blob-") {
+			return err
+		}
+
+		relative, err := filepath.Rel(provider.root, file)
+		if err != nil {
+			return err
+		}
+
+		if name := filepath.ToSlash(relative); strings.HasPrefix(name, cleaned) {
+			result = append(result, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Issues a URL of the API route serving blobs, signed with HMAC of blob name and expiration time
+func (provider *FilesystemBlobProvider) GetBlobURL(name string, expires time.Duration) (string, error) {
+	if provider.baseURL == "" || len(provider.secret) == 0 {
+		return "", ErrBlobURLNotSupported
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set(FILESYSTEM_URL_EXPIRES, expiresAt)
+	query.Set(FILESYSTEM_URL_SIGNATURE, provider.sign(name, expiresAt))
+
+	segments := strings.Split(strings.TrimPrefix(path.Clean("/"+name), "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return provider.baseURL + "/" + strings.Join(segments, "/") + "?" + query.Encode(), nil
+}
+
+func (provider *FilesystemBlobProvider) VerifyBlobURL(name string, query url.Values) bool {
+	if len(provider.secret) == 0 {
+		return false
+	}
+
+	expiresAt := query.Get(FILESYSTEM_URL_EXPIRES)
+	expires, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+
+	signature := provider.sign(name, expiresAt)
+	return hmac.Equal([]byte(signature), []byte(query.Get(FILESYSTEM_URL_SIGNATURE)))
+}
+
+func (provider *FilesystemBlobProvider) sign(name string, expiresAt string) string {
+	mac := hmac.New(sha256.New, provider.secret)
+	mac.Write([]byte(path.Clean("/" + name)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (provider *FilesystemBlobProvider) getPath(name string) string {
+	// Cleaning the name as an absolute path prevents escaping the root directory
+	cleaned := path.Clean("/" + name)
+	return filepath.Join(provider.root, filepath.FromSlash(cleaned))
+}
+
+func CreateFilesystemBlobProvider(URL string) (BlobProvider, error) {
+	urlObject, err := url.Parse(URL)
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.FromSlash(urlObject.Host + urlObject.Path)
+	if root == "" {
+		return nil, errors.New("root directory is empty")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	// Blob URLs point to the API route serving blobs, e.g. file:///var/guide?url=https://api.example.com/blobs&secret=...
+	params := urlObject.Query()
+	provider := FilesystemBlobProvider{
+		root:    root,
+		baseURL: strings.TrimSuffix(params.Get("url"), "/"),
+		secret:  []byte(params.Get("secret")),
+	}
+
+	return &provider, nil
+}
@@ -0,0 +1,169 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func createTestFilesystemProvider(t *testing.T) (BlobProvider, string) {
+	root := filepath.Join(t.TempDir(), "root")
+	provider, err := CreateFilesystemBlobProvider("file://" + filepath.ToSlash(root))
+	if err != nil {
+		t.Fatalf("failed to create provider: %v", err)
+	}
+
+	return provider, root
+}
+
+func readTestBlob(t *testing.T, provider BlobProvider, name string, options ReadBlobOptions) string {
+	reader, err := provider.ReadBlob(name, options)
+	if err != nil {
+		t.Fatalf("failed to read blob %s: %v", name, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read blob %s: %v", name, err)
+	}
+
+	return string(data)
+}
+
+func TestFilesystemPathTraversal(t *testing.T) {
+	provider, root := createTestFilesystemProvider(t)
+
+	if err := provider.WriteBlob("../../escaped.txt", strings.NewReader("data")); err != nil {
+		t.Fatalf("failed to write blob: %v", err)
+	}
+
+	// Names escaping the root are resolved inside of it
+	if _, err := os.Stat(filepath.Join(root, "escaped.txt")); err != nil {
+		t.Fatalf("expected blob to be written inside the root: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(filepath.Dir(filepath.Dir(root)), "escaped.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("blob was written outside of the root")
+	}
+
+	if data := readTestBlob(t, provider, "/a/../../escaped.txt", ReadBlobOptions{}); data != "data" {
+		t.Fatalf("unexpected blob data %q", data)
+	}
+
+	secret := filepath.Join(filepath.Dir(root), "secret.txt")
+	os.WriteFile(secret, []byte("secret"), 0644)
+	if _, err := provider.ReadBlob("../secret.txt", ReadBlobOptions{}); err != ErrNotExist {
+		t.Fatalf("expected file outside of the root to be not found, got %v", err)
+	}
+}
+
+func TestFilesystemReadRange(t *testing.T) {
+	provider, _ := createTestFilesystemProvider(t)
+	provider.WriteBlob("object/audio.mp3", strings.NewReader("0123456789"))
+
+	// Range end is inclusive
+	cases := map[BlobRange]string{
+		{Start: 0, End: 0}:  "0",
+		{Start: 2, End: 5}:  "2345",
+		{Start: 7, End: 9}:  "789",
+		{Start: 8, End: 20}: "89",
+	}
+
+	for blobRange, expected := range cases {
+		if data := readTestBlob(t, provider, "object/audio.mp3", ReadBlobOptions{Range: &blobRange}); data != expected {
+			t.Fatalf("range %+v: expected %q, got %q", blobRange, expected, data)
+		}
+	}
+
+	for _, blobRange := range []BlobRange{{Start: -1, End: 2}, {Start: 5, End: 4}} {
+		if _, err := provider.ReadBlob("object/audio.mp3", ReadBlobOptions{Range: &blobRange}); err == nil {
+			t.Fatalf("expected range %+v to be rejected", blobRange)
+		}
+	}
+
+	if _, err := provider.ReadBlob("object/missing.mp3", ReadBlobOptions{}); err != ErrNotExist {
+		t.Fatalf("expected missing blob to be not found, got %v", err)
+	}
+}
+
+type failingReader struct {
+	data string
+}
+
+func (reader *failingReader) Read(buffer []byte) (int, error) {
+	if reader.data == "" {
+		return 0, errors.New("connection reset")
+	}
+
+	n := copy(buffer, reader.data)
+	reader.data = reader.data[n:]
+	return n, nil
+}
+
+func TestFilesystemAtomicWrite(t *testing.T) {
+	provider, root := createTestFilesystemProvider(t)
+	provider.WriteBlob("object/cover.jpg", strings.NewReader("original"))
+
+	if err := provider.WriteBlob("object/cover.jpg", &failingReader{data: "partial"}); err == nil {
+		t.Fatalf("expected failed upload to return an error")
+	}
+
+	// Failed write neither replaces the blob nor leaves temporary files
+	if data := readTestBlob(t, provider, "object/cover.jpg", ReadBlobOptions{}); data != "original" {
+		t.Fatalf("expected blob to be unchanged, got %q", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(root, "object"))
+	if len(entries) != 1 {
+		t.Fatalf("expected only the blob in the directory, got %v", entries)
+	}
+
+	if err := provider.WriteBlob("object/cover.jpg", strings.NewReader("replaced")); err != nil {
+		t.Fatalf("failed to replace blob: %v", err)
+	}
+
+	stat, err := provider.StatBlob("object/cover.jpg")
+	if err != nil || stat.Size != int64(len("replaced")) {
+		t.Fatalf("unexpected blob stat %+v, %v", stat, err)
+	}
+
+	if err := provider.DeleteBlob("object/cover.jpg"); err != nil {
+		t.Fatalf("failed to delete blob: %v", err)
+	}
+
+	if _, err := provider.StatBlob("object/cover.jpg"); err != ErrNotExist {
+		t.Fatalf("expected deleted blob to be not found, got %v", err)
+	}
+
+	if err := provider.DeleteBlob("object/cover.jpg"); err != nil {
+		t.Fatalf("expected deleting missing blob to succeed, got %v", err)
+	}
+}
+
+func TestFilesystemListBlobs(t *testing.T) {
+	provider, root := createTestFilesystemProvider(t)
+	for _, name := range []string{"variants/object/cover.jpg/160x0.jpeg", "variants/object/cover.jpg/0x0.webp", "variants/object/cover.jpg.old/160x0.jpeg", "object/cover.jpg"} {
+		provider.WriteBlob(name, strings.NewReader("data"))
+	}
+	os.WriteFile(filepath.Join(root, "variants", "object", "cover.jpg", ".blob-123"), []byte("partial"), 0644)
+
+	cases := map[string][]string{
+		"variants/object/cover.jpg/":  {"variants/object/cover.jpg/0x0.webp", "variants/object/cover.jpg/160x0.jpeg"},
+		"/variants/object/cover.jpg/": {"variants/object/cover.jpg/0x0.webp", "variants/object/cover.jpg/160x0.jpeg"},
+		"variants/object/cover.jpg.":  {"variants/object/cover.jpg.old/160x0.jpeg"},
+		"object/":                     {"object/cover.jpg"},
+		"missing/":                    {},
+	}
+
+	for prefix, expected := range cases {
+		names, err := provider.ListBlobs(prefix)
+		if err != nil || !slices.Equal(names, expected) {
+			t.Fatalf("%s: expected %v, got %v: %v", prefix, expected, names, err)
+		}
+	}
+}
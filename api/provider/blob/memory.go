@@ -0,0 +1,119 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryBlob struct {
+	data     []byte
+	modified time.Time
+}
+
+// In-memory implementation of BlobProvider for tests
+type MemoryBlobProvider struct {
+	mutex sync.Mutex
+	blobs map[string]memoryBlob
+}
+
+func (provider *MemoryBlobProvider) ReadBlob(name string, options ReadBlobOptions) (io.ReadCloser, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	blob, found := provider.blobs[name]
+	if !found {
+		return nil, ErrNotExist
+	}
+
+	data := blob.data
+	if options.Range != nil {
+		if options.Range.Start < 0 || options.Range.End < options.Range.Start || options.Range.Start >= int64(len(data)) {
+			return nil, errors.New("invalid blob range")
+		}
+
+		end := options.Range.End + 1
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		data = data[options.Range.Start:end]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (provider *MemoryBlobProvider) WriteBlob(name string, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.blobs[name] = memoryBlob{data: data, modified: time.Now()}
+	return nil
+}
+
+func (provider *MemoryBlobProvider) StatBlob(name string) (StatBlobResult, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	blob, found := provider.blobs[name]
+	if !found {
+		return StatBlobResult{}, ErrNotExist
+	}
+
+	return StatBlobResult{
+		Size:         int64(len(blob.data)),
+		ETag:         fmt.Sprintf("%x", md5.Sum(blob.data)),
+		LastModified: blob.modified,
+	}, nil
+}
+
+func (provider *MemoryBlobProvider) DeleteBlob(name string) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	delete(provider.blobs, name)
+	return nil
+}
+
+func (provider *MemoryBlobProvider) ListBlobs(prefix string) ([]string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	result := []string{}
+	for name := range provider.blobs {
+		if strings.HasPrefix(name, prefix) {
+			result = append(result, name)
+		}
+	}
+
+	sort.Strings(result)
+	return result, nil
+}
+
+// Returns a fake URL that includes blob name and expiration time
+func (provider *MemoryBlobProvider) GetBlobURL(name string, expires time.Duration) (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if _, found := provider.blobs[name]; !found {
+		return "", ErrNotExist
+	}
+
+	return fmt.Sprintf("https://blobs.test/%s?expires=%d", name, time.Now().Add(expires).Unix()), nil
+}
+
+func CreateMemoryBlobProvider() *MemoryBlobProvider {
+	return &MemoryBlobProvider{
+		blobs: map[string]memoryBlob{},
+	}
+}
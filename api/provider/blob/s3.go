@@ -6,6 +6,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -45,15 +46,51 @@ func (provider *S3BlobProvider) WriteBlob(name string, reader io.Reader) error {
 
 func (provider *S3BlobProvider) StatBlob(name string) (StatBlobResult, error) {
 	stat, err := provider.client.StatObject(context.Background(), provider.bucketName, name, minio.StatObjectOptions{})
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return StatBlobResult{}, ErrNotExist
+	}
+
 	if err != nil {
 		return StatBlobResult{}, err
 	}
 
 	return StatBlobResult{
-		Size: stat.Size,
+		Size:         stat.Size,
+		ETag:         strings.Trim(stat.ETag, `"`),
+		LastModified: stat.LastModified,
 	}, nil
 }
 
+func (provider *S3BlobProvider) DeleteBlob(name string) error {
+	return provider.client.RemoveObject(context.Background(), provider.bucketName, name, minio.RemoveObjectOptions{})
+}
+
+func (provider *S3BlobProvider) ListBlobs(prefix string) ([]string, error) {
+	// Listing is stopped by cancelling the context if it fails midway
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	result := []string{}
+	for object := range provider.client.ListObjects(ctx, provider.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+
+		result = append(result, object.Key)
+	}
+
+	return result, nil
+}
+
+func (provider *S3BlobProvider) GetBlobURL(name string, expires time.Duration) (string, error) {
+	result, err := provider.client.PresignedGetObject(context.Background(), provider.bucketName, name, expires, url.Values{})
+	if err != nil {
+		return "", err
+	}
+
+	return result.String(), nil
+}
+
 func CreateS3BlobProvider(URL string) (BlobProvider, error) {
 	urlObject, err := url.Parse(URL)
 	if err != nil {
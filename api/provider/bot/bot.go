@@ -4,6 +4,20 @@ import "github.com/PaulSonOfLars/gotgbot/v2"
 
 type Update gotgbot.Update
 
+// Returns the user that sent the message, callback or pre-checkout query, nil for other updates
+func (update *Update) Sender() *gotgbot.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return &update.CallbackQuery.From
+	case update.PreCheckoutQuery != nil:
+		return &update.PreCheckoutQuery.From
+	}
+
+	return nil
+}
+
 type InlineKeyboardButton struct {
 	Text         string
 	URL          *string
@@ -22,6 +36,8 @@ type SendMessageOptions struct {
 
 type SendInvoiceOptions struct {
 	InlineKeyboard *InlineKeyboardMarkup
+	// Payments provider token of the invoice, the bot payments token is used if nil
+	ProviderToken *string
 }
 
 type PricePart struct {
@@ -38,9 +54,22 @@ type AnswerPreCheckoutQueryOptions struct {
 	ErrorMessage *string
 }
 
+// Telegram Stars currency, the only one that can be refunded through Bot API
+const STARS_CURRENCY = "XTR"
+
+type BotCommand struct {
+	Command     string
+	Description string
+}
+
 type BotProvider interface {
 	SendMessage(chatID int64, text string, options SendMessageOptions) error
 	AnswerCallbackQuery(queryID string) error
 	AnswerPreCheckoutQuery(queryID string, ok bool, options AnswerPreCheckoutQueryOptions) error
 	SendInvoice(chatID int64, title string, description string, payload string, price InvoicePrice, options SendInvoiceOptions) error
+	ForwardMessage(chatID int64, fromChatID int64, messageID int64) error
+	// Registers commands shown in the bot menu for users with the language, empty language sets the default commands
+	SetMyCommands(commands []BotCommand, language string) error
+	// Refunds a successful payment, returns false if payments in the currency can't be refunded by the bot
+	RefundPayment(userID int64, telegramChargeID string, currency string) (bool, error)
 }
@@ -0,0 +1,126 @@
+package bot
+
+import "sync"
+
+type MemoryMessage struct {
+	ChatID  int64
+	Text    string
+	Options SendMessageOptions
+}
+
+type MemoryInvoice struct {
+	ChatID      int64
+	Title       string
+	Description string
+	Payload     string
+	Price       InvoicePrice
+	Options     SendInvoiceOptions
+}
+
+type MemoryPreCheckoutAnswer struct {
+	QueryID string
+	OK      bool
+	Options AnswerPreCheckoutQueryOptions
+}
+
+type MemoryForwardedMessage struct {
+	ChatID     int64
+	FromChatID int64
+	MessageID  int64
+}
+
+type MemoryRefund struct {
+	UserID           int64
+	TelegramChargeID string
+}
+
+// In-memory implementation of BotProvider for tests,
+// records all calls instead of sending them to Bot API
+type MemoryBotProvider struct {
+	mutex              sync.Mutex
+	Messages           []MemoryMessage
+	Invoices           []MemoryInvoice
+	CallbackAnswers    []string
+	PreCheckoutAnswers []MemoryPreCheckoutAnswer
+	ForwardedMessages  []MemoryForwardedMessage
+	// Registered commands by language
+	Commands map[string][]BotCommand
+	Refunds  []MemoryRefund
+	// Returned by RefundPayment if set
+	RefundError error
+}
+
+func (provider *MemoryBotProvider) SendMessage(chatID int64, text string, options SendMessageOptions) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.Messages = append(provider.Messages, MemoryMessage{ChatID: chatID, Text: text, Options: options})
+	return nil
+}
+
+func (provider *MemoryBotProvider) AnswerCallbackQuery(queryID string) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.CallbackAnswers = append(provider.CallbackAnswers, queryID)
+	return nil
+}
+
+func (provider *MemoryBotProvider) AnswerPreCheckoutQuery(queryID string, ok bool, options AnswerPreCheckoutQueryOptions) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.PreCheckoutAnswers = append(provider.PreCheckoutAnswers, MemoryPreCheckoutAnswer{QueryID: queryID, OK: ok, Options: options})
+	return nil
+}
+
+func (provider *MemoryBotProvider) SendInvoice(chatID int64, title string, description string, payload string, price InvoicePrice, options SendInvoiceOptions) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.Invoices = append(provider.Invoices, MemoryInvoice{
+		ChatID:      chatID,
+		Title:       title,
+		Description: description,
+		Payload:     payload,
+		Price:       price,
+		Options:     options,
+	})
+	return nil
+}
+
+func (provider *MemoryBotProvider) ForwardMessage(chatID int64, fromChatID int64, messageID int64) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.ForwardedMessages = append(provider.ForwardedMessages, MemoryForwardedMessage{ChatID: chatID, FromChatID: fromChatID, MessageID: messageID})
+	return nil
+}
+
+func (provider *MemoryBotProvider) SetMyCommands(commands []BotCommand, language string) error {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	provider.Commands[language] = append([]BotCommand{}, commands...)
+	return nil
+}
+
+func (provider *MemoryBotProvider) RefundPayment(userID int64, telegramChargeID string, currency string) (bool, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.RefundError != nil {
+		return false, provider.RefundError
+	}
+
+	if currency != STARS_CURRENCY {
+		return false, nil
+	}
+
+	provider.Refunds = append(provider.Refunds, MemoryRefund{UserID: userID, TelegramChargeID: telegramChargeID})
+	return true, nil
+}
+
+func CreateMemoryBotProvider() *MemoryBotProvider {
+	return &MemoryBotProvider{Commands: map[string][]BotCommand{}}
+}
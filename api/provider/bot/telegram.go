@@ -1,7 +1,9 @@
 package bot
 
 import (
+	"encoding/json"
 	"errors"
+	"strconv"
 
 	"github.com/PaulSonOfLars/gotgbot/v2"
 )
@@ -62,18 +64,78 @@ func (interactor *TelegramBotProvider) SendInvoice(chatID int64, title string, d
 		labeledPrice = append(labeledPrice, gotgbot.LabeledPrice{Label: part.Label, Amount: part.Amount})
 	}
 
+	providerToken := interactor.PaymentsToken
+	if options.ProviderToken != nil {
+		providerToken = *options.ProviderToken
+	}
+
 	opts := &gotgbot.SendInvoiceOpts{}
 	if options.InlineKeyboard != nil {
 		opts.ReplyMarkup = interactor.buildKeyboard(*options.InlineKeyboard)
 	}
 
-	if _, err := interactor.Bot.SendInvoice(chatID, title, description, payload, interactor.PaymentsToken, price.Currency, labeledPrice, opts); err != nil {
+	if _, err := interactor.Bot.SendInvoice(chatID, title, description, payload, providerToken, price.Currency, labeledPrice, opts); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (interactor *TelegramBotProvider) ForwardMessage(chatID int64, fromChatID int64, messageID int64) error {
+	if _, err := interactor.Bot.ForwardMessage(chatID, fromChatID, messageID, nil); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (interactor *TelegramBotProvider) SetMyCommands(commands []BotCommand, language string) error {
+	botCommands := []gotgbot.BotCommand{}
+	for _, command := range commands {
+		botCommands = append(botCommands, gotgbot.BotCommand{Command: command.Command, Description: command.Description})
+	}
+
+	result, err := interactor.Bot.SetMyCommands(botCommands, &gotgbot.SetMyCommandsOpts{LanguageCode: language})
+	if err != nil {
 		return err
 	}
 
+	if !result {
+		return errors.New("SetMyCommands returned false")
+	}
+
 	return nil
 }
 
+func (interactor *TelegramBotProvider) RefundPayment(userID int64, telegramChargeID string, currency string) (bool, error) {
+	// Payments made with other providers are refunded in the provider dashboard
+	if currency != STARS_CURRENCY {
+		return false, nil
+	}
+
+	// gotgbot version in use has no wrapper for refundStarPayment
+	params := map[string]string{
+		"user_id":                    strconv.FormatInt(userID, 10),
+		"telegram_payment_charge_id": telegramChargeID,
+	}
+
+	response, err := interactor.Bot.Request("refundStarPayment", params, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	result := false
+	if err := json.Unmarshal(response, &result); err != nil {
+		return false, err
+	}
+
+	if !result {
+		return false, errors.New("refundStarPayment returned false")
+	}
+
+	return true, nil
+}
+
 func (interactor *TelegramBotProvider) buildKeyboard(keyboard InlineKeyboardMarkup) gotgbot.InlineKeyboardMarkup {
 	markup := [][]gotgbot.InlineKeyboardButton{}
 	for _, row := range keyboard.Markup {
@@ -10,4 +10,7 @@ type DBProvider interface {
 	Query(query string, args ...interface{}) (DBReader, error)
 	Exec(query string, args ...interface{}) (int64, error)
 	Migrate() (uint, error)
+	// Runs the callback with a provider bound to a transaction, that is committed if the callback succeeds,
+	// transactions started from the callback provider run in the same transaction
+	Transaction(callback func(provider DBProvider) error) error
 }
@@ -0,0 +1,31 @@
+BEGIN;
+
+ALTER TABLE tickets
+    ADD used BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE tickets
+    SET used = activations > 0;
+
+ALTER TABLE tickets
+    DROP COLUMN ticket_type_id,
+    DROP COLUMN activations,
+    DROP COLUMN activated_at;
+
+INSERT INTO config (key, value)
+    SELECT 'TICKET_PRICE', ticket_types.price::VARCHAR
+    FROM ticket_types
+    JOIN config ON config.value = ticket_types.ticket_type_id::VARCHAR
+    WHERE config.key = 'TICKET_TYPE';
+
+INSERT INTO config (key, value)
+    SELECT 'TICKET_CURRENCY', ticket_types.currency
+    FROM ticket_types
+    JOIN config ON config.value = ticket_types.ticket_type_id::VARCHAR
+    WHERE config.key = 'TICKET_TYPE';
+
+DELETE FROM config
+    WHERE key = 'TICKET_TYPE';
+
+DROP TABLE ticket_types;
+
+END;
@@ -0,0 +1,39 @@
+BEGIN;
+
+CREATE TABLE ticket_types(
+    ticket_type_id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    price BIGINT NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    activations INT NOT NULL DEFAULT 1,
+    validity_days INT NOT NULL DEFAULT 1,
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC');
+
+INSERT INTO ticket_types (name, price, currency)
+    SELECT 'Standard',
+        COALESCE((SELECT value::BIGINT FROM config WHERE key = 'TICKET_PRICE'), 0),
+        COALESCE((SELECT value FROM config WHERE key = 'TICKET_CURRENCY'), 'USD');
+
+INSERT INTO config (key, value)
+    SELECT 'TICKET_TYPE', ticket_type_id::VARCHAR
+    FROM ticket_types
+    WHERE EXISTS (SELECT 1 FROM config WHERE key = 'TICKET_PRICE')
+    AND EXISTS (SELECT 1 FROM config WHERE key = 'TICKET_CURRENCY');
+
+DELETE FROM config
+    WHERE key IN ('TICKET_PRICE', 'TICKET_CURRENCY');
+
+ALTER TABLE tickets
+    ADD ticket_type_id BIGINT,
+    ADD activations INT NOT NULL DEFAULT 0,
+    ADD activated_at TIMESTAMPTZ;
+
+UPDATE tickets
+    SET ticket_type_id = (SELECT MIN(ticket_type_id) FROM ticket_types),
+    activations = CASE WHEN used THEN 1 ELSE 0 END;
+
+ALTER TABLE tickets
+    ALTER COLUMN ticket_type_id SET NOT NULL,
+    DROP COLUMN used;
+
+END;
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP TABLE ticket_types_i18n;
+
+INSERT INTO config (key, value)
+    SELECT 'TICKET_TYPE', MIN(ticket_type_id)::VARCHAR
+    FROM ticket_types
+    WHERE active = true
+    HAVING COUNT(*) > 0;
+
+ALTER TABLE ticket_types
+    DROP COLUMN active;
+
+END;
@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE ticket_types
+    ADD active BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE ticket_types
+    SET active = true
+    FROM config
+    WHERE config.key = 'TICKET_TYPE'
+    AND config.value = ticket_types.ticket_type_id::VARCHAR;
+
+DELETE FROM config
+    WHERE key = 'TICKET_TYPE';
+
+CREATE TABLE ticket_types_i18n(
+    i18n_id BIGSERIAL PRIMARY KEY,
+    ticket_type_id BIGINT NOT NULL,
+    language VARCHAR(2) NOT NULL,
+    title VARCHAR(32) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    UNIQUE (ticket_type_id, language));
+
+END;
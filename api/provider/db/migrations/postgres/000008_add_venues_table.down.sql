@@ -0,0 +1,20 @@
+BEGIN;
+
+DROP INDEX config_venue_key_index;
+
+DELETE FROM config
+    WHERE venue_id IS NOT NULL;
+
+ALTER TABLE config
+    DROP COLUMN venue_id,
+    ADD CONSTRAINT config_key_key UNIQUE (key);
+
+ALTER TABLE ticket_types
+    DROP COLUMN venue_id;
+
+ALTER TABLE objects
+    DROP COLUMN venue_id;
+
+DROP TABLE venues;
+
+END;
@@ -0,0 +1,36 @@
+BEGIN;
+
+CREATE TABLE venues(
+    venue_id BIGSERIAL PRIMARY KEY,
+    code VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(64) NOT NULL);
+
+INSERT INTO venues (code, name)
+    VALUES ('default', 'Default');
+
+ALTER TABLE objects
+    ADD venue_id BIGINT;
+
+UPDATE objects
+    SET venue_id = (SELECT MIN(venue_id) FROM venues);
+
+ALTER TABLE objects
+    ALTER COLUMN venue_id SET NOT NULL;
+
+ALTER TABLE ticket_types
+    ADD venue_id BIGINT;
+
+UPDATE ticket_types
+    SET venue_id = (SELECT MIN(venue_id) FROM venues);
+
+ALTER TABLE ticket_types
+    ALTER COLUMN venue_id SET NOT NULL;
+
+-- Config rows without a venue are global and apply to all venues
+ALTER TABLE config
+    ADD venue_id BIGINT,
+    DROP CONSTRAINT config_key_key;
+
+CREATE UNIQUE INDEX config_venue_key_index ON config (COALESCE(venue_id, 0), key);
+
+END;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE tour_stops;
+
+DROP TABLE tours_i18n;
+
+DROP TABLE tours;
+
+END;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TABLE tours(
+    tour_id BIGSERIAL PRIMARY KEY,
+    venue_id BIGINT NOT NULL);
+
+CREATE TABLE tours_i18n(
+    i18n_id BIGSERIAL PRIMARY KEY,
+    tour_id BIGINT NOT NULL,
+    language VARCHAR(2) NOT NULL,
+    title VARCHAR(64) NOT NULL,
+    description VARCHAR(1024) NOT NULL,
+    UNIQUE (tour_id, language));
+
+CREATE TABLE tour_stops(
+    stop_id BIGSERIAL PRIMARY KEY,
+    tour_id BIGINT NOT NULL,
+    index INTEGER NOT NULL,
+    object_id BIGINT NOT NULL,
+    UNIQUE (tour_id, index));
+
+END;
@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP TABLE objects_tags;
+
+UPDATE objects_i18n
+    SET title = LEFT(title, 64);
+
+ALTER TABLE objects_i18n
+    ALTER COLUMN title TYPE VARCHAR(64),
+    DROP COLUMN description,
+    DROP COLUMN author,
+    DROP COLUMN location;
+
+ALTER TABLE objects
+    DROP COLUMN year;
+
+END;
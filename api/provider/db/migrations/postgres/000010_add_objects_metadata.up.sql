@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE objects
+    ADD year INTEGER;
+
+ALTER TABLE objects_i18n
+    ALTER COLUMN title TYPE VARCHAR(255),
+    ADD description TEXT NOT NULL DEFAULT '',
+    ADD author VARCHAR(128) NOT NULL DEFAULT '',
+    ADD location VARCHAR(128) NOT NULL DEFAULT '';
+
+CREATE TABLE objects_tags(
+    tag_id BIGSERIAL PRIMARY KEY,
+    object_id BIGINT NOT NULL,
+    language VARCHAR(2) NOT NULL,
+    tag VARCHAR(32) NOT NULL,
+    UNIQUE (object_id, language, tag));
+
+END;
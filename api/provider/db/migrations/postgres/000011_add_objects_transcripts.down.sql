@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE objects_i18n
+    DROP COLUMN transcript_path;
+
+END;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE objects_i18n
+    ADD transcript_path VARCHAR(128) NOT NULL DEFAULT '';
+
+END;
@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE audio_tracks;
+
+END;
@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE audio_tracks(
+    track_id BIGSERIAL PRIMARY KEY,
+    object_id BIGINT NOT NULL,
+    language VARCHAR(2) NOT NULL,
+    name VARCHAR(32) NOT NULL,
+    index INTEGER NOT NULL,
+    duration_ms BIGINT NOT NULL,
+    audio_path VARCHAR(128) NOT NULL,
+    UNIQUE (object_id, language, name));
+
+END;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE objects_i18n
+    DROP COLUMN audio_duration_ms,
+    DROP COLUMN audio_bitrate,
+    DROP COLUMN audio_sample_rate,
+    DROP COLUMN audio_mime_type;
+
+END;
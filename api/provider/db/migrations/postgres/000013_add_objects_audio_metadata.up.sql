@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE objects_i18n
+    ADD audio_duration_ms BIGINT NOT NULL DEFAULT 0,
+    ADD audio_bitrate INT NOT NULL DEFAULT 0,
+    ADD audio_sample_rate INT NOT NULL DEFAULT 0,
+    ADD audio_mime_type VARCHAR(64) NOT NULL DEFAULT '';
+
+END;
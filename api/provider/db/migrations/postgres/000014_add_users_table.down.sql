@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP INDEX tickets_buyer_user_id_index;
+
+ALTER TABLE tickets
+    DROP COLUMN buyer_user_id;
+
+DROP TABLE users;
+
+END;
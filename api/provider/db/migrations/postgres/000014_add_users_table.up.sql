@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Users are identified by Telegram user id
+CREATE TABLE users(
+    user_id BIGINT PRIMARY KEY,
+    language VARCHAR(2));
+
+ALTER TABLE tickets
+    ADD buyer_user_id BIGINT;
+
+CREATE INDEX tickets_buyer_user_id_index ON tickets (buyer_user_id);
+
+END;
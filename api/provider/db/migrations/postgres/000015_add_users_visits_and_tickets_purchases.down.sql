@@ -0,0 +1,16 @@
+BEGIN;
+
+ALTER TABLE tickets
+    DROP COLUMN chat_id,
+    DROP COLUMN telegram_payment_charge_id,
+    DROP COLUMN provider_payment_charge_id,
+    DROP COLUMN amount,
+    DROP COLUMN currency,
+    DROP COLUMN purchased_at;
+
+ALTER TABLE users
+    DROP COLUMN language_code,
+    DROP COLUMN first_seen_at,
+    DROP COLUMN last_seen_at;
+
+END;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Language of the Telegram client, language column keeps the one chosen in the bot
+ALTER TABLE users
+    ADD language_code VARCHAR(16),
+    ADD first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Purchase details are empty for tickets created before they were tracked
+ALTER TABLE tickets
+    ADD chat_id BIGINT,
+    ADD telegram_payment_charge_id VARCHAR(256),
+    ADD provider_payment_charge_id VARCHAR(256),
+    ADD amount BIGINT,
+    ADD currency VARCHAR(3),
+    ADD purchased_at TIMESTAMPTZ;
+
+END;
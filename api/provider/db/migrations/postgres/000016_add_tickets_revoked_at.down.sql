@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE tickets
+    DROP COLUMN revoked_at;
+
+END;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Revoked tickets can't be exchanged for tokens, tokens minted from them are rejected
+ALTER TABLE tickets
+    ADD revoked_at TIMESTAMPTZ;
+
+END;
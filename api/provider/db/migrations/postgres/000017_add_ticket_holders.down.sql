@@ -0,0 +1,5 @@
+BEGIN;
+
+DROP TABLE ticket_holders;
+
+END;
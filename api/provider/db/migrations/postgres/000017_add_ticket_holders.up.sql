@@ -0,0 +1,10 @@
+BEGIN;
+
+-- Telegram users that activated the ticket, tokens are re-issued to them without another activation
+CREATE TABLE ticket_holders(
+    ticket_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    activated_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (ticket_id, user_id));
+
+END;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Refresh tokens are stored hashed, every use replaces the token with a new one of the same family,
+-- so reuse of a replaced token revokes the whole family
+CREATE TABLE refresh_tokens(
+    refresh_token_id BIGSERIAL PRIMARY KEY,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    family_id VARCHAR(64) NOT NULL,
+    ticket_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ);
+
+CREATE INDEX refresh_tokens_family_id_index ON refresh_tokens (family_id);
+
+END;
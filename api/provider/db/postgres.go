@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -35,17 +36,19 @@ func (reader *PostgresDBReader) Close() {
 	}
 }
 
-type PostgresDBProvider struct {
-	connection *sql.DB
+// Implemented by both connection and transaction
+type postgresQueryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
 }
 
-func (provider *PostgresDBProvider) Query(query string, args ...interface{}) (DBReader, error) {
-	response, err := provider.connection.Query(query, args...)
+func postgresQuery(queryer postgresQueryer, query string, args ...interface{}) (DBReader, error) {
+	response, err := queryer.Query(query, args...)
 	return &PostgresDBReader{rows: response}, err
 }
 
-func (provider *PostgresDBProvider) Exec(query string, args ...interface{}) (int64, error) {
-	result, err := provider.connection.Exec(query, args...)
+func postgresExec(queryer postgresQueryer, query string, args ...interface{}) (int64, error) {
+	result, err := queryer.Exec(query, args...)
 	if err != nil {
 		return 0, err
 	}
@@ -58,6 +61,32 @@ func (provider *PostgresDBProvider) Exec(query string, args ...interface{}) (int
 	return rows, nil
 }
 
+type PostgresDBProvider struct {
+	connection *sql.DB
+}
+
+func (provider *PostgresDBProvider) Query(query string, args ...interface{}) (DBReader, error) {
+	return postgresQuery(provider.connection, query, args...)
+}
+
+func (provider *PostgresDBProvider) Exec(query string, args ...interface{}) (int64, error) {
+	return postgresExec(provider.connection, query, args...)
+}
+
+func (provider *PostgresDBProvider) Transaction(callback func(provider DBProvider) error) error {
+	tx, err := provider.connection.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := callback(&PostgresTxDBProvider{tx: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (provider *PostgresDBProvider) Migrate() (uint, error) {
 	driver, err := postgres.WithInstance(provider.connection, &postgres.Config{})
 	if err != nil {
@@ -84,6 +113,27 @@ func (provider *PostgresDBProvider) Migrate() (uint, error) {
 	return version, nil
 }
 
+// Provider bound to a transaction, readers must be closed before the next query
+type PostgresTxDBProvider struct {
+	tx *sql.Tx
+}
+
+func (provider *PostgresTxDBProvider) Query(query string, args ...interface{}) (DBReader, error) {
+	return postgresQuery(provider.tx, query, args...)
+}
+
+func (provider *PostgresTxDBProvider) Exec(query string, args ...interface{}) (int64, error) {
+	return postgresExec(provider.tx, query, args...)
+}
+
+func (provider *PostgresTxDBProvider) Migrate() (uint, error) {
+	return 0, errors.New("migrations can't run in a transaction")
+}
+
+func (provider *PostgresTxDBProvider) Transaction(callback func(provider DBProvider) error) error {
+	return callback(provider)
+}
+
 func CreatePostgresDBProvider(URL string) (DBProvider, error) {
 	db, err := sql.Open("postgres", URL)
 	if err != nil {
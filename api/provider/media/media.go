@@ -0,0 +1,21 @@
+package media
+
+import (
+	"errors"
+	"io"
+)
+
+// Returned by ParseAudio if file is not an audio of supported format
+var ErrUnsupportedFormat = errors.New("unsupported audio format")
+
+type AudioInfo struct {
+	DurationMs int64
+	// Average bitrate in bits per second
+	Bitrate    int
+	SampleRate int
+	MIMEType   string
+}
+
+type MediaProvider interface {
+	ParseAudio(reader io.ReadSeeker, size int64) (AudioInfo, error)
+}
@@ -0,0 +1,189 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// MPEG audio frame headers are searched in the first bytes after ID3 tag
+const MAX_MP3_SYNC_SEARCH = 64 * 1024
+
+const (
+	mpegVersion25 = 0
+	mpegVersion2  = 2
+	mpegVersion1  = 3
+)
+
+const (
+	mpegLayer3 = 1
+	mpegLayer2 = 2
+	mpegLayer1 = 3
+)
+
+// Bitrates in kbps by [MPEG1][layer][index]
+var mp3Bitrates = [2][4][16]int{
+	{
+		{},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	},
+	{
+		{},
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	},
+}
+
+var mp3SampleRates = map[int][3]int{
+	mpegVersion1:  {44100, 48000, 32000},
+	mpegVersion2:  {22050, 24000, 16000},
+	mpegVersion25: {11025, 12000, 8000},
+}
+
+type mp3Frame struct {
+	version    int
+	layer      int
+	bitrate    int
+	sampleRate int
+	mono       bool
+	length     int
+}
+
+func (frame mp3Frame) samples() int {
+	switch {
+	case frame.layer == mpegLayer1:
+		return 384
+	case frame.layer == mpegLayer3 && frame.version != mpegVersion1:
+		return 576
+	default:
+		return 1152
+	}
+}
+
+// Offset of Xing/Info header from the frame start, it's placed after side information
+func (frame mp3Frame) xingOffset() int {
+	switch {
+	case frame.version == mpegVersion1 && !frame.mono:
+		return 4 + 32
+	case frame.version == mpegVersion1 || !frame.mono:
+		return 4 + 17
+	default:
+		return 4 + 9
+	}
+}
+
+func isMP3FrameSync(header []byte) bool {
+	_, ok := parseMP3FrameHeader(header)
+	return ok
+}
+
+func parseMP3FrameHeader(header []byte) (mp3Frame, bool) {
+	if len(header) < 4 || header[0] != 0xFF || header[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+
+	version := int(header[1]>>3) & 0x03
+	layer := int(header[1]>>1) & 0x03
+	bitrateIndex := int(header[2]>>4) & 0x0F
+	sampleRateIndex := int(header[2]>>2) & 0x03
+	padding := int(header[2]>>1) & 0x01
+	if version == 1 || layer == 0 || bitrateIndex == 0 || bitrateIndex == 0x0F || sampleRateIndex == 0x03 {
+		return mp3Frame{}, false
+	}
+
+	mpeg1 := 0
+	if version == mpegVersion1 {
+		mpeg1 = 1
+	}
+
+	frame := mp3Frame{
+		version:    version,
+		layer:      layer,
+		bitrate:    mp3Bitrates[mpeg1][layer][bitrateIndex] * 1000,
+		sampleRate: mp3SampleRates[version][sampleRateIndex],
+		mono:       header[3]>>6 == 0x03,
+	}
+
+	if layer == mpegLayer1 {
+		frame.length = (12*frame.bitrate/frame.sampleRate + padding) * 4
+	} else {
+		frame.length = frame.samples()/8*frame.bitrate/frame.sampleRate + padding
+	}
+
+	return frame, true
+}
+
+func parseMP3(reader io.ReadSeeker, size int64) (AudioInfo, error) {
+	start := int64(0)
+	header := make([]byte, 10)
+	if err := readAt(reader, 0, header); err != nil {
+		return AudioInfo{}, err
+	}
+
+	// ID3v2 tag size is a syncsafe integer, footer is present if flag is set
+	if bytes.HasPrefix(header, []byte("ID3")) {
+		start = 10 + (int64(header[6]&0x7F)<<21 | int64(header[7]&0x7F)<<14 | int64(header[8]&0x7F)<<7 | int64(header[9]&0x7F))
+		if header[5]&0x10 != 0 {
+			start += 10
+		}
+	}
+
+	end := size
+	trailer := make([]byte, 3)
+	if size-start > 128 && readAt(reader, size-128, trailer) == nil && bytes.Equal(trailer, []byte("TAG")) {
+		end -= 128
+	}
+
+	buffer := make([]byte, min(MAX_MP3_SYNC_SEARCH, max(end-start, 0)))
+	if err := readAt(reader, start, buffer); err != nil {
+		return AudioInfo{}, err
+	}
+
+	// Frame is accepted only if it's followed by another frame, so random bytes are not taken as a header
+	for offset := 0; offset+4 <= len(buffer); offset++ {
+		frame, ok := parseMP3FrameHeader(buffer[offset:])
+		if !ok {
+			continue
+		}
+
+		next := offset + frame.length
+		if next+4 <= len(buffer) {
+			if _, ok := parseMP3FrameHeader(buffer[next:]); !ok {
+				continue
+			}
+		} else if start+int64(next) != end {
+			continue
+		}
+
+		return getMP3Info(buffer[offset:], frame, end-start-int64(offset)), nil
+	}
+
+	return AudioInfo{}, ErrUnsupportedFormat
+}
+
+func getMP3Info(data []byte, frame mp3Frame, audioSize int64) AudioInfo {
+	info := AudioInfo{SampleRate: frame.sampleRate, MIMEType: "audio/mpeg"}
+
+	// VBR files declare number of frames in Xing/Info or VBRI header of the first frame
+	frames := int64(0)
+	offset := frame.xingOffset()
+	if len(data) >= offset+12 && (bytes.Equal(data[offset:offset+4], []byte("Xing")) || bytes.Equal(data[offset:offset+4], []byte("Info"))) {
+		if binary.BigEndian.Uint32(data[offset+4:])&0x01 != 0 {
+			frames = int64(binary.BigEndian.Uint32(data[offset+8:]))
+		}
+	} else if len(data) >= 4+32+18 && bytes.Equal(data[4+32:4+32+4], []byte("VBRI")) {
+		frames = int64(binary.BigEndian.Uint32(data[4+32+14:]))
+	}
+
+	if frames > 0 {
+		info.DurationMs = frames * int64(frame.samples()) * 1000 / int64(frame.sampleRate)
+		return info
+	}
+
+	info.Bitrate = frame.bitrate
+	info.DurationMs = audioSize * 8 * 1000 / int64(frame.bitrate)
+	return info
+}
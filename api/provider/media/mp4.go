@@ -0,0 +1,180 @@
+package media
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+type mp4Box struct {
+	kind string
+	// Payload position without the box header
+	offset int64
+	size   int64
+}
+
+// Lists boxes stored in the range, box sizes are validated against the range
+func listMP4Boxes(reader io.ReadSeeker, start int64, end int64) ([]mp4Box, error) {
+	result := []mp4Box{}
+	header := make([]byte, 16)
+	for position := start; position+8 <= end; {
+		if err := readAt(reader, position, header[:8]); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - position
+		case 1:
+			if err := readAt(reader, position+8, header[8:16]); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+			headerSize = 16
+		}
+
+		if size < headerSize || size > end-position {
+			return nil, ErrUnsupportedFormat
+		}
+
+		result = append(result, mp4Box{
+			kind:   string(header[4:8]),
+			offset: position + headerSize,
+			size:   size - headerSize,
+		})
+		position += size
+	}
+
+	return result, nil
+}
+
+// Follows the path of box kinds, returns false if any of them is missing
+func findMP4Box(reader io.ReadSeeker, parent mp4Box, path ...string) (mp4Box, bool, error) {
+	for _, kind := range path {
+		boxes, err := listMP4Boxes(reader, parent.offset, parent.offset+parent.size)
+		if err != nil {
+			return mp4Box{}, false, err
+		}
+
+		found := false
+		for _, box := range boxes {
+			if box.kind == kind {
+				parent, found = box, true
+				break
+			}
+		}
+
+		if !found {
+			return mp4Box{}, false, nil
+		}
+	}
+
+	return parent, true, nil
+}
+
+func readMP4Box(reader io.ReadSeeker, box mp4Box, size int64) ([]byte, error) {
+	if box.size < size {
+		return nil, ErrUnsupportedFormat
+	}
+
+	data := make([]byte, size)
+	return data, readAt(reader, box.offset, data)
+}
+
+func parseMP4(reader io.ReadSeeker, size int64) (AudioInfo, error) {
+	moov, found, err := findMP4Box(reader, mp4Box{size: size}, "moov")
+	if err != nil || !found {
+		return AudioInfo{}, orUnsupported(err)
+	}
+
+	mvhd, found, err := findMP4Box(reader, moov, "mvhd")
+	if err != nil || !found {
+		return AudioInfo{}, orUnsupported(err)
+	}
+
+	data, err := readMP4Box(reader, mvhd, 32)
+	if err != nil {
+		return AudioInfo{}, err
+	}
+
+	// Version 1 of movie header has 64-bit times and duration
+	var timescale, duration uint64
+	if data[0] == 1 {
+		timescale = uint64(binary.BigEndian.Uint32(data[20:]))
+		duration = binary.BigEndian.Uint64(data[24:])
+	} else {
+		timescale = uint64(binary.BigEndian.Uint32(data[12:]))
+		duration = uint64(binary.BigEndian.Uint32(data[16:]))
+	}
+
+	if timescale == 0 {
+		return AudioInfo{}, ErrUnsupportedFormat
+	}
+
+	sampleRate, err := getMP4SampleRate(reader, moov)
+	if err != nil {
+		return AudioInfo{}, err
+	}
+
+	return AudioInfo{
+		DurationMs: int64(duration * 1000 / timescale),
+		SampleRate: sampleRate,
+		MIMEType:   "audio/mp4",
+	}, nil
+}
+
+// Returns sample rate of the first sound track, files without sound tracks are not supported
+func getMP4SampleRate(reader io.ReadSeeker, moov mp4Box) (int, error) {
+	boxes, err := listMP4Boxes(reader, moov.offset, moov.offset+moov.size)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, trak := range boxes {
+		if trak.kind != "trak" {
+			continue
+		}
+
+		hdlr, found, err := findMP4Box(reader, trak, "mdia", "hdlr")
+		if err != nil {
+			return 0, err
+		}
+
+		if !found {
+			continue
+		}
+
+		data, err := readMP4Box(reader, hdlr, 12)
+		if err != nil {
+			return 0, err
+		}
+
+		if string(data[8:12]) != "soun" {
+			continue
+		}
+
+		stsd, found, err := findMP4Box(reader, trak, "mdia", "minf", "stbl", "stsd")
+		if err != nil || !found {
+			return 0, orUnsupported(err)
+		}
+
+		// Audio sample entry stores sample rate as 16.16 fixed point number
+		data, err = readMP4Box(reader, stsd, 44)
+		if err != nil {
+			return 0, err
+		}
+
+		return int(binary.BigEndian.Uint32(data[40:]) >> 16), nil
+	}
+
+	return 0, ErrUnsupportedFormat
+}
+
+func orUnsupported(err error) error {
+	if err != nil {
+		return err
+	}
+
+	return ErrUnsupportedFormat
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Parses audio headers without external tools, supports MP3, M4A and OGG (Vorbis and Opus)
+type NativeMediaProvider struct{}
+
+func (provider *NativeMediaProvider) ParseAudio(reader io.ReadSeeker, size int64) (AudioInfo, error) {
+	header := make([]byte, 12)
+	if _, err := reader.Seek(0, io.SeekStart); err != nil {
+		return AudioInfo{}, err
+	}
+
+	if _, err := io.ReadFull(reader, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return AudioInfo{}, ErrUnsupportedFormat
+		}
+		return AudioInfo{}, err
+	}
+
+	var info AudioInfo
+	var err error
+	switch {
+	case bytes.HasPrefix(header, []byte("OggS")):
+		info, err = parseOGG(reader, size)
+	case bytes.Equal(header[4:8], []byte("ftyp")):
+		info, err = parseMP4(reader, size)
+	case bytes.HasPrefix(header, []byte("ID3")) || isMP3FrameSync(header):
+		info, err = parseMP3(reader, size)
+	default:
+		return AudioInfo{}, ErrUnsupportedFormat
+	}
+
+	if err != nil {
+		return AudioInfo{}, err
+	}
+
+	if info.DurationMs <= 0 || info.SampleRate <= 0 {
+		return AudioInfo{}, ErrUnsupportedFormat
+	}
+
+	// Average bitrate is calculated from the file size if container doesn't declare it
+	if info.Bitrate <= 0 {
+		info.Bitrate = int(size * 8 * 1000 / info.DurationMs)
+	}
+
+	return info, nil
+}
+
+// Reads exactly len(buffer) bytes at offset, truncated files are reported as unsupported
+func readAt(reader io.ReadSeeker, offset int64, buffer []byte) error {
+	if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	_, err := io.ReadFull(reader, buffer)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrUnsupportedFormat
+	}
+
+	return err
+}
+
+func CreateNativeMediaProvider() (MediaProvider, error) {
+	return &NativeMediaProvider{}, nil
+}
@@ -0,0 +1,167 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// MPEG1 Layer III frame header, 128 kbps, 44.1 kHz, stereo
+var testMP3Header = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+const testMP3FrameSize = 417
+
+func createTestMP3Frame(payload []byte) []byte {
+	frame := make([]byte, testMP3FrameSize)
+	copy(frame, testMP3Header)
+	copy(frame[4:], payload)
+	return frame
+}
+
+func createTestMP4Box(kind string, payloads ...[]byte) []byte {
+	payload := bytes.Join(payloads, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+	return append(append(box, kind...), payload...)
+}
+
+func createTestOGGPage(serial uint32, granule uint64, packet []byte) []byte {
+	page := append([]byte("OggS"), 0, 0)
+	page = binary.LittleEndian.AppendUint64(page, granule)
+	page = binary.LittleEndian.AppendUint32(page, serial)
+	page = append(page, make([]byte, 8)...)
+	page = append(page, 1, byte(len(packet)))
+	return append(page, packet...)
+}
+
+func parseTestAudio(t *testing.T, data []byte) (AudioInfo, error) {
+	provider, err := CreateNativeMediaProvider()
+	if err != nil {
+		t.Fatalf("failed to create media provider: %v", err)
+	}
+
+	return provider.ParseAudio(bytes.NewReader(data), int64(len(data)))
+}
+
+func TestParseMP3(t *testing.T) {
+	// ID3v2 tag with 20 bytes of syncsafe-encoded size
+	data := append([]byte("ID3"), 4, 0, 0, 0, 0, 0, 20)
+	data = append(data, make([]byte, 20)...)
+	for i := 0; i < 100; i++ {
+		data = append(data, createTestMP3Frame(nil)...)
+	}
+
+	info, err := parseTestAudio(t, data)
+	if err != nil {
+		t.Fatalf("failed to parse mp3: %v", err)
+	}
+
+	expected := AudioInfo{DurationMs: 100 * testMP3FrameSize * 8 * 1000 / 128000, Bitrate: 128000, SampleRate: 44100, MIMEType: "audio/mpeg"}
+	if info != expected {
+		t.Fatalf("expected %+v, got %+v", expected, info)
+	}
+}
+
+func TestParseMP3Xing(t *testing.T) {
+	// Xing header declares 1000 frames of 1152 samples each
+	xing := make([]byte, 32)
+	xing = append(xing, "Xing"...)
+	xing = binary.BigEndian.AppendUint32(xing, 0x01)
+	xing = binary.BigEndian.AppendUint32(xing, 1000)
+
+	data := append(createTestMP3Frame(xing), createTestMP3Frame(nil)...)
+	info, err := parseTestAudio(t, data)
+	if err != nil {
+		t.Fatalf("failed to parse mp3: %v", err)
+	}
+
+	if info.DurationMs != 1000*1152*1000/44100 || info.SampleRate != 44100 {
+		t.Fatalf("unexpected VBR info %+v", info)
+	}
+}
+
+func TestParseMP4(t *testing.T) {
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:], 65432)
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:], "soun")
+
+	stsd := make([]byte, 8, 44)
+	binary.BigEndian.PutUint32(stsd[4:], 1)
+	entry := make([]byte, 28)
+	binary.BigEndian.PutUint32(entry[24:], 48000<<16)
+	stsd = append(stsd, createTestMP4Box("mp4a", entry)...)
+
+	trak := createTestMP4Box("trak", createTestMP4Box("mdia",
+		createTestMP4Box("hdlr", hdlr),
+		createTestMP4Box("minf", createTestMP4Box("stbl", createTestMP4Box("stsd", stsd))),
+	))
+
+	// Movie box is placed after media data, as written by some encoders
+	data := createTestMP4Box("ftyp", []byte("M4A \x00\x00\x00\x00"))
+	data = append(data, createTestMP4Box("mdat", make([]byte, 1000))...)
+	data = append(data, createTestMP4Box("moov", createTestMP4Box("mvhd", mvhd), trak)...)
+
+	info, err := parseTestAudio(t, data)
+	if err != nil {
+		t.Fatalf("failed to parse mp4: %v", err)
+	}
+
+	if info.DurationMs != 65432 || info.SampleRate != 48000 || info.MIMEType != "audio/mp4" || info.Bitrate == 0 {
+		t.Fatalf("unexpected mp4 info %+v", info)
+	}
+}
+
+func TestParseOGG(t *testing.T) {
+	vorbis := append([]byte("\x01vorbis"), make([]byte, 23)...)
+	binary.LittleEndian.PutUint32(vorbis[12:], 44100)
+	binary.LittleEndian.PutUint32(vorbis[20:], 96000)
+
+	data := createTestOGGPage(7, 0, vorbis)
+	data = append(data, createTestOGGPage(7, 441000, make([]byte, 100))...)
+	data = append(data, createTestOGGPage(8, 999999, make([]byte, 100))...)
+
+	info, err := parseTestAudio(t, data)
+	if err != nil {
+		t.Fatalf("failed to parse vorbis: %v", err)
+	}
+
+	expected := AudioInfo{DurationMs: 10000, Bitrate: 96000, SampleRate: 44100, MIMEType: "audio/ogg"}
+	if info != expected {
+		t.Fatalf("expected %+v, got %+v", expected, info)
+	}
+
+	opus := append([]byte("OpusHead"), 1, 2)
+	opus = binary.LittleEndian.AppendUint16(opus, 312)
+	opus = binary.LittleEndian.AppendUint32(opus, 16000)
+	opus = append(opus, 0, 0, 0)
+
+	data = createTestOGGPage(1, 0, opus)
+	data = append(data, createTestOGGPage(1, 96312, make([]byte, 100))...)
+	info, err = parseTestAudio(t, data)
+	if err != nil {
+		t.Fatalf("failed to parse opus: %v", err)
+	}
+
+	if info.DurationMs != 2000 || info.SampleRate != 16000 {
+		t.Fatalf("unexpected opus info %+v", info)
+	}
+}
+
+func TestParseUnsupportedAudio(t *testing.T) {
+	cases := map[string][]byte{
+		"empty":     {},
+		"text":      []byte("definitely not an audio file"),
+		"png":       append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...),
+		"mp3 sync":  append(append([]byte{}, testMP3Header...), make([]byte, 1000)...),
+		"truncated": createTestMP4Box("ftyp", []byte("M4A "))[:10],
+	}
+
+	for name, data := range cases {
+		if _, err := parseTestAudio(t, data); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Fatalf("%s: expected unsupported format, got %v", name, err)
+		}
+	}
+}
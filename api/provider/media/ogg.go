@@ -0,0 +1,99 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Last page is searched at the end of the file, maximum page size is just under 64KB
+const MAX_OGG_TAIL_SEARCH = 128 * 1024
+
+// Opus granule position is always counted in 48kHz samples
+const OPUS_GRANULE_RATE = 48000
+
+const oggPageHeaderSize = 27
+
+func parseOGG(reader io.ReadSeeker, size int64) (AudioInfo, error) {
+	header := make([]byte, oggPageHeaderSize)
+	if err := readAt(reader, 0, header); err != nil {
+		return AudioInfo{}, err
+	}
+
+	segments := make([]byte, header[26])
+	if err := readAt(reader, oggPageHeaderSize, segments); err != nil {
+		return AudioInfo{}, err
+	}
+
+	// Identification header is the first packet of the first page
+	packetSize := 0
+	for _, segment := range segments {
+		packetSize += int(segment)
+		if segment < 255 {
+			break
+		}
+	}
+
+	packet := make([]byte, min(packetSize, 64))
+	if err := readAt(reader, oggPageHeaderSize+int64(len(segments)), packet); err != nil {
+		return AudioInfo{}, err
+	}
+
+	serial := binary.LittleEndian.Uint32(header[14:])
+	granule, err := getOGGLastGranule(reader, size, serial)
+	if err != nil {
+		return AudioInfo{}, err
+	}
+
+	info := AudioInfo{MIMEType: "audio/ogg"}
+	switch {
+	case len(packet) >= 28 && packet[0] == 0x01 && bytes.Equal(packet[1:7], []byte("vorbis")):
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:]))
+		if info.SampleRate == 0 {
+			return AudioInfo{}, ErrUnsupportedFormat
+		}
+
+		info.DurationMs = int64(granule * 1000 / uint64(info.SampleRate))
+		if nominal := int32(binary.LittleEndian.Uint32(packet[20:])); nominal > 0 {
+			info.Bitrate = int(nominal)
+		}
+	case len(packet) >= 16 && bytes.Equal(packet[:8], []byte("OpusHead")):
+		// Input sample rate is informational, Opus is always decoded at 48kHz
+		info.SampleRate = int(binary.LittleEndian.Uint32(packet[12:]))
+		if info.SampleRate == 0 {
+			info.SampleRate = OPUS_GRANULE_RATE
+		}
+
+		preSkip := uint64(binary.LittleEndian.Uint16(packet[10:]))
+		if granule > preSkip {
+			info.DurationMs = int64((granule - preSkip) * 1000 / OPUS_GRANULE_RATE)
+		}
+	default:
+		return AudioInfo{}, ErrUnsupportedFormat
+	}
+
+	return info, nil
+}
+
+// Returns granule position of the last page of the stream, which is the total number of samples
+func getOGGLastGranule(reader io.ReadSeeker, size int64, serial uint32) (uint64, error) {
+	start := max(size-MAX_OGG_TAIL_SEARCH, 0)
+	tail := make([]byte, size-start)
+	if err := readAt(reader, start, tail); err != nil {
+		return 0, err
+	}
+
+	for offset := bytes.LastIndex(tail, []byte("OggS")); offset >= 0; offset = bytes.LastIndex(tail[:offset], []byte("OggS")) {
+		if offset+oggPageHeaderSize > len(tail) || binary.LittleEndian.Uint32(tail[offset+14:]) != serial {
+			continue
+		}
+
+		// Pages without finished packets have granule position of -1
+		granule := binary.LittleEndian.Uint64(tail[offset+6:])
+		if granule != ^uint64(0) {
+			return granule, nil
+		}
+	}
+
+	return 0, ErrUnsupportedFormat
+}
@@ -29,14 +29,8 @@ func CreateI18NTranslationProvider() (TranslationProvider, error) {
 	bundle := i18n.NewBundle(DEFAULT_LANGUAGE)
 	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
 
-	locales := []string{
-		"locales/en.json",
-		"locales/be.json",
-		"locales/ru.json",
-	}
-
-	for _, locale := range locales {
-		_, err := bundle.LoadMessageFile(locale)
+	for _, locale := range SUPPORTED_LANGUAGES {
+		_, err := bundle.LoadMessageFile("locales/" + locale.String() + ".json")
 		if err != nil {
 			return nil, err
 		}
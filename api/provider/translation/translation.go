@@ -4,6 +4,13 @@ import "golang.org/x/text/language"
 
 var DEFAULT_LANGUAGE = language.English
 
+// Languages with message files in locales directory
+var SUPPORTED_LANGUAGES = []language.Tag{
+	language.English,
+	language.MustParse("be"),
+	language.Russian,
+}
+
 type TemplateData map[string]interface{}
 
 type TranslationProvider interface {
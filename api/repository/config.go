@@ -2,10 +2,35 @@ package repository
 
 type ConfigRepository interface {
 	GetValue(key string) (*string, error)
+	GetVenueValue(venueID int64, key string) (*string, error)
 }
 
+// Returns value of a global configuration variable
 func (repository *Repository) GetValue(key string) (*string, error) {
-	reader, err := repository.DBProvider.Query("SELECT value FROM config WHERE key = $1", key)
+	reader, err := repository.DBProvider.Query("SELECT value FROM config WHERE key = $1 AND venue_id IS NULL", key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := ""
+	found, err := reader.NextRow(&result)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// Returns value of a venue configuration variable or global value if venue doesn't override it
+func (repository *Repository) GetVenueValue(venueID int64, key string) (*string, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT value FROM config
+		WHERE key = $1
+		AND (venue_id = $2 OR venue_id IS NULL)
+		ORDER BY venue_id NULLS LAST
+		LIMIT 1`,
+		key, venueID)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,888 @@
+package repository
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryObject struct {
+	id           int64
+	venueID      int64
+	code         string
+	year         *int
+	covers       []Cover
+	translations map[string]ObjectTranslation
+	tracks       map[string][]AudioTrack
+}
+
+type memoryTour struct {
+	id           int64
+	venueID      int64
+	translations map[string]TourTranslation
+	objectIDs    []int64
+}
+
+func (object *memoryObject) toObject(translation ObjectTranslation) Object {
+	tracks := append([]AudioTrack{}, object.tracks[translation.Language]...)
+	sort.Slice(tracks, func(i, j int) bool {
+		return tracks[i].Index < tracks[j].Index
+	})
+
+	return Object{
+		ID:              object.id,
+		VenueID:         object.venueID,
+		Code:            object.code,
+		Title:           translation.Title,
+		Description:     translation.Description,
+		Author:          translation.Author,
+		Year:            object.year,
+		Location:        translation.Location,
+		Tags:            append([]string{}, translation.Tags...),
+		Covers:          append([]Cover{}, object.covers...),
+		Tracks:          tracks,
+		AudioPath:       translation.AudioPath,
+		AudioDurationMs: translation.AudioDurationMs,
+		TranscriptPath:  translation.TranscriptPath,
+	}
+}
+
+// Slices and maps are copied, their elements are replaced rather than modified on writes
+func (object *memoryObject) clone() *memoryObject {
+	result := *object
+	result.covers = append([]Cover{}, object.covers...)
+	result.translations = maps.Clone(object.translations)
+	result.tracks = maps.Clone(object.tracks)
+	return &result
+}
+
+// In-memory implementation of all repository interfaces for tests,
+// mirrors the behavior of the DB-backed Repository
+type MemoryRepository struct {
+	mutex           sync.Mutex
+	lastID          int64
+	tickets         map[string]*Ticket
+	ticketHolders   map[int64]map[int64]bool
+	ticketTypes     map[int64]TicketType
+	ticketTypesI18n map[int64]map[string]TicketTypeTranslation
+	venues          map[int64]Venue
+	objects         map[int64]*memoryObject
+	tours           map[int64]*memoryTour
+	config          map[string]string
+	venueConfig     map[int64]map[string]string
+	users           map[int64]User
+	refreshTokens   map[string]*RefreshToken
+	// Returned by SetObjectTracks if set, to test rollback of object writes
+	ObjectTracksError error
+}
+
+func (repository *MemoryRepository) CreateTicket(code string, ticketTypeID int64, purchase TicketPurchase) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticketType, found := repository.ticketTypes[ticketTypeID]
+	if !found {
+		return errors.New("ticket type not found")
+	}
+
+	repository.lastID++
+	repository.tickets[code] = &Ticket{ID: repository.lastID, Code: code, Type: ticketType, Purchase: purchase}
+	return nil
+}
+
+func (repository *MemoryRepository) GetTicket(code string) (*Ticket, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticket, found := repository.tickets[code]
+	if !found {
+		return nil, nil
+	}
+
+	result := *ticket
+	return &result, nil
+}
+
+func (repository *MemoryRepository) GetTicketByID(ticketID int64) (*Ticket, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, ticket := range repository.tickets {
+		if ticket.ID == ticketID {
+			result := *ticket
+			return &result, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (repository *MemoryRepository) GetUserTickets(userID int64, limit int) ([]Ticket, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []Ticket{}
+	for _, ticket := range repository.tickets {
+		if ticket.Purchase.BuyerUserID == userID {
+			result = append(result, *ticket)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) ActivateTicket(code string, activatedAt time.Time, holderUserID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticket, found := repository.tickets[code]
+	if !found || ticket.Activations >= ticket.Type.Activations || ticket.RevokedAt != nil {
+		return false, nil
+	}
+
+	ticket.Activations++
+	if ticket.ActivatedAt == nil {
+		ticket.ActivatedAt = &activatedAt
+	}
+
+	if holderUserID != 0 {
+		if repository.ticketHolders[ticket.ID] == nil {
+			repository.ticketHolders[ticket.ID] = map[int64]bool{}
+		}
+		repository.ticketHolders[ticket.ID][holderUserID] = true
+	}
+
+	return true, nil
+}
+
+func (repository *MemoryRepository) IsTicketHolder(ticketID int64, userID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return repository.ticketHolders[ticketID][userID], nil
+}
+
+func (repository *MemoryRepository) HasTicketHolders(ticketID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	return len(repository.ticketHolders[ticketID]) > 0, nil
+}
+
+func (repository *MemoryRepository) RevokeTicket(code string, revokedAt time.Time) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticket, found := repository.tickets[code]
+	if !found || ticket.RevokedAt != nil {
+		return false, nil
+	}
+
+	ticket.RevokedAt = &revokedAt
+	return true, nil
+}
+
+func (repository *MemoryRepository) RestoreTicket(code string, revokedAt time.Time) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticket, found := repository.tickets[code]
+	if !found || ticket.RevokedAt == nil || !ticket.RevokedAt.Equal(revokedAt) {
+		return false, nil
+	}
+
+	ticket.RevokedAt = nil
+	return true, nil
+}
+
+func (repository *MemoryRepository) IsTicketRevoked(ticketID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, ticket := range repository.tickets {
+		if ticket.ID == ticketID {
+			return ticket.RevokedAt != nil, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (repository *MemoryRepository) CreateRefreshToken(tokenHash string, token RefreshToken) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if _, found := repository.refreshTokens[tokenHash]; found {
+		return errors.New("refresh token already exists")
+	}
+
+	repository.lastID++
+	token.ID = repository.lastID
+	token.UsedAt = nil
+	token.RevokedAt = nil
+	repository.refreshTokens[tokenHash] = &token
+	return nil
+}
+
+func (repository *MemoryRepository) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	token, found := repository.refreshTokens[tokenHash]
+	if !found {
+		return nil, nil
+	}
+
+	result := *token
+	return &result, nil
+}
+
+func (repository *MemoryRepository) UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	token, found := repository.refreshTokens[tokenHash]
+	if !found || token.UsedAt != nil || token.RevokedAt != nil {
+		return nil, nil
+	}
+
+	token.UsedAt = &usedAt
+	result := *token
+	return &result, nil
+}
+
+func (repository *MemoryRepository) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, token := range repository.refreshTokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+
+	return nil
+}
+
+func (repository *MemoryRepository) GetTicketType(ticketTypeID int64) (*TicketType, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	ticketType, found := repository.ticketTypes[ticketTypeID]
+	if !found {
+		return nil, nil
+	}
+
+	return &ticketType, nil
+}
+
+func (repository *MemoryRepository) GetActiveTicketTypes(venueID int64) ([]TicketType, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []TicketType{}
+	for _, ticketType := range repository.ticketTypes {
+		if ticketType.VenueID == venueID && ticketType.Active {
+			result = append(result, ticketType)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Price != result[j].Price {
+			return result[i].Price < result[j].Price
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetTicketTypeTranslation(ticketTypeID int64, language string) (*TicketTypeTranslation, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	translation, found := repository.ticketTypesI18n[ticketTypeID][language]
+	if !found {
+		return nil, nil
+	}
+
+	return &translation, nil
+}
+
+// Sets a ticket type translation, there is no such operation in TicketTypeRepository
+func (repository *MemoryRepository) SetTicketTypeTranslation(ticketTypeID int64, language string, translation TicketTypeTranslation) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if repository.ticketTypesI18n[ticketTypeID] == nil {
+		repository.ticketTypesI18n[ticketTypeID] = map[string]TicketTypeTranslation{}
+	}
+	repository.ticketTypesI18n[ticketTypeID][language] = translation
+}
+
+// Adds a ticket type and returns its ID, there is no such operation in TicketTypeRepository
+func (repository *MemoryRepository) AddTicketType(ticketType TicketType) int64 {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.lastID++
+	ticketType.ID = repository.lastID
+	repository.ticketTypes[ticketType.ID] = ticketType
+
+	return ticketType.ID
+}
+
+func (repository *MemoryRepository) GetObject(code string, language string) (*Object, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object := repository.findObject(code)
+	if object == nil {
+		return nil, nil
+	}
+
+	translation, found := object.translations[language]
+	if !found {
+		return nil, nil
+	}
+
+	result := object.toObject(translation)
+	return &result, nil
+}
+
+func (repository *MemoryRepository) ListObjects(venueIDs []int64, options ListObjectsOptions) ([]Object, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []Object{}
+	for _, object := range repository.objects {
+		if !slices.Contains(venueIDs, object.venueID) || object.id <= options.AfterID {
+			continue
+		}
+
+		translation, found := object.translations[options.Language]
+		if !found {
+			translation, found = object.translations[options.Fallback]
+		}
+		if !found || !strings.Contains(strings.ToLower(translation.Title), strings.ToLower(options.Search)) {
+			continue
+		}
+
+		result = append(result, object.toObject(translation))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	if len(result) > options.Limit {
+		result = result[:options.Limit]
+	}
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetVenueObjects(venueID int64) ([]Object, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []Object{}
+	for _, object := range repository.objects {
+		if object.venueID != venueID {
+			continue
+		}
+
+		result = append(result, Object{
+			ID:      object.id,
+			VenueID: object.venueID,
+			Code:    object.code,
+			Year:    object.year,
+			Covers:  append([]Cover{}, object.covers...),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetObjectID(code string) (*int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object := repository.findObject(code)
+	if object == nil {
+		return nil, nil
+	}
+
+	result := object.id
+	return &result, nil
+}
+
+func (repository *MemoryRepository) CreateObject(code string, venueID int64) (int64, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.lastID++
+	repository.objects[repository.lastID] = &memoryObject{
+		id:           repository.lastID,
+		venueID:      venueID,
+		code:         code,
+		covers:       []Cover{},
+		translations: map[string]ObjectTranslation{},
+		tracks:       map[string][]AudioTrack{},
+	}
+
+	return repository.lastID, nil
+}
+
+func (repository *MemoryRepository) SetObjectYear(objectID int64, year *int) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object, found := repository.objects[objectID]
+	if !found {
+		return nil
+	}
+
+	object.year = year
+	return nil
+}
+
+func (repository *MemoryRepository) DeleteObject(objectID int64) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	delete(repository.objects, objectID)
+	for _, tour := range repository.tours {
+		objectIDs := []int64{}
+		for _, id := range tour.objectIDs {
+			if id != objectID {
+				objectIDs = append(objectIDs, id)
+			}
+		}
+		tour.objectIDs = objectIDs
+	}
+
+	return nil
+}
+
+// Objects are restored from a copy if the callback fails, other writes are not reverted
+func (repository *MemoryRepository) ObjectTransaction(callback func(tx ObjectRepository) error) error {
+	repository.mutex.Lock()
+	objects := map[int64]*memoryObject{}
+	for id, object := range repository.objects {
+		objects[id] = object.clone()
+	}
+	repository.mutex.Unlock()
+
+	err := callback(repository)
+	if err != nil {
+		repository.mutex.Lock()
+		repository.objects = objects
+		repository.mutex.Unlock()
+	}
+
+	return err
+}
+
+func (repository *MemoryRepository) SetObjectCovers(objectID int64, covers []Cover) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object, found := repository.objects[objectID]
+	if !found {
+		return nil
+	}
+
+	object.covers = append([]Cover{}, covers...)
+	return nil
+}
+
+func (repository *MemoryRepository) SetObjectTracks(objectID int64, language string, tracks []AudioTrack) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if repository.ObjectTracksError != nil {
+		return repository.ObjectTracksError
+	}
+
+	object, found := repository.objects[objectID]
+	if !found {
+		return nil
+	}
+
+	object.tracks[language] = append([]AudioTrack{}, tracks...)
+	return nil
+}
+
+func (repository *MemoryRepository) GetObjectTranslations(objectID int64) ([]ObjectTranslation, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []ObjectTranslation{}
+	object, found := repository.objects[objectID]
+	if !found {
+		return result, nil
+	}
+
+	for _, translation := range object.translations {
+		result = append(result, translation)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Language < result[j].Language
+	})
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) SetObjectTranslation(objectID int64, translation ObjectTranslation) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object, found := repository.objects[objectID]
+	if !found {
+		return nil
+	}
+
+	translation.Tags = append([]string{}, translation.Tags...)
+	object.translations[translation.Language] = translation
+	return nil
+}
+
+func (repository *MemoryRepository) DeleteObjectTranslation(objectID int64, language string) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	object, found := repository.objects[objectID]
+	if !found {
+		return false, nil
+	}
+
+	if _, found := object.translations[language]; !found {
+		return false, nil
+	}
+
+	delete(object.translations, language)
+	delete(object.tracks, language)
+	return true, nil
+}
+
+func (repository *MemoryRepository) GetObjectBlobs(objectID int64) ([]string, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []string{}
+	object, found := repository.objects[objectID]
+	if !found {
+		return result, nil
+	}
+
+	for _, cover := range object.covers {
+		result = append(result, cover.Path)
+	}
+
+	for _, translation := range object.translations {
+		result = append(result, translation.AudioPath)
+		if translation.TranscriptPath != "" {
+			result = append(result, translation.TranscriptPath)
+		}
+	}
+
+	for _, tracks := range object.tracks {
+		for _, track := range tracks {
+			result = append(result, track.AudioPath)
+		}
+	}
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetValue(key string) (*string, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	value, found := repository.config[key]
+	if !found {
+		return nil, nil
+	}
+
+	return &value, nil
+}
+
+func (repository *MemoryRepository) GetVenueValue(venueID int64, key string) (*string, error) {
+	repository.mutex.Lock()
+	value, found := repository.venueConfig[venueID][key]
+	repository.mutex.Unlock()
+
+	if !found {
+		return repository.GetValue(key)
+	}
+
+	return &value, nil
+}
+
+// Sets a global configuration variable, there is no such operation in ConfigRepository
+func (repository *MemoryRepository) SetValue(key string, value string) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.config[key] = value
+}
+
+// Sets a venue configuration variable, there is no such operation in ConfigRepository
+func (repository *MemoryRepository) SetVenueValue(venueID int64, key string, value string) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	if repository.venueConfig[venueID] == nil {
+		repository.venueConfig[venueID] = map[string]string{}
+	}
+	repository.venueConfig[venueID][key] = value
+}
+
+func (repository *MemoryRepository) GetUser(userID int64) (*User, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, found := repository.users[userID]
+	if !found {
+		return nil, nil
+	}
+
+	return &user, nil
+}
+
+func (repository *MemoryRepository) TrackUser(userID int64, languageCode string, seenAt time.Time) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, found := repository.users[userID]
+	if !found {
+		user = User{ID: userID, FirstSeenAt: seenAt}
+	}
+
+	user.LanguageCode = languageCode
+	user.LastSeenAt = seenAt
+	repository.users[userID] = user
+	return nil
+}
+
+func (repository *MemoryRepository) SetUserLanguage(userID int64, language string) error {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	user, found := repository.users[userID]
+	if !found {
+		user = User{ID: userID, FirstSeenAt: time.Now(), LastSeenAt: time.Now()}
+	}
+
+	user.Language = language
+	repository.users[userID] = user
+	return nil
+}
+
+func (repository *MemoryRepository) GetVenue(venueID int64) (*Venue, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	venue, found := repository.venues[venueID]
+	if !found {
+		return nil, nil
+	}
+
+	return &venue, nil
+}
+
+func (repository *MemoryRepository) GetVenueByCode(code string) (*Venue, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, venue := range repository.venues {
+		if venue.Code == code {
+			return &venue, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (repository *MemoryRepository) GetVenues() ([]Venue, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []Venue{}
+	for _, venue := range repository.venues {
+		result = append(result, venue)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetDefaultVenue() (*Venue, error) {
+	venues, err := repository.GetVenues()
+	if err != nil || len(venues) == 0 {
+		return nil, err
+	}
+
+	return &venues[0], nil
+}
+
+// Adds a venue and returns its ID, there is no such operation in VenueRepository
+func (repository *MemoryRepository) AddVenue(venue Venue) int64 {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.lastID++
+	venue.ID = repository.lastID
+	repository.venues[venue.ID] = venue
+
+	return venue.ID
+}
+
+func (repository *MemoryRepository) GetTours(venueIDs []int64, tourIDs []int64, language string, fallback string) ([]Tour, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	result := []Tour{}
+	for _, tour := range repository.tours {
+		if !slices.Contains(venueIDs, tour.venueID) && !slices.Contains(tourIDs, tour.id) {
+			continue
+		}
+
+		if translated := repository.translateTour(tour, language, fallback); translated != nil {
+			result = append(result, *translated)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+func (repository *MemoryRepository) GetTour(tourID int64, language string, fallback string) (*Tour, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	tour, found := repository.tours[tourID]
+	if !found {
+		return nil, nil
+	}
+
+	return repository.translateTour(tour, language, fallback), nil
+}
+
+func (repository *MemoryRepository) IsTourStop(tourIDs []int64, objectID int64) (bool, error) {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	for _, tourID := range tourIDs {
+		tour, found := repository.tours[tourID]
+		if found && slices.Contains(tour.objectIDs, objectID) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Adds a tour with stops in the given order and returns its ID, there is no such operation in TourRepository
+func (repository *MemoryRepository) AddTour(venueID int64, translations map[string]TourTranslation, objectIDs []int64) int64 {
+	repository.mutex.Lock()
+	defer repository.mutex.Unlock()
+
+	repository.lastID++
+	repository.tours[repository.lastID] = &memoryTour{
+		id:           repository.lastID,
+		venueID:      venueID,
+		translations: translations,
+		objectIDs:    append([]int64{}, objectIDs...),
+	}
+
+	return repository.lastID
+}
+
+func (repository *MemoryRepository) translateTour(tour *memoryTour, language string, fallback string) *Tour {
+	translation, found := tour.translations[language]
+	if !found {
+		translation, found = tour.translations[fallback]
+	}
+	if !found {
+		return nil
+	}
+
+	result := Tour{
+		ID:          tour.id,
+		VenueID:     tour.venueID,
+		Title:       translation.Title,
+		Description: translation.Description,
+		Stops:       []TourStop{},
+	}
+
+	for _, objectID := range tour.objectIDs {
+		object, found := repository.objects[objectID]
+		if !found {
+			continue
+		}
+
+		objectTranslation, found := object.translations[language]
+		if !found {
+			objectTranslation, found = object.translations[fallback]
+		}
+		if !found {
+			continue
+		}
+
+		result.Stops = append(result.Stops, TourStop{
+			Code:   object.code,
+			Title:  objectTranslation.Title,
+			Covers: append([]Cover{}, object.covers...),
+		})
+	}
+
+	return &result
+}
+
+func (repository *MemoryRepository) findObject(code string) *memoryObject {
+	for _, object := range repository.objects {
+		if object.code == code {
+			return object
+		}
+	}
+
+	return nil
+}
+
+func CreateMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		tickets:         map[string]*Ticket{},
+		ticketHolders:   map[int64]map[int64]bool{},
+		ticketTypes:     map[int64]TicketType{},
+		ticketTypesI18n: map[int64]map[string]TicketTypeTranslation{},
+		venues:          map[int64]Venue{},
+		objects:         map[int64]*memoryObject{},
+		tours:           map[int64]*memoryTour{},
+		config:          map[string]string{},
+		venueConfig:     map[int64]map[string]string{},
+		users:           map[int64]User{},
+		refreshTokens:   map[string]*RefreshToken{},
+	}
+}
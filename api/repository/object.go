@@ -1,25 +1,95 @@
 package repository
 
+import (
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/st-matskevich/audio-guide-bot/api/provider/db"
+)
+
 type Cover struct {
 	Index int    `json:"index"`
 	Path  string `json:"-"`
 }
 
+// Additional named audio track of object translation, e.g. "kids" version
+type AudioTrack struct {
+	Name       string `json:"name"`
+	Index      int    `json:"index"`
+	DurationMs int64  `json:"duration_ms"`
+	AudioPath  string `json:"-"`
+}
+
 type Object struct {
-	ID        int64   `json:"-"`
-	Code      string  `json:"-"`
-	Title     string  `json:"title"`
-	Covers    []Cover `json:"covers"`
-	AudioPath string  `json:"-"`
+	ID          int64        `json:"-"`
+	VenueID     int64        `json:"-"`
+	Code        string       `json:"-"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Author      string       `json:"author"`
+	Year        *int         `json:"year"`
+	Location    string       `json:"location"`
+	Tags        []string     `json:"tags"`
+	Covers      []Cover      `json:"covers"`
+	Tracks      []AudioTrack `json:"tracks"`
+	AudioPath   string       `json:"-"`
+	// Zero if audio was uploaded before metadata extraction
+	AudioDurationMs int64 `json:"audio_duration_ms"`
+	// Empty if object has no transcript
+	TranscriptPath string `json:"-"`
+}
+
+type ObjectTranslation struct {
+	Language    string
+	Title       string
+	Description string
+	Author      string
+	Location    string
+	Tags        []string
+	AudioPath   string
+	// Metadata parsed from audio headers on upload
+	AudioDurationMs int64
+	AudioBitrate    int
+	AudioSampleRate int
+	AudioMIMEType   string
+	// Either plain text or WebVTT file, empty if translation has no transcript
+	TranscriptPath string
+}
+
+type ListObjectsOptions struct {
+	// Translation to fallback language is used if requested one is missing
+	Language string
+	Fallback string
+	// Case-insensitive substring of the translated title, ignored if empty
+	Search string
+	// Only objects with ID greater than AfterID are returned
+	AfterID int64
+	Limit   int
 }
 
 type ObjectRepository interface {
 	GetObject(code string, language string) (*Object, error)
+	// Lists objects of all the venues together
+	ListObjects(venueIDs []int64, options ListObjectsOptions) ([]Object, error)
+	GetVenueObjects(venueID int64) ([]Object, error)
+	GetObjectID(code string) (*int64, error)
+	CreateObject(code string, venueID int64) (int64, error)
+	SetObjectYear(objectID int64, year *int) error
+	DeleteObject(objectID int64) error
+	SetObjectCovers(objectID int64, covers []Cover) error
+	SetObjectTracks(objectID int64, language string, tracks []AudioTrack) error
+	GetObjectTranslations(objectID int64) ([]ObjectTranslation, error)
+	SetObjectTranslation(objectID int64, translation ObjectTranslation) error
+	DeleteObjectTranslation(objectID int64, language string) (bool, error)
+	GetObjectBlobs(objectID int64) ([]string, error)
+	// Runs the callback with a repository bound to a transaction, that is committed if the callback succeeds
+	ObjectTransaction(callback func(tx ObjectRepository) error) error
 }
 
 func (repository *Repository) GetObject(code string, language string) (*Object, error) {
 	reader, err := repository.DBProvider.Query(
-		`SELECT objects.object_id, objects_i18n.title, objects_i18n.audio_path FROM objects
+		`SELECT objects.object_id, objects.venue_id, objects.year,
+		objects_i18n.title, objects_i18n.description, objects_i18n.author, objects_i18n.location, objects_i18n.audio_path, objects_i18n.audio_duration_ms, objects_i18n.transcript_path FROM objects
 		JOIN objects_i18n ON objects.object_id = objects_i18n.object_id
 		WHERE objects.code = $1
 		AND objects_i18n.language = $2`,
@@ -31,18 +101,375 @@ func (repository *Repository) GetObject(code string, language string) (*Object,
 	defer reader.Close()
 
 	result := Object{}
-	found, err := reader.NextRow(&result.ID, &result.Title, &result.AudioPath)
+	found, err := reader.NextRow(&result.ID, &result.VenueID, &result.Year,
+		&result.Title, &result.Description, &result.Author, &result.Location, &result.AudioPath, &result.AudioDurationMs, &result.TranscriptPath)
 	if err != nil || !found {
 		return nil, err
 	}
 
-	reader, err = repository.DBProvider.Query("SELECT index, path FROM covers WHERE object_id = $1", result.ID)
+	result.Tags, err = repository.getObjectTags(result.ID, language)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Tracks, err = repository.getObjectTracks(result.ID, language)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Covers, err = repository.getObjectCovers(result.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Code = code
+	return &result, nil
+}
+
+// Returns objects ordered by ID
+func (repository *Repository) ListObjects(venueIDs []int64, options ListObjectsOptions) ([]Object, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT objects.object_id, objects.venue_id, objects.code, objects.year, COALESCE(requested.language, fallback.language),
+		COALESCE(requested.title, fallback.title), COALESCE(requested.description, fallback.description),
+		COALESCE(requested.author, fallback.author), COALESCE(requested.location, fallback.location),
+		COALESCE(requested.audio_path, fallback.audio_path), COALESCE(requested.audio_duration_ms, fallback.audio_duration_ms),
+		COALESCE(requested.transcript_path, fallback.transcript_path) FROM objects
+		LEFT JOIN objects_i18n requested ON requested.object_id = objects.object_id AND requested.language = $2
+		LEFT JOIN objects_i18n fallback ON fallback.object_id = objects.object_id AND fallback.language = $3
+		WHERE objects.venue_id = ANY($1)
+		AND objects.object_id > $4
+		AND (requested.object_id IS NOT NULL OR fallback.object_id IS NOT NULL)
+		AND COALESCE(requested.title, fallback.title) ILIKE '%' || $5 || '%'
+		ORDER BY objects.object_id
+		LIMIT $6`,
+		pq.Array(venueIDs), options.Language, options.Fallback, options.AfterID, escapeLikePattern(options.Search), options.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer reader.Close()
 
-	result.Covers = []Cover{}
+	result := []Object{}
+	languages := []string{}
+	for {
+		language := ""
+		row := Object{}
+		ok, err := reader.NextRow(&row.ID, &row.VenueID, &row.Code, &row.Year, &language,
+			&row.Title, &row.Description, &row.Author, &row.Location, &row.AudioPath, &row.AudioDurationMs, &row.TranscriptPath)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		languages = append(languages, language)
+		result = append(result, row)
+	}
+
+	for i := range result {
+		result[i].Tags, err = repository.getObjectTags(result[i].ID, languages[i])
+		if err != nil {
+			return nil, err
+		}
+
+		result[i].Tracks, err = repository.getObjectTracks(result[i].ID, languages[i])
+		if err != nil {
+			return nil, err
+		}
+
+		result[i].Covers, err = repository.getObjectCovers(result[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// Returns all objects of the venue ordered by ID, only code, year and covers are set
+func (repository *Repository) GetVenueObjects(venueID int64) ([]Object, error) {
+	reader, err := repository.DBProvider.Query("SELECT object_id, code, year FROM objects WHERE venue_id = $1 ORDER BY object_id", venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []Object{}
+	for {
+		row := Object{VenueID: venueID}
+		ok, err := reader.NextRow(&row.ID, &row.Code, &row.Year)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	for i := range result {
+		result[i].Covers, err = repository.getObjectCovers(result[i].ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetObjectID(code string) (*int64, error) {
+	reader, err := repository.DBProvider.Query("SELECT object_id FROM objects WHERE code = $1", code)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := int64(0)
+	found, err := reader.NextRow(&result)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *Repository) CreateObject(code string, venueID int64) (int64, error) {
+	reader, err := repository.DBProvider.Query("INSERT INTO objects(code, venue_id) VALUES ($1, $2) RETURNING object_id", code, venueID)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	result := int64(0)
+	err = reader.GetRow(&result)
+	if err != nil {
+		return 0, err
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) SetObjectYear(objectID int64, year *int) error {
+	_, err := repository.DBProvider.Exec("UPDATE objects SET year = $2 WHERE object_id = $1", objectID, year)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *Repository) DeleteObject(objectID int64) error {
+	return repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		_, err := tx.Exec("DELETE FROM covers WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM objects_tags WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM audio_tracks WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM objects_i18n WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM tour_stops WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM objects WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) ObjectTransaction(callback func(tx ObjectRepository) error) error {
+	return repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		return callback(&Repository{DBProvider: tx})
+	})
+}
+
+func (repository *Repository) SetObjectCovers(objectID int64, covers []Cover) error {
+	return repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		_, err := tx.Exec("DELETE FROM covers WHERE object_id = $1", objectID)
+		if err != nil {
+			return err
+		}
+
+		for _, cover := range covers {
+			_, err := tx.Exec("INSERT INTO covers(object_id, index, path) VALUES ($1, $2, $3)", objectID, cover.Index, cover.Path)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) SetObjectTracks(objectID int64, language string, tracks []AudioTrack) error {
+	return repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		_, err := tx.Exec("DELETE FROM audio_tracks WHERE object_id = $1 AND language = $2", objectID, language)
+		if err != nil {
+			return err
+		}
+
+		for _, track := range tracks {
+			_, err := tx.Exec(
+				"INSERT INTO audio_tracks(object_id, language, name, index, duration_ms, audio_path) VALUES ($1, $2, $3, $4, $5, $6)",
+				objectID, language, track.Name, track.Index, track.DurationMs, track.AudioPath)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) GetObjectTranslations(objectID int64) ([]ObjectTranslation, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT language, title, description, author, location, audio_path,
+		audio_duration_ms, audio_bitrate, audio_sample_rate, audio_mime_type, transcript_path FROM objects_i18n WHERE object_id = $1`,
+		objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []ObjectTranslation{}
+	row := ObjectTranslation{}
+	for {
+		ok, err := reader.NextRow(&row.Language, &row.Title, &row.Description, &row.Author, &row.Location, &row.AudioPath,
+			&row.AudioDurationMs, &row.AudioBitrate, &row.AudioSampleRate, &row.AudioMIMEType, &row.TranscriptPath)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	for i := range result {
+		result[i].Tags, err = repository.getObjectTags(objectID, result[i].Language)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) SetObjectTranslation(objectID int64, translation ObjectTranslation) error {
+	return repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		_, err := tx.Exec(
+			`INSERT INTO objects_i18n(object_id, language, title, description, author, location, audio_path,
+			audio_duration_ms, audio_bitrate, audio_sample_rate, audio_mime_type, transcript_path)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			ON CONFLICT (object_id, language) DO UPDATE SET title = EXCLUDED.title, description = EXCLUDED.description,
+			author = EXCLUDED.author, location = EXCLUDED.location, audio_path = EXCLUDED.audio_path,
+			audio_duration_ms = EXCLUDED.audio_duration_ms, audio_bitrate = EXCLUDED.audio_bitrate,
+			audio_sample_rate = EXCLUDED.audio_sample_rate, audio_mime_type = EXCLUDED.audio_mime_type, transcript_path = EXCLUDED.transcript_path`,
+			objectID, translation.Language, translation.Title, translation.Description, translation.Author, translation.Location,
+			translation.AudioPath, translation.AudioDurationMs, translation.AudioBitrate, translation.AudioSampleRate, translation.AudioMIMEType,
+			translation.TranscriptPath)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM objects_tags WHERE object_id = $1 AND language = $2", objectID, translation.Language)
+		if err != nil {
+			return err
+		}
+
+		for _, tag := range translation.Tags {
+			_, err := tx.Exec(
+				"INSERT INTO objects_tags(object_id, language, tag) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+				objectID, translation.Language, tag)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repository *Repository) DeleteObjectTranslation(objectID int64, language string) (bool, error) {
+	deleted := int64(0)
+	err := repository.DBProvider.Transaction(func(tx db.DBProvider) error {
+		_, err := tx.Exec("DELETE FROM objects_tags WHERE object_id = $1 AND language = $2", objectID, language)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM audio_tracks WHERE object_id = $1 AND language = $2", objectID, language)
+		if err != nil {
+			return err
+		}
+
+		deleted, err = tx.Exec("DELETE FROM objects_i18n WHERE object_id = $1 AND language = $2", objectID, language)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted == 1, nil
+}
+
+// Returns paths of all blobs referenced by the object: covers, audio, transcripts and tracks
+func (repository *Repository) GetObjectBlobs(objectID int64) ([]string, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT path FROM covers WHERE object_id = $1
+		UNION SELECT audio_path FROM objects_i18n WHERE object_id = $1
+		UNION SELECT transcript_path FROM objects_i18n WHERE object_id = $1 AND transcript_path <> ''
+		UNION SELECT audio_path FROM audio_tracks WHERE object_id = $1`,
+		objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []string{}
+	path := ""
+	for {
+		ok, err := reader.NextRow(&path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, path)
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) getObjectCovers(objectID int64) ([]Cover, error) {
+	reader, err := repository.DBProvider.Query("SELECT index, path FROM covers WHERE object_id = $1 ORDER BY index", objectID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []Cover{}
 	row := Cover{}
 	for {
 		ok, err := reader.NextRow(&row.Index, &row.Path)
@@ -53,9 +480,65 @@ func (repository *Repository) GetObject(code string, language string) (*Object,
 			break
 		}
 
-		result.Covers = append(result.Covers, row)
+		result = append(result, row)
 	}
 
-	result.Code = code
-	return &result, nil
+	return result, nil
+}
+
+func (repository *Repository) getObjectTags(objectID int64, language string) ([]string, error) {
+	reader, err := repository.DBProvider.Query(
+		"SELECT tag FROM objects_tags WHERE object_id = $1 AND language = $2 ORDER BY tag_id",
+		objectID, language)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []string{}
+	for {
+		tag := ""
+		ok, err := reader.NextRow(&tag)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, tag)
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) getObjectTracks(objectID int64, language string) ([]AudioTrack, error) {
+	reader, err := repository.DBProvider.Query(
+		"SELECT name, index, duration_ms, audio_path FROM audio_tracks WHERE object_id = $1 AND language = $2 ORDER BY index",
+		objectID, language)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []AudioTrack{}
+	row := AudioTrack{}
+	for {
+		ok, err := reader.NextRow(&row.Name, &row.Index, &row.DurationMs, &row.AudioPath)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+// Escapes LIKE wildcards so the pattern matches literally
+func escapeLikePattern(pattern string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(pattern)
 }
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/st-matskevich/audio-guide-bot/api/provider/db"
+)
+
+// Refresh token issued with an access token, tokens replacing each other on refresh share the family
+type RefreshToken struct {
+	ID        int64
+	FamilyID  string
+	TicketID  int64
+	UserID    int64
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// Tokens are looked up by hash, so the stored values can't be used as refresh tokens
+type RefreshTokenRepository interface {
+	CreateRefreshToken(tokenHash string, token RefreshToken) error
+	GetRefreshToken(tokenHash string) (*RefreshToken, error)
+	UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error)
+	RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error
+}
+
+// Columns of refresh tokens, read with readRefreshToken
+const REFRESH_TOKEN_COLUMNS = "refresh_token_id, family_id, ticket_id, user_id, created_at, expires_at, used_at, revoked_at"
+
+func readRefreshToken(reader db.DBReader) (*RefreshToken, error) {
+	result := RefreshToken{}
+	found, err := reader.NextRow(&result.ID, &result.FamilyID, &result.TicketID, &result.UserID,
+		&result.CreatedAt, &result.ExpiresAt, &result.UsedAt, &result.RevokedAt)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *Repository) CreateRefreshToken(tokenHash string, token RefreshToken) error {
+	_, err := repository.DBProvider.Exec(
+		`INSERT INTO refresh_tokens(token_hash, family_id, ticket_id, user_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		tokenHash, token.FamilyID, token.TicketID, token.UserID, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (repository *Repository) GetRefreshToken(tokenHash string) (*RefreshToken, error) {
+	reader, err := repository.DBProvider.Query("SELECT "+REFRESH_TOKEN_COLUMNS+" FROM refresh_tokens WHERE token_hash = $1", tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readRefreshToken(reader)
+}
+
+// Marks the token used, returns nil if it's not found, already used or revoked,
+// so concurrent refreshes with the same token can't both succeed
+func (repository *Repository) UseRefreshToken(tokenHash string, usedAt time.Time) (*RefreshToken, error) {
+	reader, err := repository.DBProvider.Query(
+		`UPDATE refresh_tokens SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND revoked_at IS NULL
+		RETURNING `+REFRESH_TOKEN_COLUMNS,
+		tokenHash, usedAt)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return readRefreshToken(reader)
+}
+
+func (repository *Repository) RevokeRefreshTokenFamily(familyID string, revokedAt time.Time) error {
+	_, err := repository.DBProvider.Exec(
+		"UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL",
+		familyID, revokedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
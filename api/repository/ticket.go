@@ -1,19 +1,77 @@
 package repository
 
+import (
+	"time"
+
+	"github.com/st-matskevich/audio-guide-bot/api/provider/db"
+)
+
+// Telegram payment the ticket was bought with, empty for tickets bought before purchases were tracked
+type TicketPurchase struct {
+	BuyerUserID      int64
+	ChatID           int64
+	TelegramChargeID string
+	ProviderChargeID string
+	Amount           int64
+	Currency         string
+	PurchasedAt      *time.Time
+}
+
 type Ticket struct {
-	ID   int64
-	Code string
-	Used bool
+	ID          int64
+	Code        string
+	Type        TicketType
+	Activations int
+	ActivatedAt *time.Time
+	RevokedAt   *time.Time
+	Purchase    TicketPurchase
 }
 
 type TicketRepository interface {
-	CreateTicket(code string) error
+	CreateTicket(code string, ticketTypeID int64, purchase TicketPurchase) error
 	GetTicket(code string) (*Ticket, error)
-	ActivateTicket(code string) (bool, error)
+	GetTicketByID(ticketID int64) (*Ticket, error)
+	GetUserTickets(userID int64, limit int) ([]Ticket, error)
+	ActivateTicket(code string, activatedAt time.Time, holderUserID int64) (bool, error)
+	IsTicketHolder(ticketID int64, userID int64) (bool, error)
+	// Returns true if the ticket is bound to any Telegram user
+	HasTicketHolders(ticketID int64) (bool, error)
+	RevokeTicket(code string, revokedAt time.Time) (bool, error)
+	// Cancels the revocation made at revokedAt, returns false if the ticket was not revoked at that time
+	RestoreTicket(code string, revokedAt time.Time) (bool, error)
+	IsTicketRevoked(ticketID int64) (bool, error)
+}
+
+// Columns of tickets joined with ticket types, read with readTicket
+const TICKET_COLUMNS = `tickets.ticket_id, tickets.code, tickets.activations, tickets.activated_at, tickets.revoked_at,
+	COALESCE(tickets.buyer_user_id, 0), COALESCE(tickets.chat_id, 0),
+	COALESCE(tickets.telegram_payment_charge_id, ''), COALESCE(tickets.provider_payment_charge_id, ''),
+	COALESCE(tickets.amount, 0), COALESCE(tickets.currency, ''), tickets.purchased_at,
+	ticket_types.ticket_type_id, ticket_types.venue_id, ticket_types.name, ticket_types.price, ticket_types.currency,
+	ticket_types.activations, ticket_types.validity_days, ticket_types.time_zone, ticket_types.active`
+
+func readTicket(reader db.DBReader) (*Ticket, error) {
+	result := Ticket{}
+	purchase := &result.Purchase
+	ticketType := &result.Type
+	found, err := reader.NextRow(&result.ID, &result.Code, &result.Activations, &result.ActivatedAt, &result.RevokedAt,
+		&purchase.BuyerUserID, &purchase.ChatID, &purchase.TelegramChargeID, &purchase.ProviderChargeID,
+		&purchase.Amount, &purchase.Currency, &purchase.PurchasedAt,
+		&ticketType.ID, &ticketType.VenueID, &ticketType.Name, &ticketType.Price, &ticketType.Currency,
+		&ticketType.Activations, &ticketType.ValidityDays, &ticketType.TimeZone, &ticketType.Active)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
 }
 
-func (repository *Repository) CreateTicket(code string) error {
-	_, err := repository.DBProvider.Exec("INSERT INTO tickets(code) VALUES ($1)", code)
+func (repository *Repository) CreateTicket(code string, ticketTypeID int64, purchase TicketPurchase) error {
+	_, err := repository.DBProvider.Exec(
+		`INSERT INTO tickets(code, ticket_type_id, buyer_user_id, chat_id, telegram_payment_charge_id, provider_payment_charge_id, amount, currency, purchased_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		code, ticketTypeID, purchase.BuyerUserID, purchase.ChatID, purchase.TelegramChargeID, purchase.ProviderChargeID,
+		purchase.Amount, purchase.Currency, purchase.PurchasedAt)
 	if err != nil {
 		return err
 	}
@@ -22,27 +80,164 @@ func (repository *Repository) CreateTicket(code string) error {
 }
 
 func (repository *Repository) GetTicket(code string) (*Ticket, error) {
-	reader, err := repository.DBProvider.Query("SELECT ticket_id, used FROM tickets WHERE code = $1", code)
+	reader, err := repository.DBProvider.Query(
+		`SELECT `+TICKET_COLUMNS+` FROM tickets
+		JOIN ticket_types ON tickets.ticket_type_id = ticket_types.ticket_type_id
+		WHERE tickets.code = $1`,
+		code)
 	if err != nil {
 		return nil, err
 	}
 	defer reader.Close()
 
-	result := Ticket{}
-	found, err := reader.NextRow(&result.ID, &result.Used)
-	if err != nil || !found {
+	return readTicket(reader)
+}
+
+func (repository *Repository) GetTicketByID(ticketID int64) (*Ticket, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT `+TICKET_COLUMNS+` FROM tickets
+		JOIN ticket_types ON tickets.ticket_type_id = ticket_types.ticket_type_id
+		WHERE tickets.ticket_id = $1`,
+		ticketID)
+	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
 
-	result.Code = code
-	return &result, nil
+	return readTicket(reader)
 }
 
-func (repository *Repository) ActivateTicket(code string) (bool, error) {
-	updated, err := repository.DBProvider.Exec("UPDATE tickets SET used = true WHERE code = $1 AND used = false", code)
+// Returns tickets bought by the user, newest first
+func (repository *Repository) GetUserTickets(userID int64, limit int) ([]Ticket, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT `+TICKET_COLUMNS+` FROM tickets
+		JOIN ticket_types ON tickets.ticket_type_id = ticket_types.ticket_type_id
+		WHERE tickets.buyer_user_id = $1
+		ORDER BY tickets.ticket_id DESC
+		LIMIT $2`,
+		userID, limit)
 	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []Ticket{}
+	for {
+		ticket, err := readTicket(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if ticket == nil {
+			break
+		}
+
+		result = append(result, *ticket)
+	}
+
+	return result, nil
+}
+
+// Increments ticket activations if the ticket type limit is not reached and the ticket is not revoked,
+// activation time is saved only for the first activation, non-zero holder user is bound to the ticket
+func (repository *Repository) ActivateTicket(code string, activatedAt time.Time, holderUserID int64) (bool, error) {
+	reader, err := repository.DBProvider.Query(
+		`WITH activated AS (
+			UPDATE tickets SET activations = tickets.activations + 1, activated_at = COALESCE(tickets.activated_at, $2)
+			FROM ticket_types
+			WHERE tickets.ticket_type_id = ticket_types.ticket_type_id
+			AND tickets.code = $1
+			AND tickets.activations < ticket_types.activations
+			AND tickets.revoked_at IS NULL
+			RETURNING tickets.ticket_id
+		), holders AS (
+			INSERT INTO ticket_holders(ticket_id, user_id, activated_at)
+			SELECT ticket_id, $3::BIGINT, $2 FROM activated WHERE $3::BIGINT <> 0
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM activated`,
+		code, activatedAt, holderUserID)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	updated := int64(0)
+	if err := reader.GetRow(&updated); err != nil {
 		return false, err
 	}
 
 	return updated == 1, nil
 }
+
+func (repository *Repository) IsTicketHolder(ticketID int64, userID int64) (bool, error) {
+	reader, err := repository.DBProvider.Query(
+		"SELECT EXISTS (SELECT 1 FROM ticket_holders WHERE ticket_id = $1 AND user_id = $2)",
+		ticketID, userID)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	holder := false
+	if err := reader.GetRow(&holder); err != nil {
+		return false, err
+	}
+
+	return holder, nil
+}
+
+func (repository *Repository) HasTicketHolders(ticketID int64) (bool, error) {
+	reader, err := repository.DBProvider.Query(
+		"SELECT EXISTS (SELECT 1 FROM ticket_holders WHERE ticket_id = $1)",
+		ticketID)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	holders := false
+	if err := reader.GetRow(&holders); err != nil {
+		return false, err
+	}
+
+	return holders, nil
+}
+
+// Marks the ticket revoked, returns false if it's not found or already revoked
+func (repository *Repository) RevokeTicket(code string, revokedAt time.Time) (bool, error) {
+	updated, err := repository.DBProvider.Exec(
+		"UPDATE tickets SET revoked_at = $2 WHERE code = $1 AND revoked_at IS NULL",
+		code, revokedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return updated == 1, nil
+}
+
+func (repository *Repository) RestoreTicket(code string, revokedAt time.Time) (bool, error) {
+	updated, err := repository.DBProvider.Exec(
+		"UPDATE tickets SET revoked_at = NULL WHERE code = $1 AND revoked_at = $2",
+		code, revokedAt)
+	if err != nil {
+		return false, err
+	}
+
+	return updated == 1, nil
+}
+
+func (repository *Repository) IsTicketRevoked(ticketID int64) (bool, error) {
+	reader, err := repository.DBProvider.Query("SELECT revoked_at IS NOT NULL FROM tickets WHERE ticket_id = $1", ticketID)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	revoked := false
+	if _, err := reader.NextRow(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
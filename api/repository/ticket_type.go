@@ -0,0 +1,93 @@
+package repository
+
+type TicketType struct {
+	ID           int64
+	VenueID      int64
+	Name         string
+	Price        int64
+	Currency     string
+	Activations  int
+	ValidityDays int
+	TimeZone     string
+	Active       bool
+}
+
+type TicketTypeTranslation struct {
+	Title       string
+	Description string
+}
+
+type TicketTypeRepository interface {
+	GetTicketType(ticketTypeID int64) (*TicketType, error)
+	GetActiveTicketTypes(venueID int64) ([]TicketType, error)
+	GetTicketTypeTranslation(ticketTypeID int64, language string) (*TicketTypeTranslation, error)
+}
+
+func (repository *Repository) GetTicketType(ticketTypeID int64) (*TicketType, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT venue_id, name, price, currency, activations, validity_days, time_zone, active FROM ticket_types
+		WHERE ticket_type_id = $1`,
+		ticketTypeID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := TicketType{}
+	found, err := reader.NextRow(&result.VenueID, &result.Name, &result.Price, &result.Currency, &result.Activations, &result.ValidityDays, &result.TimeZone, &result.Active)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result.ID = ticketTypeID
+	return &result, nil
+}
+
+func (repository *Repository) GetActiveTicketTypes(venueID int64) ([]TicketType, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT ticket_type_id, venue_id, name, price, currency, activations, validity_days, time_zone, active FROM ticket_types
+		WHERE venue_id = $1
+		AND active = true
+		ORDER BY price, ticket_type_id`,
+		venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []TicketType{}
+	row := TicketType{}
+	for {
+		ok, err := reader.NextRow(&row.ID, &row.VenueID, &row.Name, &row.Price, &row.Currency, &row.Activations, &row.ValidityDays, &row.TimeZone, &row.Active)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetTicketTypeTranslation(ticketTypeID int64, language string) (*TicketTypeTranslation, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT title, description FROM ticket_types_i18n
+		WHERE ticket_type_id = $1
+		AND language = $2`,
+		ticketTypeID, language)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := TicketTypeTranslation{}
+	found, err := reader.NextRow(&result.Title, &result.Description)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import "github.com/lib/pq"
+
+type TourStop struct {
+	Code   string  `json:"code"`
+	Title  string  `json:"title"`
+	Covers []Cover `json:"covers"`
+}
+
+type Tour struct {
+	ID          int64      `json:"id"`
+	VenueID     int64      `json:"-"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Stops       []TourStop `json:"stops"`
+}
+
+type TourTranslation struct {
+	Title       string
+	Description string
+}
+
+// Tours and their stops are translated to the requested language,
+// translation to fallback language is used if requested one is missing
+type TourRepository interface {
+	// Lists tours of all the venues together with the tours listed by ID
+	GetTours(venueIDs []int64, tourIDs []int64, language string, fallback string) ([]Tour, error)
+	GetTour(tourID int64, language string, fallback string) (*Tour, error)
+	// Returns true if the object is a stop of any of the tours
+	IsTourStop(tourIDs []int64, objectID int64) (bool, error)
+}
+
+func (repository *Repository) GetTours(venueIDs []int64, tourIDs []int64, language string, fallback string) ([]Tour, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT tours.tour_id, tours.venue_id, COALESCE(requested.title, fallback.title), COALESCE(requested.description, fallback.description) FROM tours
+		LEFT JOIN tours_i18n requested ON requested.tour_id = tours.tour_id AND requested.language = $3
+		LEFT JOIN tours_i18n fallback ON fallback.tour_id = tours.tour_id AND fallback.language = $4
+		WHERE (tours.venue_id = ANY($1) OR tours.tour_id = ANY($2))
+		AND (requested.tour_id IS NOT NULL OR fallback.tour_id IS NOT NULL)
+		ORDER BY tours.tour_id`,
+		pq.Array(venueIDs), pq.Array(tourIDs), language, fallback)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []Tour{}
+	row := Tour{}
+	for {
+		ok, err := reader.NextRow(&row.ID, &row.VenueID, &row.Title, &row.Description)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	for i := range result {
+		result[i].Stops, err = repository.getTourStops(result[i].ID, language, fallback)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetTour(tourID int64, language string, fallback string) (*Tour, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT tours.venue_id, COALESCE(requested.title, fallback.title), COALESCE(requested.description, fallback.description) FROM tours
+		LEFT JOIN tours_i18n requested ON requested.tour_id = tours.tour_id AND requested.language = $2
+		LEFT JOIN tours_i18n fallback ON fallback.tour_id = tours.tour_id AND fallback.language = $3
+		WHERE tours.tour_id = $1
+		AND (requested.tour_id IS NOT NULL OR fallback.tour_id IS NOT NULL)`,
+		tourID, language, fallback)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := Tour{}
+	found, err := reader.NextRow(&result.VenueID, &result.Title, &result.Description)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result.ID = tourID
+	result.Stops, err = repository.getTourStops(tourID, language, fallback)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (repository *Repository) IsTourStop(tourIDs []int64, objectID int64) (bool, error) {
+	reader, err := repository.DBProvider.Query(
+		"SELECT EXISTS (SELECT 1 FROM tour_stops WHERE tour_id = ANY($1) AND object_id = $2)",
+		pq.Array(tourIDs), objectID)
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	stop := false
+	if err := reader.GetRow(&stop); err != nil {
+		return false, err
+	}
+
+	return stop, nil
+}
+
+// Returns tour stops in the route order, stops of objects without translation are skipped
+func (repository *Repository) getTourStops(tourID int64, language string, fallback string) ([]TourStop, error) {
+	reader, err := repository.DBProvider.Query(
+		`SELECT objects.object_id, objects.code, COALESCE(requested.title, fallback.title) FROM tour_stops
+		JOIN objects ON objects.object_id = tour_stops.object_id
+		LEFT JOIN objects_i18n requested ON requested.object_id = objects.object_id AND requested.language = $2
+		LEFT JOIN objects_i18n fallback ON fallback.object_id = objects.object_id AND fallback.language = $3
+		WHERE tour_stops.tour_id = $1
+		AND (requested.object_id IS NOT NULL OR fallback.object_id IS NOT NULL)
+		ORDER BY tour_stops.index`,
+		tourID, language, fallback)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	objectIDs := []int64{}
+	result := []TourStop{}
+	for {
+		objectID := int64(0)
+		row := TourStop{}
+		ok, err := reader.NextRow(&objectID, &row.Code, &row.Title)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		objectIDs = append(objectIDs, objectID)
+		result = append(result, row)
+	}
+
+	for i, objectID := range objectIDs {
+		result[i].Covers, err = repository.getObjectCovers(objectID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
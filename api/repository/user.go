@@ -0,0 +1,55 @@
+package repository
+
+import "time"
+
+// Telegram user that interacted with the bot
+type User struct {
+	ID int64
+	// Language chosen with /language, empty if the Telegram client language is used
+	Language string
+	// Language of the Telegram client reported in the last update
+	LanguageCode string
+	FirstSeenAt  time.Time
+	LastSeenAt   time.Time
+}
+
+type UserRepository interface {
+	GetUser(userID int64) (*User, error)
+	TrackUser(userID int64, languageCode string, seenAt time.Time) error
+	SetUserLanguage(userID int64, language string) error
+}
+
+func (repository *Repository) GetUser(userID int64) (*User, error) {
+	reader, err := repository.DBProvider.Query(`SELECT COALESCE(language, ''), COALESCE(language_code, ''), first_seen_at, last_seen_at
+		FROM users WHERE user_id = $1`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := User{}
+	found, err := reader.NextRow(&result.Language, &result.LanguageCode, &result.FirstSeenAt, &result.LastSeenAt)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result.ID = userID
+	return &result, nil
+}
+
+// Creates the user on the first update, later updates refresh the client language and last seen time
+func (repository *Repository) TrackUser(userID int64, languageCode string, seenAt time.Time) error {
+	_, err := repository.DBProvider.Exec(
+		`INSERT INTO users(user_id, language_code, first_seen_at, last_seen_at) VALUES ($1, $2, $3, $3)
+		ON CONFLICT (user_id) DO UPDATE SET language_code = EXCLUDED.language_code, last_seen_at = EXCLUDED.last_seen_at`,
+		userID, languageCode, seenAt)
+	return err
+}
+
+func (repository *Repository) SetUserLanguage(userID int64, language string) error {
+	_, err := repository.DBProvider.Exec(
+		"INSERT INTO users(user_id, language) VALUES ($1, $2) ON CONFLICT (user_id) DO UPDATE SET language = EXCLUDED.language",
+		userID, language)
+	return err
+}
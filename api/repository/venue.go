@@ -0,0 +1,89 @@
+package repository
+
+type Venue struct {
+	ID   int64
+	Code string
+	Name string
+}
+
+type VenueRepository interface {
+	GetVenue(venueID int64) (*Venue, error)
+	GetVenueByCode(code string) (*Venue, error)
+	GetVenues() ([]Venue, error)
+	// Returns the venue existing objects and tickets were assigned to when venues were introduced, the one with the lowest ID
+	GetDefaultVenue() (*Venue, error)
+}
+
+func (repository *Repository) GetVenue(venueID int64) (*Venue, error) {
+	reader, err := repository.DBProvider.Query("SELECT code, name FROM venues WHERE venue_id = $1", venueID)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := Venue{}
+	found, err := reader.NextRow(&result.Code, &result.Name)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result.ID = venueID
+	return &result, nil
+}
+
+func (repository *Repository) GetVenueByCode(code string) (*Venue, error) {
+	reader, err := repository.DBProvider.Query("SELECT venue_id, name FROM venues WHERE code = $1", code)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := Venue{}
+	found, err := reader.NextRow(&result.ID, &result.Name)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	result.Code = code
+	return &result, nil
+}
+
+func (repository *Repository) GetVenues() ([]Venue, error) {
+	reader, err := repository.DBProvider.Query("SELECT venue_id, code, name FROM venues ORDER BY venue_id")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := []Venue{}
+	row := Venue{}
+	for {
+		ok, err := reader.NextRow(&row.ID, &row.Code, &row.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+
+		result = append(result, row)
+	}
+
+	return result, nil
+}
+
+func (repository *Repository) GetDefaultVenue() (*Venue, error) {
+	reader, err := repository.DBProvider.Query("SELECT venue_id, code, name FROM venues ORDER BY venue_id LIMIT 1")
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := Venue{}
+	found, err := reader.NextRow(&result.ID, &result.Code, &result.Name)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	return &result, nil
+}
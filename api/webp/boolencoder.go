@@ -0,0 +1,68 @@
+package webp
+
+// Probability of an evenly distributed bit
+const uniformProb = 128
+
+// Boolean entropy encoder, as specified in section 7.3 of RFC 6386
+type boolEncoder struct {
+	buf      []byte
+	rng      uint32
+	bottom   uint32
+	bitCount int
+}
+
+func newBoolEncoder() *boolEncoder {
+	return &boolEncoder{rng: 255, bitCount: 24}
+}
+
+// Encodes a bit that is false with the probability of prob/256
+func (e *boolEncoder) putBit(bit bool, prob uint8) {
+	split := 1 + ((e.rng-1)*uint32(prob))>>8
+	if bit {
+		e.bottom += split
+		e.rng -= split
+	} else {
+		e.rng = split
+	}
+
+	for e.rng < 128 {
+		e.rng <<= 1
+		if e.bottom&(1<<31) != 0 {
+			e.addCarry()
+		}
+		e.bottom <<= 1
+		e.bitCount--
+		if e.bitCount == 0 {
+			e.buf = append(e.buf, byte(e.bottom>>24))
+			e.bottom &= 1<<24 - 1
+			e.bitCount = 8
+		}
+	}
+}
+
+func (e *boolEncoder) addCarry() {
+	i := len(e.buf) - 1
+	for i >= 0 && e.buf[i] == 0xff {
+		e.buf[i] = 0
+		i--
+	}
+	if i >= 0 {
+		e.buf[i]++
+	}
+}
+
+// Encodes n lowest bits of value, most significant first
+func (e *boolEncoder) putLiteral(value uint32, n int) {
+	for n > 0 {
+		n--
+		e.putBit(value&(1<<n) != 0, uniformProb)
+	}
+}
+
+// Pads the output so the decoder never reads past the written data
+func (e *boolEncoder) finish() []byte {
+	for i := 0; i < 32; i++ {
+		e.putBit(false, uniformProb)
+	}
+	return e.buf
+}
@@ -0,0 +1,574 @@
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+const DefaultQuality = 75
+
+// VP8 frame dimensions are stored in 14 bits
+const MAX_SIZE = 16383
+
+// Quantized coefficients above 2048 don't fit DCT_CAT6 token
+const maxLevel = 2048
+
+// First partition size is stored in 19 bits
+const maxFirstPartitionSize = 1<<19 - 1
+
+var ErrInvalidSize = errors.New("webp: invalid image size")
+var errFirstPartitionTooLarge = errors.New("webp: first partition is too large")
+
+// Options are the encoding parameters, quality ranges from 1 to 100, higher is better
+type Options struct {
+	Quality int
+}
+
+// The plane enumeration is specified in section 13.3 of RFC 6386
+const (
+	planeY1WithY2 = iota
+	planeY2
+	planeUV
+	planeY1SansY2
+	nPlane
+)
+
+const (
+	nBand    = 8
+	nContext = 3
+	nProb    = 11
+)
+
+// DC and AC quantization factors of each plane
+type quant struct {
+	y1 [2]int32
+	y2 [2]int32
+	uv [2]int32
+}
+
+// Whether 4x4 blocks along the edge of a macroblock have non-zero coefficients,
+// used as a context for coefficient tokens of the adjacent macroblock
+type nzContext struct {
+	y2 uint8
+	y  [4]uint8
+	u  [2]uint8
+	v  [2]uint8
+}
+
+type encoder struct {
+	width  int
+	height int
+	mbw    int
+	mbh    int
+	// Source and reconstructed YCbCr 4:2:0 planes, padded to whole macroblocks
+	y  []uint8
+	u  []uint8
+	v  []uint8
+	ry []uint8
+	ru []uint8
+	rv []uint8
+	// Quantizer index and factors
+	qi    int
+	quant quant
+	// First partition with headers and modes, and the partition with coefficients
+	fp *boolEncoder
+	tp *boolEncoder
+	// Token contexts of the macroblocks above and to the left of the current one
+	upNz   []nzContext
+	leftNz nzContext
+}
+
+// Writes the image to w in lossy WebP format, transparency is not preserved.
+// Every macroblock is DC-predicted and the loop filter is disabled,
+// which keeps the encoder simple at the cost of a larger output
+func Encode(w io.Writer, m image.Image, o *Options) error {
+	bounds := m.Bounds()
+	if bounds.Dx() < 1 || bounds.Dy() < 1 || bounds.Dx() > MAX_SIZE || bounds.Dy() > MAX_SIZE {
+		return ErrInvalidSize
+	}
+
+	quality := DefaultQuality
+	if o != nil {
+		quality = min(max(o.Quality, 1), 100)
+	}
+
+	frame, err := newEncoder(m, quality).encodeFrame()
+	if err != nil {
+		return err
+	}
+
+	// Simple file format: RIFF header followed by a single VP8 chunk
+	size := len(frame) + len(frame)&1
+	header := []byte("RIFF")
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+size))
+	header = append(header, "WEBPVP8 "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(frame)))
+	if len(frame) != size {
+		frame = append(frame, 0)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err = w.Write(frame)
+	return err
+}
+
+func newEncoder(m image.Image, quality int) *encoder {
+	bounds := m.Bounds()
+	e := &encoder{
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		mbw:    (bounds.Dx() + 15) / 16,
+		mbh:    (bounds.Dy() + 15) / 16,
+		qi:     (100 - quality) * 127 / 99,
+		fp:     newBoolEncoder(),
+		tp:     newBoolEncoder(),
+	}
+	e.quant = newQuant(e.qi)
+	e.upNz = make([]nzContext, e.mbw)
+
+	rgba, ok := m.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(bounds)
+		draw.Draw(rgba, bounds, m, bounds.Min, draw.Src)
+	}
+
+	// Pixels outside of the image repeat the nearest edge
+	pixel := func(x, y int) (int32, int32, int32) {
+		x = bounds.Min.X + min(x, e.width-1)
+		y = bounds.Min.Y + min(y, e.height-1)
+		p := rgba.Pix[rgba.PixOffset(x, y):]
+		return int32(p[0]), int32(p[1]), int32(p[2])
+	}
+
+	yStride, cStride := e.mbw*16, e.mbw*8
+	e.y = make([]uint8, yStride*e.mbh*16)
+	e.ry = make([]uint8, len(e.y))
+	for y := 0; y < e.mbh*16; y++ {
+		for x := 0; x < yStride; x++ {
+			e.y[y*yStride+x] = rgbToY(pixel(x, y))
+		}
+	}
+
+	e.u = make([]uint8, cStride*e.mbh*8)
+	e.v = make([]uint8, len(e.u))
+	e.ru = make([]uint8, len(e.u))
+	e.rv = make([]uint8, len(e.u))
+	for y := 0; y < e.mbh*8; y++ {
+		for x := 0; x < cStride; x++ {
+			var r, g, b int32
+			for i := 0; i < 4; i++ {
+				pr, pg, pb := pixel(2*x+i%2, 2*y+i/2)
+				r, g, b = r+pr, g+pg, b+pb
+			}
+			e.u[y*cStride+x], e.v[y*cStride+x] = rgbToUV(r, g, b)
+		}
+	}
+
+	return e
+}
+
+// BT.601 limited range conversion, as expected by WebP decoders
+func rgbToY(r, g, b int32) uint8 {
+	return uint8((16839*r + 33059*g + 6420*b + 16<<16 + 1<<15) >> 16)
+}
+
+// Converts sums of 2x2 pixels to chroma values
+func rgbToUV(r, g, b int32) (uint8, uint8) {
+	u := (-9719*r - 19081*g + 28800*b + 128<<18 + 1<<17) >> 18
+	v := (28800*r - 24116*g - 4684*b + 128<<18 + 1<<17) >> 18
+	return uint8(u), uint8(v)
+}
+
+// Quantization factors are derived as specified in section 9.6 of RFC 6386
+func newQuant(qi int) quant {
+	y2ac := int32(dequantTableAC[qi]) * 155 / 100
+	if y2ac < 8 {
+		y2ac = 8
+	}
+
+	return quant{
+		y1: [2]int32{int32(dequantTableDC[qi]), int32(dequantTableAC[qi])},
+		y2: [2]int32{int32(dequantTableDC[qi]) * 2, y2ac},
+		uv: [2]int32{int32(dequantTableDC[min(qi, 117)]), int32(dequantTableAC[qi])},
+	}
+}
+
+// Encodes the image as a VP8 key frame, as specified in section 9 of RFC 6386
+func (e *encoder) encodeFrame() ([]byte, error) {
+	e.writeHeader()
+	for mby := 0; mby < e.mbh; mby++ {
+		e.leftNz = nzContext{}
+		for mbx := 0; mbx < e.mbw; mbx++ {
+			e.encodeMacroblock(mbx, mby)
+		}
+	}
+
+	first := e.fp.finish()
+	if len(first) > maxFirstPartitionSize {
+		return nil, errFirstPartitionTooLarge
+	}
+
+	// Key frame, version 0, shown, followed by the start code and dimensions without scaling
+	size := len(first)
+	frame := []byte{byte(size<<5 | 1<<4), byte(size >> 3), byte(size >> 11), 0x9d, 0x01, 0x2a}
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(e.width))
+	frame = binary.LittleEndian.AppendUint16(frame, uint16(e.height))
+	frame = append(frame, first...)
+	return append(frame, e.tp.finish()...), nil
+}
+
+func (e *encoder) writeHeader() {
+	// Color space and clamping type
+	e.fp.putBit(false, uniformProb)
+	e.fp.putBit(false, uniformProb)
+	// No segmentation
+	e.fp.putBit(false, uniformProb)
+	// Normal filter with zero level, so the loop filter is disabled
+	e.fp.putBit(false, uniformProb)
+	e.fp.putLiteral(0, 6)
+	e.fp.putLiteral(0, 3)
+	e.fp.putBit(false, uniformProb)
+	// Single coefficients partition
+	e.fp.putLiteral(0, 2)
+	// Quantizer index without per-plane deltas
+	e.fp.putLiteral(uint32(e.qi), 7)
+	for i := 0; i < 5; i++ {
+		e.fp.putBit(false, uniformProb)
+	}
+	// Refresh entropy probabilities
+	e.fp.putBit(false, uniformProb)
+	// Default token probabilities are kept
+	for i := range tokenProbUpdateProb {
+		for j := range tokenProbUpdateProb[i] {
+			for k := range tokenProbUpdateProb[i][j] {
+				for _, prob := range tokenProbUpdateProb[i][j][k] {
+					e.fp.putBit(false, prob)
+				}
+			}
+		}
+	}
+	// Coefficients are coded for every macroblock
+	e.fp.putBit(false, uniformProb)
+}
+
+func (e *encoder) encodeMacroblock(mbx, mby int) {
+	// 16x16 luma prediction with DC_PRED for both luma and chroma, section 11.2
+	e.fp.putBit(true, 145)
+	e.fp.putBit(false, 156)
+	e.fp.putBit(false, 163)
+	e.fp.putBit(false, 142)
+
+	up := &e.upNz[mbx]
+	e.encodeLuma(mbx, mby, up)
+	e.encodeChroma(e.u, e.ru, mbx, mby, &e.leftNz.u, &up.u)
+	e.encodeChroma(e.v, e.rv, mbx, mby, &e.leftNz.v, &up.v)
+}
+
+// Codes luma residuals and reconstructs the macroblock the same way decoders do,
+// so the next macroblocks are predicted from the decoded values
+func (e *encoder) encodeLuma(mbx, mby int, up *nzContext) {
+	stride := e.mbw * 16
+	x0, y0 := mbx*16, mby*16
+	pred := predictDC(e.ry, stride, x0, y0, 16)
+
+	// DC coefficients of all blocks are coded separately after Walsh-Hadamard transform
+	var coeffs [16][16]int32
+	var dc [16]int32
+	for n := range coeffs {
+		coeffs[n] = forwardDCT(e.y, stride, x0+n%4*4, y0+n/4*4, pred)
+		dc[n] = coeffs[n][0]
+	}
+
+	levels, dequantized := quantize(forwardWHT(&dc), e.quant.y2, 0)
+	nz := e.putCoefficients(planeY2, e.leftNz.y2+up.y2, &levels, 0)
+	e.leftNz.y2, up.y2 = nz, nz
+	dc = inverseWHT(&dequantized)
+
+	for n := range coeffs {
+		bx, by := n%4, n/4
+		levels, dequantized := quantize(coeffs[n], e.quant.y1, 1)
+		dequantized[0] = dc[n]
+		nz := e.putCoefficients(planeY1WithY2, e.leftNz.y[by]+up.y[bx], &levels, 1)
+		e.leftNz.y[by], up.y[bx] = nz, nz
+		inverseDCT(e.ry, stride, x0+bx*4, y0+by*4, pred, &dequantized)
+	}
+}
+
+func (e *encoder) encodeChroma(src, recon []uint8, mbx, mby int, left, up *[2]uint8) {
+	stride := e.mbw * 8
+	x0, y0 := mbx*8, mby*8
+	pred := predictDC(recon, stride, x0, y0, 8)
+
+	for n := 0; n < 4; n++ {
+		bx, by := n%2, n/2
+		levels, dequantized := quantize(forwardDCT(src, stride, x0+bx*4, y0+by*4, pred), e.quant.uv, 0)
+		nz := e.putCoefficients(planeUV, left[by]+up[bx], &levels, 0)
+		left[by], up[bx] = nz, nz
+		inverseDCT(recon, stride, x0+bx*4, y0+by*4, pred, &dequantized)
+	}
+}
+
+// Codes coefficients of a 4x4 block starting from the first one in zigzag order,
+// as specified in section 13 of RFC 6386, and returns 1 if any of them is non-zero
+func (e *encoder) putCoefficients(plane int, context uint8, levels *[16]int32, first int) uint8 {
+	probs := &defaultTokenProb[plane]
+	last := -1
+	for n := first; n < 16; n++ {
+		if levels[zigzag[n]] != 0 {
+			last = n
+		}
+	}
+
+	p := &probs[bands[first]][context]
+	if last < 0 {
+		e.tp.putBit(false, p[0])
+		return 0
+	}
+
+	e.tp.putBit(true, p[0])
+	for n := first; n <= last; n++ {
+		level := levels[zigzag[n]]
+		if level == 0 {
+			// End of block can't follow a zero, so the next token skips its check
+			e.tp.putBit(false, p[1])
+			p = &probs[bands[n+1]][0]
+			continue
+		}
+
+		e.tp.putBit(true, p[1])
+		abs := max(level, -level)
+		if abs == 1 {
+			e.tp.putBit(false, p[2])
+			p = &probs[bands[n+1]][1]
+		} else {
+			e.putLevel(p, abs)
+			p = &probs[bands[n+1]][2]
+		}
+		e.tp.putBit(level < 0, uniformProb)
+
+		if n < 15 {
+			e.tp.putBit(n < last, p[0])
+		}
+	}
+
+	return 1
+}
+
+// Codes an absolute coefficient value above 1 with the token tree and extra bits
+func (e *encoder) putLevel(p *[nProb]uint8, v int32) {
+	e.tp.putBit(true, p[2])
+	switch {
+	case v <= 4:
+		e.tp.putBit(false, p[3])
+		if v == 2 {
+			e.tp.putBit(false, p[4])
+		} else {
+			e.tp.putBit(true, p[4])
+			e.tp.putBit(v == 4, p[5])
+		}
+	case v <= 10:
+		e.tp.putBit(true, p[3])
+		e.tp.putBit(false, p[6])
+		if v <= 6 {
+			// DCT_CAT1
+			e.tp.putBit(false, p[7])
+			e.tp.putBit(v == 6, 159)
+		} else {
+			// DCT_CAT2
+			e.tp.putBit(true, p[7])
+			e.tp.putBit((v-7)&2 != 0, 165)
+			e.tp.putBit((v-7)&1 != 0, 145)
+		}
+	default:
+		// DCT_CAT3 to DCT_CAT6 start at 11, 19, 35 and 67
+		e.tp.putBit(true, p[3])
+		e.tp.putBit(true, p[6])
+		cat := 3
+		for v < 3+8<<cat {
+			cat--
+		}
+		e.tp.putBit(cat >= 2, p[8])
+		e.tp.putBit(cat&1 != 0, p[9+cat>>1])
+
+		extra := v - (3 + 8<<cat)
+		probs := cat3456[cat]
+		for i, prob := range probs {
+			e.tp.putBit(extra&(1<<(len(probs)-1-i)) != 0, prob)
+		}
+	}
+}
+
+// Quantizes coefficients starting from first, returns quantized levels and values restored from them
+func quantize(coeffs [16]int32, factors [2]int32, first int) ([16]int32, [16]int32) {
+	var levels, dequantized [16]int32
+	for i := first; i < 16; i++ {
+		q := factors[min(i, 1)]
+		abs := max(coeffs[i], -coeffs[i])
+		// Decoders keep restored values in 16 bits
+		level := min((abs+q/2)/q, maxLevel, 32767/q)
+		if coeffs[i] < 0 {
+			level = -level
+		}
+		levels[i] = level
+		dequantized[i] = level * q
+	}
+
+	return levels, dequantized
+}
+
+// Average of the reconstructed pixels above and to the left of the block,
+// as DC_PRED is specified in section 12.2 of RFC 6386
+func predictDC(plane []uint8, stride, x, y, size int) uint8 {
+	sum, count := 0, 0
+	if y > 0 {
+		for i := 0; i < size; i++ {
+			sum += int(plane[(y-1)*stride+x+i])
+		}
+		count += size
+	}
+	if x > 0 {
+		for i := 0; i < size; i++ {
+			sum += int(plane[(y+i)*stride+x-1])
+		}
+		count += size
+	}
+
+	if count == 0 {
+		return 0x80
+	}
+	return uint8((sum + count/2) / count)
+}
+
+// Forward DCT of a 4x4 block residual, matching the one from the reference encoder
+func forwardDCT(plane []uint8, stride, x, y int, pred uint8) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		row := plane[(y+i)*stride+x:]
+		d0, d1, d2, d3 := int32(row[0])-int32(pred), int32(row[1])-int32(pred), int32(row[2])-int32(pred), int32(row[3])-int32(pred)
+		a := (d0 + d3) * 8
+		b := (d1 + d2) * 8
+		c := (d1 - d2) * 8
+		d := (d0 - d3) * 8
+		tmp[i*4+0] = a + b
+		tmp[i*4+1] = (c*2217 + d*5352 + 14500) >> 12
+		tmp[i*4+2] = a - b
+		tmp[i*4+3] = (d*2217 - c*5352 + 7500) >> 12
+	}
+
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[12+i]
+		b := tmp[4+i] + tmp[8+i]
+		c := tmp[4+i] - tmp[8+i]
+		d := tmp[i] - tmp[12+i]
+		out[i] = (a + b + 7) >> 4
+		out[4+i] = (c*2217+d*5352+12000)>>16 + btoi(d != 0)
+		out[8+i] = (a - b + 7) >> 4
+		out[12+i] = (d*2217 - c*5352 + 51000) >> 16
+	}
+
+	return out
+}
+
+// Adds inverse DCT of the coefficients to the prediction, as specified in section 14.3 of RFC 6386
+func inverseDCT(plane []uint8, stride, x, y int, pred uint8, coeffs *[16]int32) {
+	const (
+		c1 = 85627 // 65536 * cos(pi/8) * sqrt(2)
+		c2 = 35468 // 65536 * sin(pi/8) * sqrt(2)
+	)
+
+	var m [4][4]int32
+	for i := 0; i < 4; i++ {
+		a := coeffs[i] + coeffs[8+i]
+		b := coeffs[i] - coeffs[8+i]
+		c := (coeffs[4+i]*c2)>>16 - (coeffs[12+i]*c1)>>16
+		d := (coeffs[4+i]*c1)>>16 + (coeffs[12+i]*c2)>>16
+		m[i] = [4]int32{a + d, b + c, b - c, a - d}
+	}
+
+	for j := 0; j < 4; j++ {
+		dc := m[0][j] + 4
+		a := dc + m[2][j]
+		b := dc - m[2][j]
+		c := (m[1][j]*c2)>>16 - (m[3][j]*c1)>>16
+		d := (m[1][j]*c1)>>16 + (m[3][j]*c2)>>16
+		row := plane[(y+j)*stride+x:]
+		row[0] = clip8(int32(pred) + (a+d)>>3)
+		row[1] = clip8(int32(pred) + (b+c)>>3)
+		row[2] = clip8(int32(pred) + (b-c)>>3)
+		row[3] = clip8(int32(pred) + (a-d)>>3)
+	}
+}
+
+// Forward Walsh-Hadamard transform of luma DC coefficients, matching the one from the reference encoder
+func forwardWHT(dc *[16]int32) [16]int32 {
+	var tmp, out [16]int32
+	for i := 0; i < 4; i++ {
+		a := (dc[i*4+0] + dc[i*4+2]) * 4
+		d := (dc[i*4+1] + dc[i*4+3]) * 4
+		c := (dc[i*4+1] - dc[i*4+3]) * 4
+		b := (dc[i*4+0] - dc[i*4+2]) * 4
+		tmp[i*4+0] = a + d + btoi(a != 0)
+		tmp[i*4+1] = b + c
+		tmp[i*4+2] = b - c
+		tmp[i*4+3] = a - d
+	}
+
+	for i := 0; i < 4; i++ {
+		a := tmp[i] + tmp[8+i]
+		d := tmp[4+i] + tmp[12+i]
+		c := tmp[4+i] - tmp[12+i]
+		b := tmp[i] - tmp[8+i]
+		for j, v := range [4]int32{a + d, b + c, b - c, a - d} {
+			v += btoi(v < 0)
+			out[j*4+i] = (v + 3) >> 3
+		}
+	}
+
+	return out
+}
+
+// Inverse Walsh-Hadamard transform, as specified in section 14.3 of RFC 6386
+func inverseWHT(coeffs *[16]int32) [16]int32 {
+	var m, out [16]int32
+	for i := 0; i < 4; i++ {
+		a0 := coeffs[i] + coeffs[12+i]
+		a1 := coeffs[4+i] + coeffs[8+i]
+		a2 := coeffs[4+i] - coeffs[8+i]
+		a3 := coeffs[i] - coeffs[12+i]
+		m[i] = a0 + a1
+		m[4+i] = a3 + a2
+		m[8+i] = a0 - a1
+		m[12+i] = a3 - a2
+	}
+
+	for i := 0; i < 4; i++ {
+		dc := m[i*4] + 3
+		a0 := dc + m[i*4+3]
+		a1 := m[i*4+1] + m[i*4+2]
+		a2 := m[i*4+1] - m[i*4+2]
+		a3 := dc - m[i*4+3]
+		// Decoders keep transformed values in 16 bits
+		out[i*4+0] = int32(int16((a0 + a1) >> 3))
+		out[i*4+1] = int32(int16((a3 + a2) >> 3))
+		out[i*4+2] = int32(int16((a0 - a1) >> 3))
+		out[i*4+3] = int32(int16((a3 - a2) >> 3))
+	}
+
+	return out
+}
+
+func clip8(v int32) uint8 {
+	return uint8(min(max(v, 0), 255))
+}
+
+func btoi(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
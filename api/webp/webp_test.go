@@ -0,0 +1,135 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func createTestImage(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Gradients with a sharp edge in the middle to produce AC coefficients of all sizes
+			edge := uint8(0)
+			if x > width/2 {
+				edge = 200
+			}
+			img.SetRGBA(x, y, color.RGBA{R: uint8(x * 255 / width), G: uint8(y * 255 / height), B: edge, A: 255})
+		}
+	}
+	return img
+}
+
+func encodeTestImage(t *testing.T, img image.Image, quality int) *image.YCbCr {
+	var buffer bytes.Buffer
+	if err := Encode(&buffer, img, &Options{Quality: quality}); err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+
+	decoded, err := webp.Decode(&buffer)
+	if err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+
+	ycbcr, ok := decoded.(*image.YCbCr)
+	if !ok {
+		t.Fatalf("unexpected decoded image type %T", decoded)
+	}
+	if ycbcr.Bounds() != img.Bounds().Sub(img.Bounds().Min) {
+		t.Fatalf("unexpected decoded bounds %v, expected %v", ycbcr.Bounds(), img.Bounds())
+	}
+	return ycbcr
+}
+
+// Peak signal-to-noise ratio of the decoded luma
+func getTestLumaPSNR(img *image.RGBA, decoded *image.YCbCr) float64 {
+	bounds := img.Bounds()
+	var squares float64
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			p := img.RGBAAt(bounds.Min.X+x, bounds.Min.Y+y)
+			diff := float64(rgbToY(int32(p.R), int32(p.G), int32(p.B))) - float64(decoded.Y[decoded.YOffset(x, y)])
+			squares += diff * diff
+		}
+	}
+
+	mse := squares / float64(bounds.Dx()*bounds.Dy())
+	if mse == 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		width   int
+		height  int
+		quality int
+		noise   bool
+		psnr    float64
+	}{
+		{width: 64, height: 48, quality: 80, psnr: 40},
+		{width: 37, height: 21, quality: 80, psnr: 40},
+		{width: 1, height: 1, quality: 80, psnr: 40},
+		{width: 100, height: 60, quality: 100, psnr: 50},
+		{width: 100, height: 60, quality: 1, psnr: 30},
+		// Noise produces the largest coefficients
+		{width: 70, height: 50, quality: 100, noise: true, psnr: 50},
+		{width: 70, height: 50, quality: 80, noise: true, psnr: 33},
+	}
+
+	for _, test := range tests {
+		img := createTestImage(test.width, test.height)
+		if test.noise {
+			random := rand.New(rand.NewSource(1))
+			for i := range img.Pix {
+				img.Pix[i] = uint8(random.Intn(256)) | uint8(255*btoi(i%4 == 3))
+			}
+		}
+		decoded := encodeTestImage(t, img, test.quality)
+		if psnr := getTestLumaPSNR(img, decoded); psnr < test.psnr {
+			t.Errorf("%dx%d at quality %d, noise %v: luma PSNR is %.1f dB, expected at least %.1f dB", test.width, test.height, test.quality, test.noise, psnr, test.psnr)
+		}
+	}
+}
+
+func TestEncodeColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := range img.Pix {
+		img.Pix[i] = []uint8{200, 40, 90, 255}[i%4]
+	}
+
+	decoded := encodeTestImage(t, img, 80)
+	y, u, v := decoded.Y[0], decoded.Cb[0], decoded.Cr[0]
+	expectedU, expectedV := rgbToUV(4*200, 4*40, 4*90)
+	expected := []uint8{rgbToY(200, 40, 90), expectedU, expectedV}
+	for i, value := range []uint8{y, u, v} {
+		if diff := int(value) - int(expected[i]); diff < -2 || diff > 2 {
+			t.Errorf("unexpected decoded color %v, expected %v", []uint8{y, u, v}, expected)
+			break
+		}
+	}
+}
+
+func TestEncodeSubImage(t *testing.T) {
+	img := createTestImage(64, 64).SubImage(image.Rect(16, 8, 48, 40)).(*image.RGBA)
+	decoded := encodeTestImage(t, img, 80)
+	if psnr := getTestLumaPSNR(img, decoded); psnr < 35 {
+		t.Errorf("luma PSNR is %.1f dB, expected at least 35 dB", psnr)
+	}
+}
+
+func TestEncodeInvalidSize(t *testing.T) {
+	for _, rect := range []image.Rectangle{image.Rect(0, 0, 0, 10), image.Rect(0, 0, MAX_SIZE+1, 1)} {
+		err := Encode(&bytes.Buffer{}, image.NewGray(rect), nil)
+		if err != ErrInvalidSize {
+			t.Errorf("unexpected error for %v: %v", rect, err)
+		}
+	}
+}
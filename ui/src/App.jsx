@@ -3,10 +3,20 @@ import "./components/CommonStyles.css";
 import { useEffect, useState } from "react";
 import { addTokenListener, removeTokenListener } from "./api/auth";
 import ObjectViewerComponent from "./components/ObjectViewerComponent";
-import { isTelegramAPISupported } from "./api/telegram";
+import TourViewerComponent from "./components/TourViewerComponent";
+import { getObjectCodeFromQR, getStartParam, isTelegramAPISupported } from "./api/telegram";
 import { i18n } from "./api/i18n";
 import { ButtonComponent } from "./components/ButtonComponents";
 
+const OBJECT_URL_PARAM = "object";
+const TOUR_URL_PARAM = "tour";
+
+// Object or tour opened with a link from the bot
+const getURLParam = (name) => {
+    const queryParameters = new URLSearchParams(window.location.search);
+    return queryParameters.get(name);
+};
+
 function App() {
     const isSupported = isTelegramAPISupported();
     const onScanQRClicked = () => {
@@ -17,7 +27,8 @@ function App() {
         window.Telegram.WebApp.close();
     };
 
-    const [scannedObject, setScannedObject] = useState(null);
+    const [scannedObject, setScannedObject] = useState(() => getStartParam() ?? getURLParam(OBJECT_URL_PARAM));
+    const [selectedTour, setSelectedTour] = useState(() => getURLParam(TOUR_URL_PARAM));
     const [tokenState, setTokenState] = useState({
         loaded: false,
         token: null
@@ -38,23 +49,30 @@ function App() {
     }, []);
 
     useEffect(() => {
+        window.Telegram.WebApp.BackButton.isVisible = scannedObject != null || selectedTour != null;
+    }, [scannedObject, selectedTour]);
+
+    useEffect(() => {
+        // Object opened from the tour goes back to the tour
         const onBackClicked = () => {
-            window.Telegram.WebApp.BackButton.isVisible = false;
-            setScannedObject(null);
+            if (scannedObject != null) {
+                setScannedObject(null);
+            } else {
+                setSelectedTour(null);
+            }
         };
 
         window.Telegram.WebApp.BackButton.onClick(onBackClicked);
         return () => {
             window.Telegram.WebApp.BackButton.offClick(onBackClicked);
         };
-    }, []);
+    }, [scannedObject]);
 
     useEffect(() => {
         const QR_EVENT = "qrTextReceived";
         const onQRTextReceived = (event) => {
             window.Telegram.WebApp.closeScanQrPopup();
-            window.Telegram.WebApp.BackButton.isVisible = true;
-            setScannedObject(event.data);
+            setScannedObject(getObjectCodeFromQR(event.data));
         };
 
         window.Telegram.WebApp.onEvent(QR_EVENT, onQRTextReceived);
@@ -83,6 +101,8 @@ function App() {
                     <ButtonComponent onClick={onCloseClicked}>{i18n.t("BUTTON_CLOSE_APP")}</ButtonComponent>
                 </div>
             );
+        } else if (scannedObject == null && selectedTour != null) {
+            return <TourViewerComponent accessToken={tokenState.token} tourID={selectedTour} onStopSelected={setScannedObject} />;
         } else if (scannedObject == null) {
             return (
                 <div className="scanner-wrapper">
@@ -1,10 +1,17 @@
 import jwt_decode from "jwt-decode";
 import { isTelegramAPISupported, getCloudValue, setCloudValue } from "./telegram";
-import { exchangeTicketForToken } from "./guide";
+import { exchangeTicketForToken, refreshAccessToken } from "./guide";
 
 const TICKET_URL_PARAM = "ticket";
 const CLOUD_TOKEN_KEY = "AUTH_TOKEN";
+const CLOUD_REFRESH_TOKEN_KEY = "REFRESH_TOKEN";
 const TOKEN_CHANGED_EVENT = "TOKEN_CHANGED";
+// token is renewed a bit before it expires, so requests in flight don't fail
+const TOKEN_RENEW_MARGIN_SECONDS = 60;
+// failed renewal is retried, e.g. when the device is offline for a while
+const TOKEN_RETRY_DELAY_MS = 10 * 1000;
+// refresh token just used by another device is replaced in CloudStorage a moment later
+const REFRESH_CONFLICT_DELAY_MS = 2 * 1000;
 
 const dispatcher = new EventTarget();
 let state = {
@@ -12,36 +19,82 @@ let state = {
     loaded: false,
 };
 
+// saves both tokens to CloudStorage, every refresh token can be used only once
+const saveTokens = (response) => {
+    const { token, refresh_token } = response.data.data;
+    return setCloudValue(CLOUD_TOKEN_KEY, token).then(() => {
+        return setCloudValue(CLOUD_REFRESH_TOKEN_KEY, refresh_token);
+    }).then(() => {
+        return token;
+    });
+};
+
+// resolves null if the token can't be issued:
+// 401 = refresh token is invalid, expired or reused
+// 403 = ticket is expired, revoked or activations limit is reached
+// 404 = ticket is not found
+const resolveRejectedToken = (err) => {
+    const status = err?.response?.status;
+    if (status === 401 || status === 403 || status === 404) {
+        return null;
+    }
+    throw err;
+};
+
+const loadTokens = () => {
+    return Promise.all([
+        getCloudValue(CLOUD_TOKEN_KEY),
+        getCloudValue(CLOUD_REFRESH_TOKEN_KEY),
+    ]);
+};
+
+const isValidToken = (jwt) => {
+    return jwt?.claims?.exp - TOKEN_RENEW_MARGIN_SECONDS > Date.now() / 1000;
+};
+
+// CloudStorage is shared by user devices, so another device may refresh the same token at the same time,
+// 409 = refresh token was just used, its successor is read from CloudStorage once the other device saves it
+const resolveConflictedToken = (err) => {
+    if (err?.response?.status !== 409) {
+        throw err;
+    }
+
+    return new Promise((resolve) => {
+        setTimeout(resolve, REFRESH_CONFLICT_DELAY_MS);
+    }).then(loadTokens).then(([value]) => {
+        const jwt = decodeJWT(value);
+        return isValidToken(jwt) ? jwt.token : null;
+    });
+};
+
+const exchangeTicket = () => {
+    const queryParameters = new URLSearchParams(window.location.search);
+    const ticket = queryParameters.get(TICKET_URL_PARAM);
+    if (ticket == null) {
+        return Promise.resolve(null);
+    }
+
+    return exchangeTicketForToken(ticket).then(saveTokens).catch(resolveRejectedToken);
+};
+
 // refreshes token if needed
-// - if token is (null or expired) AND ticket is not null
-// -- exchange ticket for token
-// -- save token to CloudStorage
-const refreshToken = (jwt) => {
-    return new Promise((resolve, reject) => {
-        const isValidToken = jwt?.claims?.exp > Date.now() / 1000;
-        if (isValidToken) {
-            return resolve(jwt.token);
-        }
-
-        const queryParameters = new URLSearchParams(window.location.search);
-        const ticket = queryParameters.get(TICKET_URL_PARAM);
-        if (ticket == null) {
-            return resolve(null);
-        }
-
-        exchangeTicketForToken(ticket).then((response) => {
-            const token = response.data.data.token;
-            return setCloudValue(CLOUD_TOKEN_KEY, token).then(() => {
-                return resolve(token);
-            });
-        }).catch((err) => {
-            // 403 = ticket is already activated and cannot be exchanged
-            if (err?.response?.status === 403) {
-                resolve(null);
-            } else {
-                reject(err);
-            }
-        });
+// - if token is (null or about to expire) AND refresh token is not null
+// -- exchange refresh token for new tokens
+// -- if another device has just exchanged it, read the tokens it saved
+// - if there are still no token AND ticket is not null
+// -- exchange ticket for tokens, ticket holder gets them without another activation
+// - save tokens to CloudStorage
+const refreshToken = (jwt, refresh) => {
+    if (isValidToken(jwt)) {
+        return Promise.resolve(jwt.token);
+    }
+
+    if (!refresh) {
+        return exchangeTicket();
+    }
+
+    return refreshAccessToken(refresh).then(saveTokens).catch(resolveConflictedToken).catch(resolveRejectedToken).then((token) => {
+        return token ?? exchangeTicket();
     });
 };
 
@@ -53,18 +106,40 @@ const decodeJWT = (value) => {
     }
 };
 
+// renews the token while the app is open, the API issues short-lived tokens
+const scheduleRenewal = (token) => {
+    const jwt = decodeJWT(token);
+    if (jwt?.claims?.exp == null) {
+        return;
+    }
+
+    const delay = (jwt.claims.exp - TOKEN_RENEW_MARGIN_SECONDS) * 1000 - Date.now();
+    setTimeout(getToken, Math.max(delay, 0));
+};
+
+// only one renewal runs at a time, so the same refresh token is never sent twice by this client
+let pendingRenewal = null;
+
 const getToken = () => {
-    state.loaded = false;
-    getCloudValue(CLOUD_TOKEN_KEY).then((value) => {
-        return decodeJWT(value);
-    }).then((jwt) => {
-        return refreshToken(jwt);
+    if (pendingRenewal != null) {
+        return pendingRenewal;
+    }
+
+    pendingRenewal = loadTokens().then(([value, refresh]) => {
+        return refreshToken(decodeJWT(value), refresh);
     }).then((token) => {
+        scheduleRenewal(token);
         state.token = token;
         state.loaded = true;
         const event = new CustomEvent(TOKEN_CHANGED_EVENT, { detail: token });
         dispatcher.dispatchEvent(event);
+    }).catch(() => {
+        setTimeout(getToken, TOKEN_RETRY_DELAY_MS);
+    }).finally(() => {
+        pendingRenewal = null;
     });
+
+    return pendingRenewal;
 };
 
 if(isTelegramAPISupported()) {
@@ -80,4 +155,4 @@ export const addTokenListener = (callback) => {
 
 export const removeTokenListener = (callback) => {
     dispatcher.removeEventListener(TOKEN_CHANGED_EVENT, callback);
-};
\ No newline at end of file
+};
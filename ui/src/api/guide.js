@@ -1,10 +1,18 @@
 import axios from "axios";
-import { getTelegramLanguage } from "./telegram";
+import { getTelegramLanguage, getInitData } from "./telegram";
 
 const URL_BASE = window.REACT_APP_ENV.REACT_APP_BOT_API_URL;
 
 export const exchangeTicketForToken = (ticket) => {
-    return axios.post(`${URL_BASE}/tickets/${ticket}/token`, {});
+    return axios.post(`${URL_BASE}/tickets/${ticket}/token`, {}, {
+        headers: {
+            "Authorization": `tma ${getInitData()}`
+        }
+    });
+};
+
+export const refreshAccessToken = (refreshToken) => {
+    return axios.post(`${URL_BASE}/tokens/refresh`, { refresh_token: refreshToken });
 };
 
 export const getObjectData = (accessToken, objectCode) => {
@@ -21,4 +29,11 @@ export const getObjectCoverURL = (accessToken, objectCode, coverIndex) => {
 
 export const getObjectAudioURL = (accessToken, objectCode) => {
     return `${URL_BASE}/objects/${objectCode}/audio?access-token=${accessToken}&language=${getTelegramLanguage()}`;
-};
\ No newline at end of file
+};
+export const getTourData = (accessToken, tourID) => {
+    return axios.get(`${URL_BASE}/tours/${tourID}?language=${getTelegramLanguage()}`, {
+        headers: {
+            "Authorization": accessToken
+        }
+    });
+};
@@ -8,6 +8,30 @@ export const getTelegramLanguage = () => {
     return window.Telegram.WebApp.initDataUnsafe.user.language_code;
 };
 
+// Signed init data, validated by the API to bind tickets to the user
+export const getInitData = () => {
+    return window.Telegram.WebApp.initData;
+};
+
+// Object code passed with Mini App direct link, e.g. https://t.me/<bot>/<app>?startapp=<code>
+export const getStartParam = () => {
+    return window.Telegram.WebApp.initDataUnsafe.start_param || null;
+};
+
+// QR codes contain either Mini App direct link or raw object code
+export const getObjectCodeFromQR = (text) => {
+    try {
+        const code = new URL(text).searchParams.get("startapp");
+        if (code) {
+            return code;
+        }
+    } catch {
+        // Not a URL, use text as is
+    }
+
+    return text;
+};
+
 export const getCloudValue = (key) => {
     return new Promise((resolve, reject) => {
         window.Telegram.WebApp.CloudStorage.getItem(key, (err, value) => {
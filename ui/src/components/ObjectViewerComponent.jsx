@@ -15,26 +15,50 @@ import { ButtonComponent, IconButtonComponent } from "./ButtonComponents";
 function ObjectViewerComponent(props) {
     const { accessToken, objectCode } = props;
 
+    // Object is not reloaded on token renewal, only media URLs are rebuilt with the new token
+    const accessTokenRef = useRef(accessToken);
+    accessTokenRef.current = accessToken;
+
     const [objectData, setObjectData] = useState({ loaded: false, data: null, error: null });
     useEffect(() => {
         setObjectData({ loaded: false, data: null, error: null });
-        getObjectData(accessToken, objectCode).then((response) => {
+        getObjectData(accessTokenRef.current, objectCode).then((response) => {
             const object = response.data.data;
             object.covers.sort((a, b) => a.index - b.index);
             setObjectData({ loaded: true, code: objectCode, data: object, error: null });
         }).catch((error) => {
-            setObjectData({ loaded: true, code: null, data: null, error: error.response.data.data });
+            setObjectData({ loaded: true, code: null, data: null, error: error.response?.data?.data ?? error.message });
         });
-    }, [objectCode, accessToken]);
+    }, [objectCode]);
 
     const audioRef = useRef();
-    const audioURL = objectData.loaded ? getObjectAudioURL(accessToken, objectData.code) : null;
+    const audioCodeRef = useRef(null);
+    const audioURL = objectData.loaded && objectData.code != null ? getObjectAudioURL(accessToken, objectData.code) : null;
     const [audioPlaying, setAudioPlaying] = useState(false);
     const [audioProgress, setAudioProgress] = useState(0);
 
+    // audio source is set here, so the position and playback survive the token renewal
     useEffect(() => {
-        setAudioPlaying(false);
-        setAudioProgress(0);
+        const current = audioRef.current;
+        if (!current || audioURL == null) {
+            return;
+        }
+
+        const renewed = audioCodeRef.current === objectData.code;
+        const position = current.currentTime;
+        const playing = !current.paused;
+        audioCodeRef.current = objectData.code;
+        current.src = audioURL;
+
+        if (renewed) {
+            current.currentTime = position;
+            if (playing) {
+                current.play();
+            }
+        } else {
+            setAudioPlaying(false);
+            setAudioProgress(0);
+        }
     }, [audioURL]);
 
     const onToggleAudioPlay = () => {
@@ -112,7 +136,7 @@ function ObjectViewerComponent(props) {
                         })}
                     </CarouselComponent>
                     <MarqueeComponent className="object-title" string={objectData.data.title} />
-                    <audio ref={audioRef} src={audioURL} onTimeUpdate={onAudioTimeUpdate} onPlay={onAudioPlay} onPause={onAudioPause} />
+                    <audio ref={audioRef} onTimeUpdate={onAudioTimeUpdate} onPlay={onAudioPlay} onPause={onAudioPause} />
                     <SliderComponent className="audio-range" value={audioProgress} min={0} max={1} step={0.01} onChange={onSeekAudio} />
                     <div className="controls-bar">
                         <IconButtonComponent className="icon-button" onClick={onScanQRClicked}>
@@ -0,0 +1,57 @@
+import "./TourViewerComponent.css";
+import "./CommonStyles.css";
+import { useState, useEffect } from "react";
+import { getTourData } from "../api/guide";
+import { i18n } from "../api/i18n";
+import { ButtonComponent } from "./ButtonComponents";
+
+function TourViewerComponent(props) {
+    const { accessToken, tourID, onStopSelected } = props;
+
+    const [tourData, setTourData] = useState({ loaded: false, data: null, error: null });
+    useEffect(() => {
+        setTourData({ loaded: false, data: null, error: null });
+        getTourData(accessToken, tourID).then((response) => {
+            setTourData({ loaded: true, data: response.data.data, error: null });
+        }).catch((error) => {
+            // network errors have no response, API failures have the reason in JSend data
+            setTourData({ loaded: true, data: null, error: error.response?.data?.data ?? error.message });
+        });
+    }, [tourID, accessToken]);
+
+    const onScanQRClicked = () => {
+        window.Telegram.WebApp.showScanQrPopup({});
+    };
+
+    const getUI = () => {
+        if (!tourData.loaded) {
+            return (<div className="preloader" />);
+        } else if (tourData.error != null) {
+            return (
+                <div className="tour-viewer-wrapper">
+                    <span>{i18n.t("ERROR_TOUR_LOAD_FAILED")}</span>
+                    <span>{tourData.error}</span>
+                    <ButtonComponent onClick={onScanQRClicked}>{i18n.t("BUTTON_SCAN_QR")}</ButtonComponent>
+                </div>
+            );
+        } else {
+            return (
+                <div className="tour-viewer-wrapper">
+                    <span className="tour-title">{tourData.data.title}</span>
+                    <span>{tourData.data.description}</span>
+                    {tourData.data.stops.map((stop, index) => {
+                        return (
+                            <ButtonComponent key={stop.code} onClick={() => onStopSelected(stop.code)}>
+                                {`${index + 1}. ${stop.title}`}
+                            </ButtonComponent>
+                        );
+                    })}
+                </div>
+            );
+        }
+    };
+
+    return getUI();
+}
+
+export default TourViewerComponent;